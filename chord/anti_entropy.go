@@ -0,0 +1,270 @@
+package chord
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
+	"time"
+)
+
+const (
+	// merkleDepth is the depth of Merkle trees replicas compare. Every node of a ring must use the same depth.
+	merkleDepth = 10
+)
+
+// MerkleHashes returns hashes of nodes of indexes at a level of the Merkle tree of records in (from, to].
+func (l *LocalNode) MerkleHashes(_ context.Context, from model.HashID, to model.HashID, level int, indexes []int) ([][]byte, error) {
+	if l.isShutdown {
+		return nil, ErrNodeUnavailable
+	}
+	if l.storage == nil {
+		return nil, ErrNoStorage
+	}
+	tree, err := l.storage.MerkleTree(from, to, merkleDepth)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Hashes(level, indexes)
+}
+
+// Digests returns digests of records in (from, to].
+func (l *LocalNode) Digests(_ context.Context, from model.HashID, to model.HashID) ([]storage.Digest, error) {
+	if l.isShutdown {
+		return nil, ErrNodeUnavailable
+	}
+	if l.storage == nil {
+		return nil, ErrNoStorage
+	}
+	return storage.Digests(l.storage, from, to)
+}
+
+// GetRecords returns records of keys. Keys which aren't stored are skipped.
+func (l *LocalNode) GetRecords(_ context.Context, keys []string) ([]*storage.Record, error) {
+	if l.isShutdown {
+		return nil, ErrNodeUnavailable
+	}
+	if l.storage == nil {
+		return nil, ErrNoStorage
+	}
+	records := make([]*storage.Record, 0, len(keys))
+	for _, key := range keys {
+		record, err := l.storage.Get(key)
+		if err == storage.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// PutRecords merges records sent by a coordinator or a replica peer into the records this node stores.
+func (l *LocalNode) PutRecords(_ context.Context, records []*storage.Record) error {
+	if l.isShutdown {
+		return ErrNodeUnavailable
+	}
+	if l.storage == nil {
+		return ErrNoStorage
+	}
+	return l.mergeRecords(records)
+}
+
+// mergeRecords resolves each record with the stored record of its key, following the conflict policy.
+// A record which adds nothing to the stored one is not written.
+func (l *LocalNode) mergeRecords(records []*storage.Record) error {
+	for _, record := range records {
+		if record.Version != nil {
+			if err := l.clock.observe(record.Version.Timestamp); err != nil {
+				log.Warnf("Host[%s] didn't move its clock for %s. err = %v", l.Key(), record.Key, err)
+			}
+		}
+		err := l.storage.Update(record.Key, func(current *storage.Record) *storage.Record {
+			if current == nil {
+				return record
+			}
+			resolved := storage.Resolve(l.conflictPolicy, current, record)
+			if bytes.Equal(resolved.Digest(), current.Digest()) {
+				return nil
+			}
+			return resolved
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AntiEntropyStabilizer makes replicas of the range a local node owns converge.
+// It compares a Merkle tree of the range with trees of replica peers, which are successors on distinct hosts,
+// and exchanges only records in buckets the trees disagree on. The storage keeps the trees up to date on writes,
+// so a round doesn't scan the range unless the range has changed.
+// Records missing on either side are copied. If both sides hold different records of a key,
+// the owner merges them by their versions and sends the result back.
+type AntiEntropyStabilizer struct {
+	Node     *LocalNode
+	replicas int
+	interval time.Duration
+	lastRun  time.Time
+}
+
+// NewAntiEntropyStabilizer creates an anti-entropy stabilizer, which syncs with replicas-1 peers at most once per interval.
+func NewAntiEntropyStabilizer(node *LocalNode, replicas int, interval time.Duration) *AntiEntropyStabilizer {
+	return &AntiEntropyStabilizer{
+		Node:     node,
+		replicas: replicas,
+		interval: interval,
+	}
+}
+
+// Stabilize is implemented for Stabilizer interface.
+// Anti-entropy is far more expensive than routing stabilizers, so it skips rounds within the interval.
+func (s *AntiEntropyStabilizer) Stabilize(ctx context.Context) {
+	if time.Since(s.lastRun) < s.interval {
+		return
+	}
+	s.lastRun = time.Now()
+	s.Node.stabilizers.record(antiEntropyStabilizerName, s.stabilize(ctx))
+}
+
+func (s *AntiEntropyStabilizer) stabilize(ctx context.Context) error {
+	l := s.Node
+	if l.storage == nil {
+		return ErrNoStorage
+	}
+	l.lock.Lock()
+	pred := l.predecessor
+	l.lock.Unlock()
+	if pred == nil {
+		return ErrStabilizeNotCompleted
+	}
+	if pred.Reference().Key() == l.Key() {
+		// This node is alone, so it has no replica.
+		return nil
+	}
+	replicas, err := l.FindReplicas(ctx, l.ID, s.replicas)
+	if err != nil {
+		return err
+	}
+	if replicas[0].Reference().Key() != l.Key() {
+		return fmt.Errorf("%w: Host[%s] doesn't own its own ID yet", ErrStabilizeNotCompleted, l.Key())
+	}
+	from := pred.Reference().ID
+	var lastErr error
+	for _, peer := range replicas[1:] {
+		// Records pulled from a peer change the tree, so a snapshot is taken for each peer.
+		tree, err := l.storage.MerkleTree(from, l.ID, merkleDepth)
+		if err != nil {
+			return err
+		}
+		if err := s.sync(ctx, peer, tree, from); err != nil {
+			log.Warnf("Host[%s] failed anti-entropy with Host[%s]. err = %v", l.Key(), peer.Reference().Key(), err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// sync walks the trees of this node and a peer top down, and repairs the leaves they disagree on.
+func (s *AntiEntropyStabilizer) sync(ctx context.Context, peer RingNode, tree *storage.MerkleTree, from model.HashID) error {
+	differing := []int{0}
+	for level := 0; level <= tree.Depth() && len(differing) > 0; level++ {
+		indexes := differing
+		if level > 0 {
+			indexes = make([]int, 0, len(differing)*2)
+			for _, index := range differing {
+				indexes = append(indexes, 2*index, 2*index+1)
+			}
+		}
+		remote, err := peer.MerkleHashes(ctx, from, s.Node.ID, level, indexes)
+		if err != nil {
+			return err
+		}
+		local, err := tree.Hashes(level, indexes)
+		if err != nil {
+			return err
+		}
+		if len(remote) != len(local) {
+			return fmt.Errorf("Host[%s] returned %d hashes for %d nodes", peer.Reference().Key(), len(remote), len(local))
+		}
+		differing = nil
+		for i, index := range indexes {
+			if !bytes.Equal(local[i], remote[i]) {
+				differing = append(differing, index)
+			}
+		}
+	}
+	var pulled, pushed int
+	for _, leaf := range differing {
+		leafFrom, leafTo := tree.LeafRange(leaf)
+		p, q, err := s.repair(ctx, peer, leafFrom, leafTo)
+		pulled, pushed = pulled+p, pushed+q
+		if err != nil {
+			return err
+		}
+	}
+	if pulled > 0 || pushed > 0 {
+		log.Infof("Host[%s] repaired replicas with Host[%s]. pulled = %d, pushed = %d", s.Node.Key(), peer.Reference().Key(), pulled, pushed)
+	}
+	return nil
+}
+
+// repair exchanges records in (from, to] which this node and a peer disagree on.
+// It returns how many records are pulled from the peer and pushed to it.
+func (s *AntiEntropyStabilizer) repair(ctx context.Context, peer RingNode, from model.HashID, to model.HashID) (int, int, error) {
+	remote, err := peer.Digests(ctx, from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+	local, err := storage.Digests(s.Node.storage, from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+	remoteHashes := make(map[string][]byte, len(remote))
+	for _, digest := range remote {
+		remoteHashes[digest.Key] = digest.Hash
+	}
+	// Records only this node holds are pushed, and records only the peer holds are pulled.
+	// Records both hold differently are pulled and merged, and the merged ones are pushed.
+	var pushKeys, pullKeys []string
+	for _, digest := range local {
+		hash, ok := remoteHashes[digest.Key]
+		if !ok || !bytes.Equal(hash, digest.Hash) {
+			pushKeys = append(pushKeys, digest.Key)
+		}
+		if ok && !bytes.Equal(hash, digest.Hash) {
+			pullKeys = append(pullKeys, digest.Key)
+		}
+		delete(remoteHashes, digest.Key)
+	}
+	for _, digest := range remote {
+		if _, ok := remoteHashes[digest.Key]; ok {
+			pullKeys = append(pullKeys, digest.Key)
+		}
+	}
+	var pulled []*storage.Record
+	if len(pullKeys) > 0 {
+		if pulled, err = peer.GetRecords(ctx, pullKeys); err != nil {
+			return 0, 0, err
+		}
+		if err := s.Node.mergeRecords(pulled); err != nil {
+			return 0, 0, err
+		}
+	}
+	if len(pushKeys) == 0 {
+		return len(pulled), 0, nil
+	}
+	pushed, err := s.Node.GetRecords(ctx, pushKeys)
+	if err != nil {
+		return len(pulled), 0, err
+	}
+	if err := peer.PutRecords(ctx, pushed); err != nil {
+		return len(pulled), 0, err
+	}
+	return len(pulled), len(pushed), nil
+}
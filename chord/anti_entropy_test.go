@@ -0,0 +1,140 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// digestCountingNode counts requests for digests, which are sent only for buckets replicas disagree on.
+type digestCountingNode struct {
+	*LocalNode
+	digests int
+}
+
+func (d *digestCountingNode) Digests(ctx context.Context, from model.HashID, to model.HashID) ([]storage.Digest, error) {
+	d.digests++
+	return d.LocalNode.Digests(ctx, from, to)
+}
+
+// createReplicatedRing creates a ring of n nodes with their own storage, whose routing states are stabilized.
+func createReplicatedRing(n int) []*LocalNode {
+	nodes := createNodes(n)
+	for i, node := range nodes {
+		// Spread nodes over the ring, so that each of them owns some keys.
+		id := big.NewInt(int64(i) + 1)
+		node.ID = model.BytesToHashID(id.Lsh(id, model.BitSize-3).Bytes())
+		node.fingerTable = NewFingerTable(node.ID)
+		node.storage = storage.NewMemoryEngine()
+		node.CreateRing()
+	}
+	for i, node := range nodes {
+		node.setSuccessors(nodes[(i+1)%n], []RingNode{nodes[(i+2)%n]})
+		node.predecessor = nodes[(i+n-1)%n]
+	}
+	return nodes
+}
+
+// ownedKeys returns keys which the node owns.
+func ownedKeys(node *LocalNode, pred *LocalNode, n int) []string {
+	var keys []string
+	for i := 0; len(keys) < n; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if id := model.NewHashID(key); id.Equals(node.ID) || id.Between(pred.ID, node.ID) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestAntiEntropyStabilizer(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(3)
+	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
+	peer := &digestCountingNode{LocalNode: node2}
+	node1.setSuccessors(peer, []RingNode{node3})
+
+	keys := ownedKeys(node1, node3, 50)
+	// Expiry travels with records.
+	expiresAt := storage.ExpiresAt(time.Now().Add(time.Hour))
+	for _, key := range keys {
+		record := &storage.Record{Key: key, Value: []byte("value"), ExpiresAt: expiresAt}
+		assert.Nil(t, node1.storage.Put(record))
+		assert.Nil(t, node2.storage.Put(record))
+	}
+	// node1 misses a write, and node2 misses another. node2 holds a stale value of another key.
+	assert.Nil(t, node1.storage.Delete(keys[0]))
+	assert.Nil(t, node2.storage.Delete(keys[1]))
+	stale := &storage.Version{Clock: map[string]uint64{node1.Key(): 1}, Timestamp: 1}
+	latest := &storage.Version{Clock: map[string]uint64{node1.Key(): 2}, Timestamp: 2}
+	assert.Nil(t, node1.storage.Put(&storage.Record{Key: keys[2], Value: []byte("value"), Version: latest, ExpiresAt: expiresAt}))
+	assert.Nil(t, node2.storage.Put(&storage.Record{Key: keys[2], Value: []byte("stale"), Version: stale}))
+	// A record out of the range node1 owns is left alone.
+	outside := ownedKeys(node2, node1, 1)[0]
+	assert.Nil(t, node2.storage.Put(&storage.Record{Key: outside}))
+
+	s := NewAntiEntropyStabilizer(node1, 3, time.Minute)
+	assert.Nil(t, s.stabilize(ctx))
+	assert.True(t, peer.digests > 0 && peer.digests <= 3, "digests are requested for %d buckets", peer.digests)
+
+	for _, node := range nodes {
+		for _, key := range keys {
+			record, err := node.storage.Get(key)
+			assert.Nil(t, err, "%s misses %s", node.Key(), key)
+			if err == nil {
+				assert.Equal(t, []byte("value"), record.Value)
+				assert.Equal(t, expiresAt, record.ExpiresAt)
+			}
+		}
+	}
+	_, err := node1.storage.Get(outside)
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = node3.storage.Get(outside)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	// Replicas have converged, so the next round exchanges nothing but the roots.
+	peer.digests = 0
+	assert.Nil(t, s.stabilize(ctx))
+	assert.Equal(t, 0, peer.digests)
+}
+
+func TestAntiEntropyStabilizer_Siblings(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(3)
+	for _, node := range nodes {
+		node.conflictPolicy = storage.KeepSiblings
+	}
+	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
+	key := ownedKeys(node1, node3, 1)[0]
+
+	// node1 and node2 have accepted concurrent writes.
+	v1 := &storage.Version{Clock: map[string]uint64{node1.Key(): 1}, Timestamp: 1}
+	v2 := &storage.Version{Clock: map[string]uint64{node2.Key(): 2}, Timestamp: 2}
+	assert.Nil(t, node1.storage.Put(&storage.Record{Key: key, Value: []byte("value1"), Version: v1}))
+	assert.Nil(t, node2.storage.Put(&storage.Record{Key: key, Value: []byte("value2"), Version: v2}))
+
+	assert.Nil(t, NewAntiEntropyStabilizer(node1, 3, time.Minute).stabilize(ctx))
+	expected := &storage.Record{
+		Key:      key,
+		Value:    []byte("value2"),
+		Version:  v2,
+		Siblings: []*storage.Sibling{{Value: []byte("value1"), Version: v1}},
+	}
+	for _, node := range nodes {
+		record, err := node.storage.Get(key)
+		assert.Nil(t, err, "%s misses %s", node.Key(), key)
+		assert.Equal(t, expected, record, node.Key())
+	}
+}
+
+func TestAntiEntropyStabilizer_SingleNode(t *testing.T) {
+	node := NewLocalNode("gord")
+	node.storage = storage.NewMemoryEngine()
+	node.CreateRing()
+	assert.Nil(t, NewAntiEntropyStabilizer(node, 3, time.Minute).stabilize(context.Background()))
+}
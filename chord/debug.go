@@ -0,0 +1,152 @@
+package chord
+
+import (
+	"github.com/taisho6339/gord/pkg/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	aliveStabilizerName       = "alive"
+	successorStabilizerName   = "successor"
+	fingerTableStabilizerName = "finger_table"
+	ringMergeStabilizerName   = "ring_merge"
+	antiEntropyStabilizerName = "anti_entropy"
+	reaperStabilizerName      = "reaper"
+)
+
+// DebugState represents a routing state of a local node, for operators to inspect.
+type DebugState struct {
+	Node        *model.NodeRef   `json:"node"`
+	Predecessor *model.NodeRef   `json:"predecessor"`
+	Successors  []*model.NodeRef `json:"successors"`
+	Fingers     []FingerState    `json:"fingers"`
+	// LastStabilizedIndex is the last finger the finger table stabilizer updated, or -1 before the first update.
+	LastStabilizedIndex int               `json:"last_stabilized_index"`
+	IsShutdown          bool              `json:"is_shutdown"`
+	Stabilizers         []StabilizerState `json:"stabilizers"`
+}
+
+// FingerState represents a finger of a finger table.
+// Node is nil until the finger is stabilized.
+type FingerState struct {
+	Index int            `json:"index"`
+	ID    model.HashID   `json:"id"`
+	Node  *model.NodeRef `json:"node"`
+}
+
+// StabilizerState represents results of the last runs of a stabilizer.
+type StabilizerState struct {
+	Name        string    `json:"name"`
+	LastRun     time.Time `json:"last_run"`
+	LastSuccess time.Time `json:"last_success"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at"`
+}
+
+// stabilizerStates records results of stabilizers.
+type stabilizerStates struct {
+	states map[string]*StabilizerState
+	// fingerIndex is the last finger the finger table stabilizer updated, or -1 before the first update.
+	fingerIndex int
+	lock        sync.Mutex
+}
+
+func newStabilizerStates() *stabilizerStates {
+	return &stabilizerStates{
+		states:      map[string]*StabilizerState{},
+		fingerIndex: -1,
+	}
+}
+
+func (s *stabilizerStates) recordFinger(index int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.fingerIndex = index
+}
+
+func (s *stabilizerStates) lastFinger() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.fingerIndex
+}
+
+func (s *stabilizerStates) record(name string, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	state, ok := s.states[name]
+	if !ok {
+		state = &StabilizerState{Name: name}
+		s.states[name] = state
+	}
+	now := time.Now()
+	state.LastRun = now
+	if err != nil {
+		state.LastError = err.Error()
+		state.LastErrorAt = now
+		return
+	}
+	state.LastSuccess = now
+}
+
+func (s *stabilizerStates) snapshot() []StabilizerState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	states := make([]StabilizerState, 0, len(s.states))
+	for _, state := range s.states {
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+func references(nodes []RingNode) []*model.NodeRef {
+	refs := make([]*model.NodeRef, 0, len(nodes))
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		refs = append(refs, node.Reference())
+	}
+	return refs
+}
+
+// DebugState returns a routing state of a local node, including the progress of the finger table stabilizer.
+// It copies the state under the lock of the node, so stabilizers may run meanwhile.
+func (l *LocalNode) DebugState() DebugState {
+	state := DebugState{
+		Node:                l.NodeRef,
+		Fingers:             make([]FingerState, len(l.fingerTable)),
+		LastStabilizedIndex: l.stabilizers.lastFinger(),
+		IsShutdown:          l.isShutdown,
+		Stabilizers:         l.stabilizers.snapshot(),
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.successors != nil {
+		state.Successors = references(l.successors.nodes)
+	}
+	if l.predecessor != nil {
+		state.Predecessor = l.predecessor.Reference()
+	}
+	for i, finger := range l.fingerTable {
+		state.Fingers[i] = FingerState{
+			Index: finger.Index,
+			ID:    finger.ID,
+		}
+		if finger.Node != nil {
+			state.Fingers[i].Node = finger.Node.Reference()
+		}
+	}
+	return state
+}
+
+// DebugState returns a routing state of a local node.
+func (p *Process) DebugState() DebugState {
+	state := p.LocalNode.DebugState()
+	state.IsShutdown = p.IsShutdown || state.IsShutdown
+	return state
+}
@@ -0,0 +1,100 @@
+package chord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestStabilizerStates_Record(t *testing.T) {
+	states := newStabilizerStates()
+	states.record(successorStabilizerName, nil)
+	states.record(aliveStabilizerName, errors.New("failed"))
+	states.record(aliveStabilizerName, nil)
+
+	snapshot := states.snapshot()
+	assert.Equal(t, 2, len(snapshot))
+	alive, successor := snapshot[0], snapshot[1]
+	assert.Equal(t, aliveStabilizerName, alive.Name)
+	assert.Equal(t, "failed", alive.LastError)
+	assert.False(t, alive.LastErrorAt.After(alive.LastSuccess))
+	assert.Equal(t, alive.LastRun, alive.LastSuccess)
+	assert.Equal(t, successorStabilizerName, successor.Name)
+	assert.Equal(t, "", successor.LastError)
+	assert.True(t, successor.LastErrorAt.IsZero())
+}
+
+func TestProcess_DebugState(t *testing.T) {
+	ctx := context.Background()
+	node := NewLocalNode("gord")
+	process := NewProcess(node, mockTransport)
+	defer process.Shutdown()
+
+	state := process.DebugState()
+	assert.Nil(t, state.Predecessor)
+	assert.Equal(t, 0, len(state.Successors))
+	assert.Equal(t, -1, state.LastStabilizedIndex)
+
+	node.CreateRing()
+	process.AliveStabilizer.Stabilize(ctx)
+	process.SuccessorStabilizer.Stabilize(ctx)
+	process.FingerTableStabilizer.Stabilize(ctx)
+
+	state = process.DebugState()
+	assert.Equal(t, "gord", state.Predecessor.Host)
+	assert.Equal(t, 1, len(state.Successors))
+	assert.Equal(t, len(node.fingerTable), len(state.Fingers))
+	assert.Equal(t, 0, state.LastStabilizedIndex)
+	assert.Equal(t, "gord", state.Fingers[0].Node.Host)
+	for i, finger := range state.Fingers {
+		assert.Equal(t, i, finger.Index)
+		assert.Equal(t, node.fingerTable[i].ID, finger.ID)
+	}
+	assert.Equal(t, 3, len(state.Stabilizers))
+	for _, s := range state.Stabilizers {
+		assert.Equal(t, "", s.LastError)
+		assert.False(t, s.LastRun.IsZero())
+	}
+	assert.False(t, state.IsShutdown)
+
+	b, err := json.Marshal(state)
+	assert.Nil(t, err)
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, node.ID.String(), decoded["node"].(map[string]interface{})["id"])
+	assert.Equal(t, float64(state.LastStabilizedIndex), decoded["last_stabilized_index"])
+}
+
+// TestProcess_DebugState_Concurrent reads the state while stabilizers run. Run it with -race.
+func TestProcess_DebugState_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	node := NewLocalNode("gord")
+	process := NewProcess(node, mockTransport)
+	node.CreateRing()
+
+	done := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			process.AliveStabilizer.Stabilize(ctx)
+			process.SuccessorStabilizer.Stabilize(ctx)
+			process.FingerTableStabilizer.Stabilize(ctx)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		state := process.DebugState()
+		assert.Equal(t, len(node.fingerTable), len(state.Fingers))
+	}
+	close(done)
+	wg.Wait()
+}
@@ -11,4 +11,16 @@ var (
 	ErrNodeUnavailable = errors.New("NodeUnavailable")
 	// ErrNoSuccessorAlive represents no successor available error
 	ErrNoSuccessorAlive = errors.New("ErrNoSuccessorAlive")
+	// ErrIncompatibleProtocol represents a peer speaks an incompatible protocol
+	ErrIncompatibleProtocol = errors.New("IncompatibleProtocol")
+	// ErrUnverifiedIdentity represents a peer fails to prove its identity
+	ErrUnverifiedIdentity = errors.New("UnverifiedIdentity")
+	// ErrNoStorage represents a node keeps no records
+	ErrNoStorage = errors.New("NoStorage")
+	// ErrInvalidQuorum represents N, R or W of a request is out of range
+	ErrInvalidQuorum = errors.New("InvalidQuorum")
+	// ErrQuorumNotReached represents too few replicas answer a request
+	ErrQuorumNotReached = errors.New("QuorumNotReached")
+	// ErrTimestampAhead represents a version has a timestamp too far ahead of the clock of a node
+	ErrTimestampAhead = errors.New("TimestampAhead")
 )
@@ -0,0 +1,163 @@
+package chord
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/taisho6339/gord/pkg/model"
+	"sync"
+)
+
+// RingEventType represents a kind of ring membership change.
+type RingEventType int
+
+const (
+	// PredecessorChanged represents that the predecessor of a local node has been replaced.
+	PredecessorChanged RingEventType = iota
+	// SuccessorAdded represents that a node has been added to the successor list.
+	SuccessorAdded
+	// SuccessorRemoved represents that a node has been removed from the successor list.
+	SuccessorRemoved
+	// NodeDead represents that a successor has been declared dead, and removed from the successor list without SuccessorRemoved.
+	NodeDead
+)
+
+func (t RingEventType) String() string {
+	switch t {
+	case PredecessorChanged:
+		return "PredecessorChanged"
+	case SuccessorAdded:
+		return "SuccessorAdded"
+	case SuccessorRemoved:
+		return "SuccessorRemoved"
+	case NodeDead:
+		return "NodeDead"
+	default:
+		return "Unknown"
+	}
+}
+
+// RingEvent represents a ring membership change observed by a local node.
+// The ownership of keys in the range (From, To] has shifted.
+type RingEvent struct {
+	Type RingEventType
+	Node *model.NodeRef
+	From model.HashID
+	To   model.HashID
+}
+
+// RingEventHandler is a callback to receive ring events.
+type RingEventHandler func(event RingEvent)
+
+const watcherBufferSize = 256
+
+type ringWatcher struct {
+	handler RingEventHandler
+	events  chan RingEvent
+}
+
+// ringWatchers dispatches ring events to handlers.
+// Each handler runs in its own goroutine, so a slow handler never blocks stabilizers.
+type ringWatchers struct {
+	watchers map[int]*ringWatcher
+	nextID   int
+	lock     sync.Mutex
+}
+
+func newRingWatchers() *ringWatchers {
+	return &ringWatchers{
+		watchers: map[int]*ringWatcher{},
+	}
+}
+
+func (w *ringWatchers) add(handler RingEventHandler) func() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	id := w.nextID
+	w.nextID++
+	watcher := &ringWatcher{
+		handler: handler,
+		events:  make(chan RingEvent, watcherBufferSize),
+	}
+	w.watchers[id] = watcher
+	go func() {
+		for event := range watcher.events {
+			watcher.handler(event)
+		}
+	}()
+	return func() {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		if _, ok := w.watchers[id]; !ok {
+			return
+		}
+		delete(w.watchers, id)
+		close(watcher.events)
+	}
+}
+
+func (w *ringWatchers) emit(events ...RingEvent) {
+	if len(events) == 0 {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, event := range events {
+		for _, watcher := range w.watchers {
+			select {
+			case watcher.events <- event:
+			default:
+				log.Warnf("ring event watcher is too slow. event %s for Host[%s] dropped.", event.Type, event.Node.Host)
+			}
+		}
+	}
+}
+
+// successorEvents compares successor lists and returns events for added and removed nodes.
+// The range of each event is from the previous node in the list to the node itself.
+func successorEvents(self *model.NodeRef, oldNodes []RingNode, newNodes []RingNode) []RingEvent {
+	var events []RingEvent
+	diff := func(eventType RingEventType, from []RingNode, to []RingNode) {
+		keys := map[string]struct{}{}
+		for _, node := range to {
+			keys[node.Reference().Key()] = struct{}{}
+		}
+		prev := self
+		for _, node := range from {
+			ref := node.Reference()
+			if _, ok := keys[ref.Key()]; !ok {
+				events = append(events, RingEvent{
+					Type: eventType,
+					Node: ref,
+					From: prev.ID,
+					To:   ref.ID,
+				})
+			}
+			prev = ref
+		}
+	}
+	diff(SuccessorRemoved, oldNodes, newNodes)
+	diff(SuccessorAdded, newNodes, oldNodes)
+	return events
+}
+
+// predecessorEvent returns an event for a predecessor replaced from oldPred to newPred.
+// If the new predecessor lies between the old one and the local node, the local node hands (old, new] over.
+// Otherwise, the local node takes (new, old] over.
+func predecessorEvent(self *model.NodeRef, oldPred RingNode, newPred RingNode) RingEvent {
+	newRef := newPred.Reference()
+	event := RingEvent{
+		Type: PredecessorChanged,
+		Node: newRef,
+		From: newRef.ID,
+		To:   self.ID,
+	}
+	if oldPred == nil {
+		return event
+	}
+	oldRef := oldPred.Reference()
+	if newRef.ID.Between(oldRef.ID, self.ID) {
+		event.From, event.To = oldRef.ID, newRef.ID
+		return event
+	}
+	event.From, event.To = newRef.ID, oldRef.ID
+	return event
+}
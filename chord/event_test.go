@@ -0,0 +1,109 @@
+package chord
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSuccessorEvents(t *testing.T) {
+	nodes := createNodes(4)
+	node1, node2, node3, node4 := nodes[0], nodes[1], nodes[2], nodes[3]
+	events := successorEvents(node1.NodeRef, []RingNode{node2, node3}, []RingNode{node2, node4})
+	assert.Equal(t, 2, len(events))
+
+	assert.Equal(t, SuccessorRemoved, events[0].Type)
+	assert.Equal(t, node3.Host, events[0].Node.Host)
+	assert.Equal(t, node2.ID, events[0].From)
+	assert.Equal(t, node3.ID, events[0].To)
+
+	assert.Equal(t, SuccessorAdded, events[1].Type)
+	assert.Equal(t, node4.Host, events[1].Node.Host)
+	assert.Equal(t, node2.ID, events[1].From)
+	assert.Equal(t, node4.ID, events[1].To)
+}
+
+func TestPredecessorEvent(t *testing.T) {
+	nodes := createNodes(4)
+	node1, node2, node3, node4 := nodes[0], nodes[1], nodes[2], nodes[3]
+
+	// A new node joins between the old predecessor and the local node.
+	event := predecessorEvent(node4.NodeRef, node1, node3)
+	assert.Equal(t, PredecessorChanged, event.Type)
+	assert.Equal(t, node1.ID, event.From)
+	assert.Equal(t, node3.ID, event.To)
+
+	// The old predecessor has gone, the local node takes its range over.
+	event = predecessorEvent(node4.NodeRef, node3, node2)
+	assert.Equal(t, node2.ID, event.From)
+	assert.Equal(t, node3.ID, event.To)
+
+	event = predecessorEvent(node4.NodeRef, nil, node2)
+	assert.Equal(t, node2.ID, event.From)
+	assert.Equal(t, node4.ID, event.To)
+}
+
+func TestLocalNode_Watch(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(3)
+	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
+	node1.CreateRing()
+
+	events := make(chan RingEvent, 10)
+	unwatch := node1.Watch(func(event RingEvent) {
+		events <- event
+	})
+	node1.PutSuccessor(node2)
+	assert.NoError(t, node1.Notify(ctx, node3))
+	unwatch()
+	node1.PutSuccessor(node3)
+
+	received := make([]RingEvent, 0)
+	timeout := time.After(time.Second)
+	for len(received) < 2 {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case <-timeout:
+			t.Fatal("test failed by timeout.")
+		}
+	}
+	assert.Equal(t, SuccessorAdded, received[0].Type)
+	assert.Equal(t, node2.Host, received[0].Node.Host)
+	assert.Equal(t, PredecessorChanged, received[1].Type)
+	assert.Equal(t, node3.Host, received[1].Node.Host)
+	assert.Equal(t, 0, len(events))
+}
+
+func TestAliveStabilizer_Events(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(4)
+	node1, node2, node3, node4 := nodes[0], nodes[1], nodes[2], nodes[3]
+	node1.CreateRing()
+	node1.setSuccessors(node2, []RingNode{node3, node4})
+
+	events := make(chan RingEvent, 10)
+	unwatch := node1.Watch(func(event RingEvent) {
+		events <- event
+	})
+	defer unwatch()
+	node3.isShutdown = true
+	NewAliveStabilizer(node1).Stabilize(ctx)
+
+	// The dead successor is reported once, as dead rather than removed.
+	select {
+	case event := <-events:
+		assert.Equal(t, NodeDead, event.Type)
+		assert.Equal(t, node3.Host, event.Node.Host)
+		assert.Equal(t, node2.ID, event.From)
+	case <-time.After(time.Second):
+		t.Fatal("test failed by timeout.")
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %v of Host[%s]", event.Type, event.Node.Host)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, 2, len(node1.successors.nodes))
+}
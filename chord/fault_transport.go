@@ -0,0 +1,322 @@
+package chord
+
+import (
+	"context"
+	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Names of RPCs which fault rules match, the same as methods of the internal gRPC service.
+const (
+	RPCPing                     = "Ping"
+	RPCSuccessors               = "Successors"
+	RPCPredecessor              = "Predecessor"
+	RPCFindSuccessorByTable     = "FindSuccessorByTable"
+	RPCFindSuccessorByList      = "FindSuccessorByList"
+	RPCFindClosestPrecedingNode = "FindClosestPrecedingNode"
+	RPCNotify                   = "Notify"
+	RPCHandshake                = "Handshake"
+	RPCMerkleHashes             = "MerkleHashes"
+	RPCDigests                  = "Digests"
+	RPCGetRecords               = "GetRecords"
+	RPCPutRecords               = "PutRecords"
+)
+
+// FaultAction represents how an RPC fails.
+type FaultAction int
+
+const (
+	// FaultError fails an RPC immediately as if the peer is unavailable.
+	FaultError FaultAction = iota
+	// FaultDelay delays an RPC, which then runs as usual.
+	FaultDelay
+	// FaultTimeout never sends an RPC, and fails it with context.DeadlineExceeded after the delay.
+	FaultTimeout
+	// FaultDrop sends an RPC but drops the response, and fails it with context.DeadlineExceeded after the delay.
+	FaultDrop
+)
+
+func (a FaultAction) String() string {
+	switch a {
+	case FaultError:
+		return "Error"
+	case FaultDelay:
+		return "Delay"
+	case FaultTimeout:
+		return "Timeout"
+	case FaultDrop:
+		return "Drop"
+	default:
+		return "Unknown"
+	}
+}
+
+// FaultRule represents a fault injected into RPCs.
+type FaultRule struct {
+	// Method is a name of RPCs the rule applies to. Empty matches every RPC.
+	Method string
+	// Peer is a key of peers the rule applies to. Empty matches every peer.
+	Peer   string
+	Action FaultAction
+	Delay  time.Duration
+	// Probability is a chance that the rule applies to a matched RPC. Zero is treated as one.
+	Probability float64
+}
+
+func (r FaultRule) matches(method string, peer *model.NodeRef) bool {
+	return (r.Method == "" || r.Method == method) && (r.Peer == "" || r.Peer == peer.Key())
+}
+
+// FaultInjector keeps fault rules, which can be replaced at runtime.
+type FaultInjector struct {
+	rules []FaultRule
+	rand  *rand.Rand
+	lock  sync.Mutex
+}
+
+// NewFaultInjector creates an injector without rules.
+func NewFaultInjector(seed int64) *FaultInjector {
+	return &FaultInjector{
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// SetRules replaces all rules.
+func (f *FaultInjector) SetRules(rules []FaultRule) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.rules = append([]FaultRule{}, rules...)
+}
+
+// Rules returns current rules.
+func (f *FaultInjector) Rules() []FaultRule {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]FaultRule{}, f.rules...)
+}
+
+// pick returns the first rule which applies to an RPC.
+func (f *FaultInjector) pick(method string, peer *model.NodeRef) (FaultRule, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, rule := range f.rules {
+		if !rule.matches(method, peer) {
+			continue
+		}
+		if rule.Probability > 0 && f.rand.Float64() >= rule.Probability {
+			continue
+		}
+		return rule, true
+	}
+	return FaultRule{}, false
+}
+
+// FaultTransport is a transport which injects faults into RPCs of another transport.
+type FaultTransport struct {
+	inner    Transport
+	injector *FaultInjector
+}
+
+// NewFaultTransport wraps a transport to inject faults following the rules of injector.
+func NewFaultTransport(inner Transport, injector *FaultInjector) Transport {
+	return &FaultTransport{
+		inner:    inner,
+		injector: injector,
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// invoke runs an RPC under the rule which applies to it.
+func (f *FaultTransport) invoke(ctx context.Context, method string, to *model.NodeRef, rpc func() error) error {
+	rule, ok := f.injector.pick(method, to)
+	if !ok {
+		return rpc()
+	}
+	switch rule.Action {
+	case FaultError:
+		return ErrNodeUnavailable
+	case FaultDelay:
+		if err := wait(ctx, rule.Delay); err != nil {
+			return err
+		}
+		return rpc()
+	case FaultTimeout:
+		if err := wait(ctx, rule.Delay); err != nil {
+			return err
+		}
+		return context.DeadlineExceeded
+	case FaultDrop:
+		_ = rpc()
+		if err := wait(ctx, rule.Delay); err != nil {
+			return err
+		}
+		return context.DeadlineExceeded
+	default:
+		return rpc()
+	}
+}
+
+// wrap makes RPCs to a returned remote node go through this transport as well.
+func (f *FaultTransport) wrap(node RingNode) RingNode {
+	if remote, ok := node.(*RemoteNode); ok {
+		return NewRemoteNodeFromRef(remote.NodeRef, f)
+	}
+	return node
+}
+
+func (f *FaultTransport) wrapAll(nodes []RingNode) []RingNode {
+	if nodes == nil {
+		return nil
+	}
+	wrapped := make([]RingNode, len(nodes))
+	for i, node := range nodes {
+		wrapped[i] = f.wrap(node)
+	}
+	return wrapped
+}
+
+func (f *FaultTransport) PingRPC(ctx context.Context, to *model.NodeRef) error {
+	return f.invoke(ctx, RPCPing, to, func() error {
+		return f.inner.PingRPC(ctx, to)
+	})
+}
+
+func (f *FaultTransport) SuccessorsRPC(ctx context.Context, to *model.NodeRef) ([]RingNode, error) {
+	var successors []RingNode
+	err := f.invoke(ctx, RPCSuccessors, to, func() (err error) {
+		successors, err = f.inner.SuccessorsRPC(ctx, to)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.wrapAll(successors), nil
+}
+
+func (f *FaultTransport) PredecessorRPC(ctx context.Context, to *model.NodeRef) (RingNode, error) {
+	var pred RingNode
+	err := f.invoke(ctx, RPCPredecessor, to, func() (err error) {
+		pred, err = f.inner.PredecessorRPC(ctx, to)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.wrap(pred), nil
+}
+
+func (f *FaultTransport) FindSuccessorByTableRPC(ctx context.Context, to *model.NodeRef, id model.HashID) (RingNode, error) {
+	var successor RingNode
+	err := f.invoke(ctx, RPCFindSuccessorByTable, to, func() (err error) {
+		successor, err = f.inner.FindSuccessorByTableRPC(ctx, to, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.wrap(successor), nil
+}
+
+func (f *FaultTransport) FindSuccessorByListRPC(ctx context.Context, to *model.NodeRef, id model.HashID) (RingNode, error) {
+	var successor RingNode
+	err := f.invoke(ctx, RPCFindSuccessorByList, to, func() (err error) {
+		successor, err = f.inner.FindSuccessorByListRPC(ctx, to, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.wrap(successor), nil
+}
+
+func (f *FaultTransport) FindClosestPrecedingNodeRPC(ctx context.Context, to *model.NodeRef, id model.HashID) (RingNode, error) {
+	var node RingNode
+	err := f.invoke(ctx, RPCFindClosestPrecedingNode, to, func() (err error) {
+		node, err = f.inner.FindClosestPrecedingNodeRPC(ctx, to, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.wrap(node), nil
+}
+
+func (f *FaultTransport) NotifyRPC(ctx context.Context, to *model.NodeRef, node *model.NodeRef) error {
+	return f.invoke(ctx, RPCNotify, to, func() error {
+		return f.inner.NotifyRPC(ctx, to, node)
+	})
+}
+
+func (f *FaultTransport) HandshakeRPC(ctx context.Context, to *model.NodeRef, node *model.NodeRef) (Protocol, error) {
+	var protocol Protocol
+	err := f.invoke(ctx, RPCHandshake, to, func() (err error) {
+		protocol, err = f.inner.HandshakeRPC(ctx, to, node)
+		return err
+	})
+	if err != nil {
+		return Protocol{}, err
+	}
+	return protocol, nil
+}
+
+func (f *FaultTransport) MerkleHashesRPC(ctx context.Context, to *model.NodeRef, rangeFrom model.HashID, rangeTo model.HashID, level int, indexes []int) ([][]byte, error) {
+	var hashes [][]byte
+	err := f.invoke(ctx, RPCMerkleHashes, to, func() (err error) {
+		hashes, err = f.inner.MerkleHashesRPC(ctx, to, rangeFrom, rangeTo, level, indexes)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+func (f *FaultTransport) DigestsRPC(ctx context.Context, to *model.NodeRef, rangeFrom model.HashID, rangeTo model.HashID) ([]storage.Digest, error) {
+	var digests []storage.Digest
+	err := f.invoke(ctx, RPCDigests, to, func() (err error) {
+		digests, err = f.inner.DigestsRPC(ctx, to, rangeFrom, rangeTo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+func (f *FaultTransport) GetRecordsRPC(ctx context.Context, to *model.NodeRef, keys []string) ([]*storage.Record, error) {
+	var records []*storage.Record
+	err := f.invoke(ctx, RPCGetRecords, to, func() (err error) {
+		records, err = f.inner.GetRecordsRPC(ctx, to, keys)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (f *FaultTransport) PutRecordsRPC(ctx context.Context, to *model.NodeRef, records []*storage.Record) error {
+	return f.invoke(ctx, RPCPutRecords, to, func() error {
+		return f.inner.PutRecordsRPC(ctx, to, records)
+	})
+}
+
+func (f *FaultTransport) Shutdown() {
+	f.inner.Shutdown()
+}
@@ -0,0 +1,116 @@
+package chord
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/pkg/model"
+	"testing"
+	"time"
+)
+
+// countingTransport counts RPCs which reach it.
+type countingTransport struct {
+	MockTransport
+	calls int
+}
+
+func (c *countingTransport) PingRPC(_ context.Context, _ *model.NodeRef) error {
+	c.calls++
+	return nil
+}
+
+func (c *countingTransport) SuccessorsRPC(_ context.Context, _ *model.NodeRef) ([]RingNode, error) {
+	c.calls++
+	return []RingNode{NewRemoteNode("gord2", c)}, nil
+}
+
+func TestFaultInjector_Pick(t *testing.T) {
+	injector := NewFaultInjector(1)
+	injector.SetRules([]FaultRule{
+		{Method: RPCPing, Peer: "gord1", Action: FaultError},
+		{Method: RPCNotify, Action: FaultDelay},
+		{Peer: "gord2", Action: FaultDrop, Probability: 0.5},
+	})
+	gord1, gord2 := model.NewNodeRef("gord1"), model.NewNodeRef("gord2")
+
+	rule, ok := injector.pick(RPCPing, gord1)
+	assert.True(t, ok)
+	assert.Equal(t, FaultError, rule.Action)
+	rule, ok = injector.pick(RPCNotify, gord1)
+	assert.True(t, ok)
+	assert.Equal(t, FaultDelay, rule.Action)
+	_, ok = injector.pick(RPCSuccessors, gord1)
+	assert.False(t, ok)
+
+	hits := 0
+	for i := 0; i < 1000; i++ {
+		if _, ok := injector.pick(RPCPing, gord2); ok {
+			hits++
+		}
+	}
+	assert.InDelta(t, 500, hits, 100)
+}
+
+func TestFaultTransport(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingTransport{}
+	injector := NewFaultInjector(1)
+	transport := NewFaultTransport(inner, injector)
+	to := model.NewNodeRef("gord1")
+
+	testcases := []struct {
+		rule          FaultRule
+		expectedErr   error
+		expectedCalls int
+		minElapsed    time.Duration
+	}{
+		{
+			rule:          FaultRule{Method: RPCSuccessors, Action: FaultError},
+			expectedErr:   nil,
+			expectedCalls: 1,
+		},
+		{
+			rule:          FaultRule{Method: RPCPing, Action: FaultError},
+			expectedErr:   ErrNodeUnavailable,
+			expectedCalls: 0,
+		},
+		{
+			rule:          FaultRule{Action: FaultDelay, Delay: 20 * time.Millisecond},
+			expectedErr:   nil,
+			expectedCalls: 1,
+			minElapsed:    20 * time.Millisecond,
+		},
+		{
+			rule:          FaultRule{Peer: "gord1", Action: FaultTimeout, Delay: 20 * time.Millisecond},
+			expectedErr:   context.DeadlineExceeded,
+			expectedCalls: 0,
+			minElapsed:    20 * time.Millisecond,
+		},
+		{
+			rule:          FaultRule{Peer: "gord1", Action: FaultDrop},
+			expectedErr:   context.DeadlineExceeded,
+			expectedCalls: 1,
+		},
+	}
+	for _, testcase := range testcases {
+		inner.calls = 0
+		injector.SetRules([]FaultRule{testcase.rule})
+		start := time.Now()
+		err := transport.PingRPC(ctx, to)
+		assert.Equal(t, testcase.expectedErr, err)
+		assert.Equal(t, testcase.expectedCalls, inner.calls)
+		assert.True(t, time.Since(start) >= testcase.minElapsed)
+	}
+
+	// RPCs to returned nodes go through the fault transport as well.
+	injector.SetRules([]FaultRule{{Method: RPCPing, Peer: "gord2", Action: FaultError}})
+	successors, err := transport.SuccessorsRPC(ctx, to)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrNodeUnavailable, successors[0].Ping(ctx))
+
+	// A canceled context stops waiting.
+	injector.SetRules([]FaultRule{{Action: FaultTimeout, Delay: time.Hour}})
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Equal(t, context.Canceled, transport.PingRPC(canceled, to))
+}
@@ -0,0 +1,60 @@
+package chord
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxClockDrift bounds how far ahead of the wall clock a timestamp observed may be.
+// A timestamp far ahead would stop the clock from following the wall clock, and the largest one would overflow it.
+const maxClockDrift = time.Minute
+
+// hybridClock is a hybrid logical clock, which versions writes a node coordinates.
+// A timestamp packs milliseconds of the wall clock into the upper 48 bits and a logical counter into the lower 16 bits,
+// so timestamps follow the wall clock, but keep increasing even if the wall clock goes back or another node's clock is ahead.
+type hybridClock struct {
+	last uint64
+	lock sync.Mutex
+	now  func() time.Time
+}
+
+func newHybridClock() *hybridClock {
+	return &hybridClock{
+		now: time.Now,
+	}
+}
+
+// next returns a timestamp later than any timestamp returned or observed before.
+func (c *hybridClock) next() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	wall := c.wall()
+	if wall > c.last {
+		c.last = wall
+	} else if c.last < math.MaxUint64 {
+		// observe keeps the clock far from the largest timestamp, but it must never wrap around to 0.
+		c.last++
+	}
+	return c.last
+}
+
+// observe moves the clock past a timestamp of another node, so that writes after it get later timestamps.
+// It refuses a timestamp more than maxClockDrift ahead of the wall clock.
+func (c *hybridClock) observe(timestamp uint64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if limit := c.wall() + uint64(maxClockDrift/time.Millisecond)<<16; timestamp > limit {
+		return fmt.Errorf("%w: %d is more than %s ahead of the clock", ErrTimestampAhead, timestamp, maxClockDrift)
+	}
+	if timestamp > c.last {
+		c.last = timestamp
+	}
+	return nil
+}
+
+// wall returns the timestamp of the wall clock with a logical counter of 0.
+func (c *hybridClock) wall() uint64 {
+	return uint64(c.now().UnixNano()/int64(time.Millisecond)) << 16
+}
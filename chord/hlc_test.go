@@ -0,0 +1,51 @@
+package chord
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHybridClock(t *testing.T) {
+	wall := time.Unix(1000, 0)
+	clock := newHybridClock()
+	clock.now = func() time.Time {
+		return wall
+	}
+	t1 := clock.next()
+	assert.Equal(t, uint64(1000*1000)<<16, t1)
+
+	// The wall clock stays or goes back, but timestamps keep increasing.
+	t2 := clock.next()
+	wall = wall.Add(-time.Second)
+	t3 := clock.next()
+	assert.True(t, t1 < t2 && t2 < t3)
+
+	// A timestamp of a node whose clock is ahead moves the clock forward.
+	ahead := uint64(1030*1000) << 16
+	assert.Nil(t, clock.observe(ahead))
+	assert.True(t, clock.next() > ahead)
+
+	// The clock follows the wall clock once it catches up.
+	wall = time.Unix(3000, 0)
+	assert.Equal(t, uint64(3000*1000)<<16, clock.next())
+}
+
+func TestHybridClock_TimestampAhead(t *testing.T) {
+	wall := time.Unix(1000, 0)
+	clock := newHybridClock()
+	clock.now = func() time.Time {
+		return wall
+	}
+	// Timestamps too far ahead, up to the largest one, are refused, and the clock keeps following the wall clock.
+	for _, timestamp := range []uint64{uint64(1000*1000+61*1000) << 16, math.MaxUint64} {
+		assert.True(t, errors.Is(clock.observe(timestamp), ErrTimestampAhead))
+	}
+	assert.Equal(t, uint64(1000*1000)<<16, clock.next())
+
+	// The clock never wraps around, even if it gets to the largest timestamp.
+	clock.last = math.MaxUint64
+	assert.Equal(t, uint64(math.MaxUint64), clock.next())
+}
@@ -0,0 +1,96 @@
+package chord
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/taisho6339/gord/pkg/model"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Identity is an ed25519 keypair of a node.
+// The ring ID of a node with an identity is derived from the public key instead of the host,
+// and the node proves it owns the key by signing its reference.
+type Identity struct {
+	privateKey ed25519.PrivateKey
+}
+
+// GenerateIdentity creates a new random identity.
+func GenerateIdentity() (*Identity, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{privateKey: privateKey}, nil
+}
+
+// NewIdentityFromSeed creates an identity from a 32 bytes seed.
+func NewIdentityFromSeed(seed []byte) (*Identity, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("identity seed must be %d bytes, but got %d bytes", ed25519.SeedSize, len(seed))
+	}
+	return &Identity{privateKey: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// LoadIdentity reads an identity from a file which holds a hex encoded seed.
+// If the file doesn't exist, it generates a new identity and saves it, so that the node keeps its ID over restarts.
+func LoadIdentity(path string) (*Identity, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		identity, err := GenerateIdentity()
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(identity.privateKey.Seed())+"\n"), 0600); err != nil {
+			return nil, err
+		}
+		return identity, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("identity file %s is broken. err = %w", path, err)
+	}
+	return NewIdentityFromSeed(seed)
+}
+
+// PublicKey returns the public key of an identity.
+func (i *Identity) PublicKey() ed25519.PublicKey {
+	return i.privateKey.Public().(ed25519.PublicKey)
+}
+
+// sign derives the ID of a node from the public key, and signs the node's reference.
+func (i *Identity) sign(ref *model.NodeRef) {
+	ref.SetIdentity(i.PublicKey(), nil)
+	ref.Signature = ed25519.Sign(i.privateKey, ref.IdentityMessage())
+}
+
+// VerifyIdentity checks that a node owns the public key its ID is derived from, and that the key signed its host.
+func VerifyIdentity(ref *model.NodeRef) error {
+	if len(ref.PublicKey) == 0 {
+		return fmt.Errorf("%w: Host[%s] has no signed identity", ErrUnverifiedIdentity, ref.Key())
+	}
+	if len(ref.PublicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: Host[%s] has a malformed public key", ErrUnverifiedIdentity, ref.Key())
+	}
+	if !ref.ID.Equals(model.IdentityHashID(ref.PublicKey, ref.VNode)) {
+		return fmt.Errorf("%w: ID of Host[%s] isn't derived from its public key", ErrUnverifiedIdentity, ref.Key())
+	}
+	if !ed25519.Verify(ref.PublicKey, ref.IdentityMessage(), ref.Signature) {
+		return fmt.Errorf("%w: signature of Host[%s] is invalid", ErrUnverifiedIdentity, ref.Key())
+	}
+	return nil
+}
+
+// VerifyPeer checks the identity of a peer, if a local node accepts only verified peers.
+func (l *LocalNode) VerifyPeer(ref *model.NodeRef) error {
+	if !l.verifyPeers {
+		return nil
+	}
+	return VerifyIdentity(ref)
+}
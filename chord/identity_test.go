@@ -0,0 +1,100 @@
+package chord
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/pkg/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newIdentity(t *testing.T) *Identity {
+	identity, err := GenerateIdentity()
+	assert.Nil(t, err)
+	return identity
+}
+
+func TestVerifyIdentity(t *testing.T) {
+	identity := newIdentity(t)
+	node := NewLocalNode("gord", WithIdentity(identity))
+	assert.Equal(t, model.IdentityHashID(identity.PublicKey(), 0), node.ID)
+	assert.Nil(t, VerifyIdentity(node.NodeRef))
+
+	vnode := NewLocalNode("gord", WithIdentity(identity), WithVirtualNode(1))
+	assert.NotEqual(t, node.ID, vnode.ID)
+	assert.Nil(t, VerifyIdentity(vnode.NodeRef))
+
+	testcases := map[string]func(ref *model.NodeRef){
+		"unsigned": func(ref *model.NodeRef) {
+			ref.PublicKey = nil
+		},
+		"spoofed host": func(ref *model.NodeRef) {
+			ref.Host = "attacker"
+		},
+		"spoofed id": func(ref *model.NodeRef) {
+			ref.ID = model.NewHashID("gord")
+		},
+		"another key": func(ref *model.NodeRef) {
+			ref.SetIdentity(newIdentity(t).PublicKey(), ref.Signature)
+		},
+	}
+	for name, tamper := range testcases {
+		ref := *node.NodeRef
+		tamper(&ref)
+		assert.True(t, errors.Is(VerifyIdentity(&ref), ErrUnverifiedIdentity), name)
+	}
+}
+
+func TestLoadIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gord")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "identity")
+
+	identity, err := LoadIdentity(path)
+	assert.Nil(t, err)
+	loaded, err := LoadIdentity(path)
+	assert.Nil(t, err)
+	assert.Equal(t, identity.PublicKey(), loaded.PublicKey())
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("broken"), 0600))
+	_, err = LoadIdentity(path)
+	assert.NotNil(t, err)
+}
+
+func TestLocalNode_VerifiedPeers(t *testing.T) {
+	ctx := context.Background()
+	node := NewLocalNode("gord", WithIdentity(newIdentity(t)), WithVerifiedPeers())
+	node.CreateRing()
+	signed := NewLocalNode("signed", WithIdentity(newIdentity(t)))
+	unsigned := NewLocalNode("unsigned")
+
+	// An unsigned node can't take the predecessor slot.
+	assert.True(t, errors.Is(node.Notify(ctx, unsigned), ErrUnverifiedIdentity))
+	assert.Equal(t, node.ID, node.predecessor.Reference().ID)
+	_, err := node.Handshake(ctx, unsigned)
+	assert.True(t, errors.Is(err, ErrUnverifiedIdentity))
+
+	assert.Nil(t, node.Notify(ctx, signed))
+	assert.Equal(t, signed.ID, node.predecessor.Reference().ID)
+
+	// Unsigned nodes are dropped from successor lists received from peers.
+	node.setSuccessors(signed, []RingNode{unsigned, node})
+	assert.Equal(t, []RingNode{signed, node}, node.successors.nodes)
+}
+
+func TestLocalNode_JoinRing_VerifiedPeers(t *testing.T) {
+	ctx := context.Background()
+	unsigned := NewLocalNode("unsigned")
+	unsigned.CreateRing()
+	node := NewLocalNode("gord", WithIdentity(newIdentity(t)), WithVerifiedPeers())
+	assert.True(t, errors.Is(node.JoinRing(ctx, unsigned), ErrUnverifiedIdentity))
+
+	signed := NewLocalNode("signed", WithIdentity(newIdentity(t)))
+	signed.CreateRing()
+	assert.Nil(t, node.JoinRing(ctx, signed))
+	assert.Equal(t, signed.ID, node.successors.nodes[0].Reference().ID)
+}
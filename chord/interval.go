@@ -0,0 +1,43 @@
+package chord
+
+import "time"
+
+// stabilizeInterval decides how long stabilizers sleep between rounds.
+// It backs off exponentially while a round changes nothing,
+// and snaps back to the minimum interval as soon as a change is detected.
+type stabilizeInterval struct {
+	min     time.Duration
+	max     time.Duration
+	current time.Duration
+}
+
+func newStabilizeInterval(min time.Duration, max time.Duration) *stabilizeInterval {
+	if max < min {
+		max = min
+	}
+	return &stabilizeInterval{
+		min:     min,
+		max:     max,
+		current: min,
+	}
+}
+
+// next returns the interval to wait before the next round.
+// changed represents whether the last round updated the routing state.
+func (s *stabilizeInterval) next(changed bool) time.Duration {
+	if changed {
+		s.reset()
+		return s.current
+	}
+	current := s.current
+	s.current *= 2
+	if s.current > s.max || s.current <= 0 {
+		s.current = s.max
+	}
+	return current
+}
+
+// reset makes the interval the minimum one.
+func (s *stabilizeInterval) reset() {
+	s.current = s.min
+}
@@ -0,0 +1,26 @@
+package chord
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStabilizeInterval_Next(t *testing.T) {
+	interval := newStabilizeInterval(50*time.Millisecond, 300*time.Millisecond)
+	assert.Equal(t, 50*time.Millisecond, interval.next(false))
+	assert.Equal(t, 100*time.Millisecond, interval.next(false))
+	assert.Equal(t, 200*time.Millisecond, interval.next(false))
+	assert.Equal(t, 300*time.Millisecond, interval.next(false))
+	assert.Equal(t, 300*time.Millisecond, interval.next(false))
+	assert.Equal(t, 50*time.Millisecond, interval.next(true))
+	assert.Equal(t, 50*time.Millisecond, interval.next(false))
+	assert.Equal(t, 100*time.Millisecond, interval.next(false))
+}
+
+func TestStabilizeInterval_Fixed(t *testing.T) {
+	interval := newStabilizeInterval(50*time.Millisecond, 10*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, 50*time.Millisecond, interval.next(false))
+	}
+}
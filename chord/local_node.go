@@ -3,12 +3,17 @@ package chord
 import (
 	"context"
 	"fmt"
+	log "github.com/sirupsen/logrus"
 	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
+	"sort"
 	"sync"
+	"sync/atomic"
 )
 
 // exclusiveNodeList represents node list.
-// It restricts no overlapped host nodes.
+// It restricts no overlapped host nodes, so that a host failing takes out a single entry
+// and the list still has successors to fail over to. The head is any virtual node, which lookups need to be exact.
 type exclusiveNodeList struct {
 	nodes   []RingNode
 	hostMap map[string]struct{}
@@ -45,47 +50,79 @@ func emptyNodes(cap int) []RingNode {
 	return make([]RingNode, 0, cap)
 }
 
-func (q *exclusiveNodeList) appendHead(node RingNode) {
+// appendHead puts a node on the head of the list.
+// If the list already has a node of the same host, the node replaces it.
+// It returns true if the list has been changed.
+func (q *exclusiveNodeList) appendHead(node RingNode) bool {
 	if node == nil {
-		return
-	}
-	if q.hasHostKey(node.Reference().Host) {
-		return
+		return false
 	}
-
+	host := node.Reference().Host
 	newNodes := append(emptyNodes(cap(q.nodes)), node)
-	if len(q.nodes) >= cap(q.nodes) {
-		q.nodes = append(newNodes, q.nodes[:len(q.nodes)-1]...)
-		return
+	for _, n := range q.nodes {
+		if len(newNodes) >= cap(q.nodes) {
+			break
+		}
+		if n.Reference().Host == host {
+			continue
+		}
+		newNodes = append(newNodes, n)
 	}
-	q.nodes = append(newNodes, q.nodes[:]...)
-	q.hostMap[node.Reference().Host] = struct{}{}
+	oldNodes := q.nodes
+	q.nodes = newNodes
+	q.refreshHostMap()
+	return !sameNodes(oldNodes, q.nodes)
 }
 
-func (q *exclusiveNodeList) join(offset int, nodes []RingNode) {
+// join replaces the nodes after offset with the given nodes, skipping nodes of hosts the list already has.
+// It returns true if the list has been changed.
+func (q *exclusiveNodeList) join(offset int, nodes []RingNode) bool {
 	if len(nodes) == 0 {
-		return
+		return false
 	}
 	if cap(q.nodes) <= offset {
-		return
+		return false
 	}
 	if len(nodes) > (cap(q.nodes) - offset) {
 		nodes = nodes[:cap(q.nodes)-offset]
 	}
 
-	q.nodes = q.nodes[0:offset]
+	oldNodes := q.nodes
+	q.nodes = append(emptyNodes(cap(q.nodes)), q.nodes[0:offset]...)
 	q.refreshHostMap()
 	for _, node := range nodes {
 		if q.hasHostKey(node.Reference().Host) {
 			continue
 		}
 		q.nodes = append(q.nodes, node)
+		q.hostMap[node.Reference().Host] = struct{}{}
 	}
-	q.refreshHostMap()
+	return !sameNodes(oldNodes, q.nodes)
 }
 
+func sameNodes(a []RingNode, b []RingNode) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Reference().Key() != b[i].Reference().Key() {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	// policyCandidatesPerReplica is how many nodes on distinct hosts a successor policy chooses each replica from.
+	policyCandidatesPerReplica = 4
+	// ringWalkParallelism limits how many nodes a walk of the ring asks for successors at once.
+	ringWalkParallelism = 16
+)
+
 // LocalNode represents local host node.
 type LocalNode struct {
+	// routingVersion is accessed atomically, so it comes first to be 64-bit aligned.
+	routingVersion uint64
 	*model.NodeRef
 
 	fingerTable []*Finger
@@ -93,19 +130,145 @@ type LocalNode struct {
 	predecessor RingNode
 	isShutdown  bool
 	lock        sync.Mutex
+	changeCh    chan struct{}
+	watchers    *ringWatchers
+	policy      SuccessorPolicy
+	protocols   *protocolTable
+	stabilizers *stabilizerStates
+	peers       *peerHistory
+	// successorListSize is r, how many successors a local node keeps.
+	successorListSize int
+	// predecessorDead is set when the predecessor stops answering, so that any node may replace it.
+	predecessorDead bool
+	identity        *Identity
+	// verifyPeers makes a local node accept only peers with verified identities.
+	verifyPeers bool
+	// storage keeps records on the host, which the process of the node sets.
+	storage storage.Engine
+	// replicationFactor is N, how many replicas reads and writes of records go to by default.
+	replicationFactor int
+	// conflictPolicy decides which of concurrent values of a key the node keeps.
+	conflictPolicy storage.ConflictPolicy
+	// clock versions writes the node coordinates.
+	clock *hybridClock
+}
+
+// LocalNodeOptionFunc is function to apply options to a local node
+type LocalNodeOptionFunc func(node *LocalNode)
+
+// WithZone declares a failure domain, such as an availability zone or a rack, of a local node.
+func WithZone(zone string) LocalNodeOptionFunc {
+	return func(node *LocalNode) {
+		node.Zone = zone
+	}
+}
+
+// WithMetadata sets arbitrary labels of a local node, which travel with the node's reference.
+func WithMetadata(metadata map[string]string) LocalNodeOptionFunc {
+	return func(node *LocalNode) {
+		node.Metadata = metadata
+	}
+}
+
+// WithVirtualNode makes a local node the virtual node of index on its host.
+// Its ID is derived from the host and the index.
+func WithVirtualNode(index int) LocalNodeOptionFunc {
+	return func(node *LocalNode) {
+		node.VNode = index
+	}
+}
+
+// WithRequiredFeatures makes a local node refuse peers which don't support the features.
+func WithRequiredFeatures(features ...Feature) LocalNodeOptionFunc {
+	return func(node *LocalNode) {
+		node.protocols.required = append(node.protocols.required, features...)
+	}
+}
+
+// WithSuccessorListSize sets r, how many successors a local node keeps.
+// A ring tolerates up to r-1 consecutive failures, as long as it has at least r+1 nodes.
+// A smaller ring is covered by every successor list, which the successor stabilizer checks to repair a loopy ring.
+func WithSuccessorListSize(size int) LocalNodeOptionFunc {
+	return func(node *LocalNode) {
+		node.successorListSize = size
+	}
+}
+
+// WithIdentity makes a local node derive its ID from the identity's public key and sign its reference.
+func WithIdentity(identity *Identity) LocalNodeOptionFunc {
+	return func(node *LocalNode) {
+		node.identity = identity
+	}
+}
+
+// WithVerifiedPeers makes a local node accept only peers which prove their identities,
+// as predecessors, successors or joining nodes.
+func WithVerifiedPeers() LocalNodeOptionFunc {
+	return func(node *LocalNode) {
+		node.verifyPeers = true
+	}
 }
 
 // NewLocalNode creates a local node.
-func NewLocalNode(host string) *LocalNode {
-	id := model.NewHashID(host)
-	return &LocalNode{
+func NewLocalNode(host string, opts ...LocalNodeOptionFunc) *LocalNode {
+	node := &LocalNode{
 		NodeRef:     model.NewNodeRef(host),
-		fingerTable: NewFingerTable(id),
+		changeCh:    make(chan struct{}, 1),
+		watchers:    newRingWatchers(),
+		policy:      RingOrderPolicy{},
+		protocols:   newProtocolTable(),
+		stabilizers: newStabilizerStates(),
+		peers:       newPeerHistory(),
+		clock:       newHybridClock(),
+
+		successorListSize: model.BitSize / 2,
+		replicationFactor: defaultReplicationFactor,
+	}
+	for _, opt := range opts {
+		opt(node)
+	}
+	if node.identity != nil {
+		node.identity.sign(node.NodeRef)
+	} else {
+		node.ID = model.NewHashID(node.Key())
+	}
+	node.fingerTable = NewFingerTable(node.ID)
+	return node
+}
+
+// Watch registers a handler called on every ring membership change of this node.
+// It returns a function to unregister the handler.
+func (l *LocalNode) Watch(handler RingEventHandler) func() {
+	return l.watchers.add(handler)
+}
+
+// markChanged records that successors, predecessor or fingers of a local node have been updated.
+func (l *LocalNode) markChanged() {
+	atomic.AddUint64(&l.routingVersion, 1)
+	select {
+	case l.changeCh <- struct{}{}:
+	default:
+	}
+}
+
+// RoutingVersion returns a number which increases whenever successors, predecessor or fingers are updated.
+// Results derived from the routing state are stale once the version changes.
+func (l *LocalNode) RoutingVersion() uint64 {
+	return atomic.LoadUint64(&l.routingVersion)
+}
+
+// popChanged reports whether the routing state has been updated since the last call.
+func (l *LocalNode) popChanged() bool {
+	select {
+	case <-l.changeCh:
+		return true
+	default:
+		return false
 	}
 }
 
 func (l *LocalNode) initSuccessors(suc RingNode) {
-	l.successors = newNodeList(model.BitSize / 2)
+	l.successors = newNodeList(l.successorListSize)
 	l.PutSuccessor(suc)
 }
 
@@ -122,10 +285,21 @@ func (l *LocalNode) CreateRing() {
 }
 
 func (l *LocalNode) JoinRing(ctx context.Context, existNode RingNode) error {
+	protocol, err := existNode.Handshake(ctx, l)
+	if err != nil {
+		return fmt.Errorf("handshake failed. err = %w", err)
+	}
+	if err := l.NegotiateProtocol(existNode.Reference(), protocol); err != nil {
+		return err
+	}
+	l.RememberPeer(existNode)
 	successor, err := existNode.FindSuccessorByTable(ctx, l.ID)
 	if err != nil {
 		return fmt.Errorf("find successor failed. err = %#v", err)
 	}
+	if err := l.VerifyPeer(successor.Reference()); err != nil {
+		return fmt.Errorf("refused successor. err = %w", err)
+	}
 	l.initSuccessors(successor)
 
 	firstSuc, err := l.successors.head()
@@ -143,21 +317,117 @@ func (l *LocalNode) JoinRing(ctx context.Context, existNode RingNode) error {
 		return fmt.Errorf("get successors failed. err = %#v", err)
 	}
 
-	l.JoinSuccessors(1, successors)
+	l.setSuccessors(firstSuc, successors)
+	return nil
+}
+
+// setSuccessors makes a successor list from a successor and its successor list.
+// Following Zave's rule, the list is the successor followed by its successors.
+// It ends at this node, since the ring wraps around there.
+func (l *LocalNode) setSuccessors(suc RingNode, successors []RingNode) {
+	nodes := []RingNode{suc}
+	if suc.Reference().Key() != l.Key() {
+		for _, node := range successors {
+			if node.Reference().Key() == l.Key() {
+				nodes = append(nodes, node)
+				break
+			}
+			if err := l.VerifyPeer(node.Reference()); err != nil {
+				log.Warnf("Host[%s] dropped a successor. err = %v", l.Key(), err)
+				continue
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	l.JoinSuccessors(0, nodes)
+	l.updateFinger(0, suc)
+}
+
+// skippedSuccessor enforces Zave's minimum ring size of r+1 nodes.
+// A ring of at least r+1 nodes can't get loopy, but a smaller one can: following successors goes around the ring
+// more than once before getting back to this node. The successor list of such a ring covers the whole ring,
+// so it reaches this node, and a loopy ring shows up as a node in it between this node and its successor.
+// It returns the closest of such nodes, or nil if the ring isn't loopy or is large enough.
+func (l *LocalNode) skippedSuccessor(suc RingNode, successors []RingNode) RingNode {
+	var closest RingNode
+	for _, node := range successors {
+		if node.Reference().Key() == l.Key() {
+			return closest
+		}
+		if !node.Reference().ID.Between(l.ID, suc.Reference().ID) {
+			continue
+		}
+		if closest == nil || node.Reference().ID.Between(l.ID, closest.Reference().ID) {
+			closest = node
+		}
+	}
+	// The list doesn't wrap around, so the ring has at least r+1 nodes.
 	return nil
 }
 
 func (l *LocalNode) JoinSuccessors(offset int, successors []RingNode) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	l.successors.join(offset, successors)
+	oldNodes := l.successors.nodes
+	if l.successors.join(offset, successors) {
+		l.peers.add(l.Host, successorSide, successors...)
+		l.markChanged()
+		l.watchers.emit(successorEvents(l.NodeRef, oldNodes, l.successors.nodes)...)
+	}
+}
+
+// removeDeadSuccessors replaces successors with the alive ones, and emits NodeDead events of the dead ones.
+// A dead successor is reported only by its NodeDead event, not by SuccessorRemoved, so watchers get one event per failure.
+func (l *LocalNode) removeDeadSuccessors(aliveNodes []RingNode, deadEvents []RingEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.watchers.emit(deadEvents...)
+	oldNodes := l.successors.nodes
+	if !l.successors.join(0, aliveNodes) {
+		return
+	}
+	l.peers.add(l.Host, successorSide, aliveNodes...)
+	l.markChanged()
+	dead := map[string]struct{}{}
+	for _, event := range deadEvents {
+		dead[event.Node.Key()] = struct{}{}
+	}
+	var events []RingEvent
+	for _, event := range successorEvents(l.NodeRef, oldNodes, l.successors.nodes) {
+		if _, ok := dead[event.Node.Key()]; ok && event.Type == SuccessorRemoved {
+			continue
+		}
+		events = append(events, event)
+	}
+	l.watchers.emit(events...)
 }
 
 func (l *LocalNode) PutSuccessor(suc RingNode) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	l.successors.appendHead(suc)
-	l.fingerTable[0].Node = suc
+	oldNodes := l.successors.nodes
+	if l.successors.appendHead(suc) {
+		l.peers.add(l.Host, successorSide, suc)
+		l.markChanged()
+		l.watchers.emit(successorEvents(l.NodeRef, oldNodes, l.successors.nodes)...)
+	}
+	l.putFinger(0, suc)
+}
+
+// updateFinger updates a finger of the table under the lock of the node.
+func (l *LocalNode) updateFinger(index int, node RingNode) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.putFinger(index, node)
+}
+
+// putFinger updates a finger of the table. The caller holds the lock of the node.
+func (l *LocalNode) putFinger(index int, node RingNode) {
+	finger := l.fingerTable[index]
+	if finger.Node == nil || node == nil || !finger.Node.Reference().ID.Equals(node.Reference().ID) {
+		l.markChanged()
+	}
+	finger.Node = node
 }
 
 func (l *LocalNode) Ping(_ context.Context) error {
@@ -185,12 +455,74 @@ func (l *LocalNode) GetPredecessor(_ context.Context) (RingNode, error) {
 	return l.predecessor, nil
 }
 
+// PreferredSuccessors returns up to r successors chosen by the successor policy.
+// The successor list itself always keeps ring order, which stabilization relies on.
+func (l *LocalNode) PreferredSuccessors(_ context.Context, r int) ([]RingNode, error) {
+	if l.isShutdown {
+		return nil, ErrNodeUnavailable
+	}
+	l.lock.Lock()
+	successors := l.successors.nodes
+	l.lock.Unlock()
+	return l.activePolicy().Select(successors, r), nil
+}
+
+// activePolicy returns the successor policy, or RingOrderPolicy while neighbours lack a feature the policy relies on.
+func (l *LocalNode) activePolicy() SuccessorPolicy {
+	if p, ok := l.policy.(featureDependentPolicy); ok && !l.FeatureEnabled(p.RequiredFeature()) {
+		return RingOrderPolicy{}
+	}
+	return l.policy
+}
+
+// OwnershipShare returns a fraction of the key space this node owns, which is (predecessor, this node].
+func (l *LocalNode) OwnershipShare(_ context.Context) (float64, error) {
+	if l.isShutdown {
+		return 0, ErrNodeUnavailable
+	}
+	if l.predecessor == nil {
+		return 0, ErrNotFound
+	}
+	return model.RingShare(l.predecessor.Reference().ID, l.ID), nil
+}
+
+// NegotiateProtocol checks a protocol of a peer, and records it if it is compatible.
+func (l *LocalNode) NegotiateProtocol(peer *model.NodeRef, protocol Protocol) error {
+	if err := l.protocols.check(peer, protocol); err != nil {
+		return err
+	}
+	l.protocols.observe(peer, protocol)
+	return nil
+}
+
+// FeatureEnabled reports whether a feature is switched on.
+// A feature is switched on only once the predecessor and the successor support it.
+func (l *LocalNode) FeatureEnabled(feature Feature) bool {
+	var neighbours []RingNode
+	l.lock.Lock()
+	if l.predecessor != nil {
+		neighbours = append(neighbours, l.predecessor)
+	}
+	if suc, err := l.successors.head(); err == nil {
+		neighbours = append(neighbours, suc)
+	}
+	l.lock.Unlock()
+	return l.protocols.enabled(feature, neighbours)
+}
+
 func (l *LocalNode) FindSuccessorByList(ctx context.Context, id model.HashID) (RingNode, error) {
 	if l.isShutdown {
 		return nil, ErrNodeUnavailable
 	}
-	for _, successor := range l.successors.nodes {
-		if id.Between(l.ID, successor.Reference().ID) {
+	l.lock.Lock()
+	successors := l.successors.nodes
+	l.lock.Unlock()
+	for _, successor := range successors {
+		// The list wraps around at this node, so the rest of the ring belongs to this node.
+		if successor.Reference().Key() == l.Key() {
+			return l, nil
+		}
+		if id.Between(l.ID, successor.Reference().ID.Add(1)) {
 			return successor, nil
 		}
 	}
@@ -217,14 +549,140 @@ func (l *LocalNode) FindSuccessorByTable(ctx context.Context, id model.HashID) (
 	return nil, ErrNoSuccessorAlive
 }
 
+// FindReplicas returns a preference list for id, which is its owner followed by up to n-1 successors on distinct hosts.
+// It walks successor lists from the owner in ring order, skipping nodes which don't answer and virtual nodes of hosts already picked,
+// and the successor policy chooses replicas among the nodes found, in the same way as PreferredSuccessors.
+// If the ring has fewer than n live hosts, it returns all of them.
+func (l *LocalNode) FindReplicas(ctx context.Context, id model.HashID, n int) ([]RingNode, error) {
+	if l.isShutdown {
+		return nil, ErrNodeUnavailable
+	}
+	owner, err := l.FindSuccessorByTable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	policy := l.activePolicy()
+	// A policy other than ring order needs more nodes than n to choose from.
+	candidates := n
+	if _, ok := policy.(RingOrderPolicy); !ok {
+		candidates = n * policyCandidatesPerReplica
+	}
+	replicas := []RingNode{owner}
+	hosts := map[string]struct{}{owner.Reference().Host: {}}
+	// seen records whether each node checked so far is alive.
+	seen := map[string]bool{owner.Reference().Key(): true}
+	walked := map[string]struct{}{}
+	current := owner
+	for len(replicas) < candidates {
+		walked[current.Reference().Key()] = struct{}{}
+		successors, err := current.GetSuccessors(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var next RingNode
+		for _, suc := range successors {
+			ref := suc.Reference()
+			// The list has wrapped around the ring.
+			if ref.Key() == owner.Reference().Key() {
+				break
+			}
+			if alive, ok := seen[ref.Key()]; ok {
+				if alive {
+					next = suc
+				}
+				continue
+			}
+			seen[ref.Key()] = suc.Ping(ctx) == nil
+			if !seen[ref.Key()] {
+				continue
+			}
+			next = suc
+			if _, ok := hosts[ref.Host]; ok {
+				continue
+			}
+			hosts[ref.Host] = struct{}{}
+			replicas = append(replicas, suc)
+			if len(replicas) >= candidates {
+				break
+			}
+		}
+		// The walk goes on from the farthest live successor, until it makes no progress.
+		if next == nil {
+			break
+		}
+		if _, ok := walked[next.Reference().Key()]; ok {
+			break
+		}
+		current = next
+	}
+	return policy.Select(replicas, n), nil
+}
+
+// Ring returns every node of the ring in ring order, starting from this node.
+// A successor list has a node per host, so it skips virtual nodes of hosts it already has, but its head is the next node.
+// Thus the walk asks every node found for its successors until no new node turns up.
+// Nodes which don't answer are still returned, since other successor lists have them.
+func (l *LocalNode) Ring(ctx context.Context) ([]RingNode, error) {
+	if l.isShutdown {
+		return nil, ErrNodeUnavailable
+	}
+	nodes := []RingNode{l}
+	seen := map[string]struct{}{l.Key(): {}}
+	pending := []RingNode{l}
+	for len(pending) > 0 {
+		lists := make([][]RingNode, len(pending))
+		errs := make([]error, len(pending))
+		sem := make(chan struct{}, ringWalkParallelism)
+		wg := &sync.WaitGroup{}
+		for i, node := range pending {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, node RingNode) {
+				defer wg.Done()
+				lists[i], errs[i] = node.GetSuccessors(ctx)
+				<-sem
+			}(i, node)
+		}
+		wg.Wait()
+		// The walk can't start if this node doesn't know its successors.
+		if len(nodes) == 1 && errs[0] != nil {
+			return nil, errs[0]
+		}
+		pending = nil
+		for _, successors := range lists {
+			for _, suc := range successors {
+				key := suc.Reference().Key()
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+				nodes = append(nodes, suc)
+				pending = append(pending, suc)
+			}
+		}
+	}
+	others := nodes[1:]
+	sort.Slice(others, func(i, j int) bool {
+		return others[i].Reference().ID.Between(l.ID, others[j].Reference().ID)
+	})
+	return nodes, nil
+}
+
 func (l *LocalNode) findPredecessor(ctx context.Context, id model.HashID) (RingNode, error) {
 	var (
 		targetNode RingNode = l
+		// fallback is the successor of the last node which answered.
+		fallback RingNode
 	)
 	for {
 		successors, err := targetNode.GetSuccessors(ctx)
 		if err != nil {
-			return nil, err
+			// A finger may point to a dead node until it's stabilized, so walk along successors instead.
+			if fallback == nil || fallback.Reference().Key() == targetNode.Reference().Key() {
+				return nil, err
+			}
+			targetNode = fallback
+			continue
 		}
 		if successors == nil || len(successors) <= 0 {
 			return nil, ErrNotFound
@@ -237,9 +695,11 @@ func (l *LocalNode) findPredecessor(ctx context.Context, id model.HashID) (RingN
 			break
 		}
 		node, err := targetNode.FindClosestPrecedingNode(ctx, id)
-		if err != nil {
+		// No closer node is known while the ring is still stabilizing.
+		if err != nil || node.Reference().Key() == targetNode.Reference().Key() {
 			return nil, ErrNotFound
 		}
+		fallback = suc
 		targetNode = node
 	}
 	return targetNode, nil
@@ -249,25 +709,84 @@ func (l *LocalNode) FindClosestPrecedingNode(_ context.Context, id model.HashID)
 	if l.isShutdown {
 		return nil, ErrNodeUnavailable
 	}
+	// If the node hasn't joined yet
+	if l.fingerTable[0].Node == nil {
+		return nil, ErrStabilizeNotCompleted
+	}
+	var closest RingNode = l
 	for i := range l.fingerTable {
 		finger := l.fingerTable[len(l.fingerTable)-(i+1)]
-		// If the fingerTable has not been updated
+		// Fingers not stabilized yet are skipped, since a short successor list can't cover them.
 		if finger.Node == nil {
-			return nil, ErrStabilizeNotCompleted
+			continue
 		}
 		if finger.Node.Reference().ID.Between(l.ID, id) {
-			return finger.Node, nil
+			closest = finger.Node
+			break
+		}
+	}
+	// A successor may be closer than any finger.
+	l.lock.Lock()
+	successors := l.successors.nodes
+	l.lock.Unlock()
+	for _, successor := range successors {
+		if successor.Reference().ID.Between(closest.Reference().ID, id) {
+			closest = successor
 		}
 	}
-	return l, nil
+	return closest, nil
 }
 
+// Notify is called by a node which believes it is the predecessor of this node.
+// It works as rectify in Zave's corrected Chord: the node replaces the predecessor
+// if it lies between the predecessor and this node, or if the predecessor is dead.
 func (l *LocalNode) Notify(_ context.Context, node RingNode) error {
 	if l.isShutdown {
 		return ErrNodeUnavailable
 	}
-	if l.predecessor == nil || node.Reference().ID.Between(l.predecessor.Reference().ID, l.ID) {
+	if err := l.VerifyPeer(node.Reference()); err != nil {
+		return err
+	}
+	if l.predecessor == nil || l.predecessorDead || node.Reference().ID.Between(l.predecessor.Reference().ID, l.ID) {
+		if l.predecessor != nil && l.predecessor.Reference().Key() == node.Reference().Key() {
+			l.predecessorDead = false
+			return nil
+		}
+		l.watchers.emit(predecessorEvent(l.NodeRef, l.predecessor, node))
+		l.peers.add(l.Host, predecessorSide, node)
 		l.predecessor = node
+		l.predecessorDead = false
+		l.markChanged()
 	}
 	return nil
 }
+
+// checkPredecessor marks the predecessor dead if it doesn't answer.
+func (l *LocalNode) checkPredecessor(ctx context.Context) {
+	pred := l.predecessor
+	if pred == nil || pred.Reference().Key() == l.Key() {
+		return
+	}
+	if err := pred.Ping(ctx); err != nil {
+		if !l.predecessorDead {
+			log.Warnf("Host[%s]'s predecessor Host[%s] is dead.", l.Key(), pred.Reference().Key())
+		}
+		l.predecessorDead = true
+		return
+	}
+	l.predecessorDead = false
+}
+
+func (l *LocalNode) Handshake(_ context.Context, node RingNode) (Protocol, error) {
+	if l.isShutdown {
+		return Protocol{}, ErrNodeUnavailable
+	}
+	if err := l.VerifyPeer(node.Reference()); err != nil {
+		return Protocol{}, err
+	}
+	// A local node runs the same build.
+	if err := l.NegotiateProtocol(node.Reference(), LocalProtocol()); err != nil {
+		return Protocol{}, err
+	}
+	return l.protocols.local, nil
+}
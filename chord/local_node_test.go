@@ -59,6 +59,166 @@ func TestLocalNode_Notify(t *testing.T) {
 	assert.Equal(t, node1.ID, node2.predecessor.Reference().ID)
 }
 
+func TestLocalNode_Notify_DeadPredecessor(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(3)
+	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
+	assert.NoError(t, node3.Notify(ctx, node2))
+	assert.NoError(t, node3.Notify(ctx, node1))
+	assert.Equal(t, node2.ID, node3.predecessor.Reference().ID)
+
+	node2.Shutdown()
+	node3.checkPredecessor(ctx)
+	assert.True(t, node3.predecessorDead)
+	assert.NoError(t, node3.Notify(ctx, node1))
+	assert.Equal(t, node1.ID, node3.predecessor.Reference().ID)
+	assert.False(t, node3.predecessorDead)
+}
+
+func TestLocalNode_SetSuccessors(t *testing.T) {
+	nodes := createNodes(4)
+	node1, node2, node3, node4 := nodes[0], nodes[1], nodes[2], nodes[3]
+	node1.CreateRing()
+
+	// The list ends at the node itself.
+	node1.setSuccessors(node2, []RingNode{node3, node1, node2})
+	assert.Equal(t, []RingNode{node2, node3, node1}, node1.successors.nodes)
+	assert.Equal(t, node2.ID, node1.fingerTable[0].Node.Reference().ID)
+
+	node1.setSuccessors(node3, []RingNode{node4, node1})
+	assert.Equal(t, []RingNode{node3, node4, node1}, node1.successors.nodes)
+	assert.Equal(t, node3.ID, node1.fingerTable[0].Node.Reference().ID)
+
+	node1.setSuccessors(node1, []RingNode{node2})
+	assert.Equal(t, []RingNode{node1}, node1.successors.nodes)
+}
+
+func TestLocalNode_FindSuccessorByList(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(3)
+	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
+	for _, node := range nodes {
+		node.ID = model.BytesToHashID(node.ID)
+	}
+	node1.CreateRing()
+	node1.setSuccessors(node2, []RingNode{node3, node1})
+
+	for id, expected := range map[int64]*LocalNode{2: node2, 3: node3, 4: node1, 1: node1} {
+		suc, err := node1.FindSuccessorByList(ctx, model.BytesToHashID(big.NewInt(id).Bytes()))
+		assert.Nil(t, err)
+		assert.Equal(t, expected.ID, suc.Reference().ID)
+	}
+}
+
+func TestLocalNode_FindReplicas(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(5)
+	for _, node := range nodes {
+		node.ID = model.BytesToHashID(node.ID)
+		node.successorListSize = 2
+		node.CreateRing()
+	}
+	// node3 is a virtual node on the host of node2, and node4 is dead.
+	nodes[2].Host, nodes[2].VNode = nodes[1].Host, 1
+	nodes[3].Shutdown()
+	for i, node := range nodes {
+		node.setSuccessors(nodes[(i+1)%5], []RingNode{nodes[(i+2)%5]})
+	}
+	id := model.BytesToHashID(big.NewInt(2).Bytes())
+
+	testcases := []struct {
+		n        int
+		expected []*LocalNode
+	}{
+		{n: 1, expected: []*LocalNode{nodes[1]}},
+		// The walk goes past the successor list of the owner, which only knows node3 and node4.
+		{n: 2, expected: []*LocalNode{nodes[1], nodes[4]}},
+		{n: 3, expected: []*LocalNode{nodes[1], nodes[4], nodes[0]}},
+		// The ring has only three live hosts.
+		{n: 5, expected: []*LocalNode{nodes[1], nodes[4], nodes[0]}},
+	}
+	for _, tc := range testcases {
+		replicas, err := nodes[0].FindReplicas(ctx, id, tc.n)
+		assert.Nil(t, err)
+		hosts := make([]string, len(replicas))
+		for i, replica := range replicas {
+			hosts[i] = replica.Reference().Host
+		}
+		expected := make([]string, len(tc.expected))
+		for i, node := range tc.expected {
+			expected[i] = node.Host
+		}
+		assert.Equal(t, expected, hosts, "n = %d", tc.n)
+	}
+}
+
+func TestLocalNode_Ring(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(5)
+	expected := nodeKeys([]RingNode{nodes[0], nodes[1], nodes[2], nodes[3], nodes[4]})
+
+	// Every successor list knows only two nodes, so the walk goes beyond them.
+	ring, err := nodes[0].Ring(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, nodeKeys(ring))
+
+	// The walk goes on from the next farthest successor, if the farthest one doesn't answer.
+	nodes[2].Shutdown()
+	ring, err = nodes[0].Ring(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, nodeKeys(ring))
+}
+
+func TestLocalNode_Ring_VirtualNodes(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(4)
+	for _, node := range nodes {
+		node.CreateRing()
+	}
+	// node3 is a virtual node on the host of node2, which successor lists after node2 skip.
+	nodes[2].Host, nodes[2].VNode = nodes[1].Host, 1
+	for i, node := range nodes {
+		node.setSuccessors(nodes[(i+1)%4], []RingNode{nodes[(i+2)%4], nodes[(i+3)%4]})
+	}
+	assert.Equal(t, nodeKeys([]RingNode{nodes[1], nodes[3]}), nodeKeys(nodes[0].successors.nodes))
+
+	ring, err := nodes[0].Ring(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, nodeKeys([]RingNode{nodes[0], nodes[1], nodes[2], nodes[3]}), nodeKeys(ring))
+}
+
+func TestExclusiveNodeList(t *testing.T) {
+	nodes := createNodes(4)
+	nodes[2].Host, nodes[2].VNode = nodes[1].Host, 1
+	list := newNodeList(3)
+
+	// A list keeps a node per host, so that a host failing takes out one entry.
+	assert.True(t, list.join(0, []RingNode{nodes[1], nodes[2], nodes[3]}))
+	assert.Equal(t, nodeKeys([]RingNode{nodes[1], nodes[3]}), nodeKeys(list.nodes))
+
+	// A new head replaces the node of its host.
+	assert.True(t, list.appendHead(nodes[2]))
+	assert.Equal(t, nodeKeys([]RingNode{nodes[2], nodes[3]}), nodeKeys(list.nodes))
+	assert.False(t, list.join(1, []RingNode{nodes[1], nodes[3]}))
+	assert.True(t, list.appendHead(nodes[0]))
+	assert.Equal(t, nodeKeys([]RingNode{nodes[0], nodes[2], nodes[3]}), nodeKeys(list.nodes))
+}
+
+func TestLocalNode_FindClosestPrecedingNode_UnstabilizedFingers(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(3)
+	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
+	_, err := node1.FindClosestPrecedingNode(ctx, node3.ID)
+	assert.Equal(t, ErrStabilizeNotCompleted, err)
+
+	// Only the first finger is set after joining, but the successor list knows node3.
+	node1.initSuccessors(node2)
+	node1.setSuccessors(node2, []RingNode{node3, node1})
+	node, err := node1.FindClosestPrecedingNode(ctx, big.NewInt(4).Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, node3.ID, node.Reference().ID)
+}
+
 func TestLocalNode_JoinSuccessors(t *testing.T) {
 	nodes := createNodes(3)
 	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
@@ -100,4 +260,40 @@ func TestLocalNode_PutSuccessor(t *testing.T) {
 	assert.Equal(t, node3.ID, node1.successors.nodes[0].Reference().ID)
 	assert.Equal(t, node2.ID, node1.successors.nodes[1].Reference().ID)
 	assert.Equal(t, node1.ID, node1.successors.nodes[2].Reference().ID)
+
+	// A known node moves to the head.
+	node1.PutSuccessor(node2)
+	assert.Equal(t, 3, len(node1.successors.nodes))
+	assert.Equal(t, node2.ID, node1.successors.nodes[0].Reference().ID)
+	assert.Equal(t, node3.ID, node1.successors.nodes[1].Reference().ID)
+	assert.Equal(t, node1.ID, node1.successors.nodes[2].Reference().ID)
+}
+
+func TestLocalNode_ChangeDetection(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(3)
+	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
+	node1.CreateRing()
+	node1.popChanged()
+
+	node1.PutSuccessor(node2)
+	assert.True(t, node1.popChanged())
+	node1.PutSuccessor(node2)
+	assert.False(t, node1.popChanged())
+
+	node1.JoinSuccessors(1, []RingNode{node3})
+	assert.True(t, node1.popChanged())
+	node1.JoinSuccessors(1, []RingNode{node3})
+	assert.False(t, node1.popChanged())
+
+	assert.NoError(t, node1.Notify(ctx, node3))
+	assert.True(t, node1.popChanged())
+	assert.NoError(t, node1.Notify(ctx, node3))
+	assert.False(t, node1.popChanged())
+}
+
+func TestNewLocalNode_Options(t *testing.T) {
+	node := NewLocalNode("gord", WithZone("zone-a"), WithMetadata(map[string]string{"port": "8080"}))
+	assert.Equal(t, "zone-a", node.Reference().Zone)
+	assert.Equal(t, "8080", node.Reference().Metadata["port"])
 }
@@ -3,6 +3,7 @@ package chord
 import (
 	"context"
 	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
 )
 
 // MockTransport does nothing
@@ -43,6 +44,31 @@ func (m *MockTransport) NotifyRPC(ctx context.Context, to *model.NodeRef, node *
 	return nil
 }
 
+// HandshakeRPC returns the local protocol
+func (m *MockTransport) HandshakeRPC(ctx context.Context, to *model.NodeRef, node *model.NodeRef) (Protocol, error) {
+	return LocalProtocol(), nil
+}
+
+// MerkleHashesRPC does nothing
+func (m *MockTransport) MerkleHashesRPC(ctx context.Context, to *model.NodeRef, rangeFrom model.HashID, rangeTo model.HashID, level int, indexes []int) ([][]byte, error) {
+	return nil, nil
+}
+
+// DigestsRPC does nothing
+func (m *MockTransport) DigestsRPC(ctx context.Context, to *model.NodeRef, rangeFrom model.HashID, rangeTo model.HashID) ([]storage.Digest, error) {
+	return nil, nil
+}
+
+// GetRecordsRPC does nothing
+func (m *MockTransport) GetRecordsRPC(ctx context.Context, to *model.NodeRef, keys []string) ([]*storage.Record, error) {
+	return nil, nil
+}
+
+// PutRecordsRPC does nothing
+func (m *MockTransport) PutRecordsRPC(ctx context.Context, to *model.NodeRef, records []*storage.Record) error {
+	return nil
+}
+
 // Shutdown does nothing
 func (m *MockTransport) Shutdown() {
 }
@@ -3,6 +3,7 @@ package chord
 import (
 	"context"
 	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
 )
 
 // RingNode represents a node of Chord Ring
@@ -15,6 +16,11 @@ type RingNode interface {
 	FindSuccessorByList(ctx context.Context, id model.HashID) (RingNode, error)
 	FindClosestPrecedingNode(ctx context.Context, id model.HashID) (RingNode, error)
 	Notify(ctx context.Context, node RingNode) error
+	Handshake(ctx context.Context, node RingNode) (Protocol, error)
+	MerkleHashes(ctx context.Context, from model.HashID, to model.HashID, level int, indexes []int) ([][]byte, error)
+	Digests(ctx context.Context, from model.HashID, to model.HashID) ([]storage.Digest, error)
+	GetRecords(ctx context.Context, keys []string) ([]*storage.Record, error)
+	PutRecords(ctx context.Context, records []*storage.Record) error
 }
 
 // Transport represents rpc to remote node
@@ -26,5 +32,10 @@ type Transport interface {
 	FindSuccessorByListRPC(ctx context.Context, to *model.NodeRef, id model.HashID) (RingNode, error)
 	FindClosestPrecedingNodeRPC(ctx context.Context, to *model.NodeRef, id model.HashID) (RingNode, error)
 	NotifyRPC(ctx context.Context, to *model.NodeRef, node *model.NodeRef) error
+	HandshakeRPC(ctx context.Context, to *model.NodeRef, node *model.NodeRef) (Protocol, error)
+	MerkleHashesRPC(ctx context.Context, to *model.NodeRef, rangeFrom model.HashID, rangeTo model.HashID, level int, indexes []int) ([][]byte, error)
+	DigestsRPC(ctx context.Context, to *model.NodeRef, rangeFrom model.HashID, rangeTo model.HashID) ([]storage.Digest, error)
+	GetRecordsRPC(ctx context.Context, to *model.NodeRef, keys []string) ([]*storage.Record, error)
+	PutRecordsRPC(ctx context.Context, to *model.NodeRef, records []*storage.Record) error
 	Shutdown()
 }
@@ -3,6 +3,7 @@ package chord
 import (
 	"context"
 	log "github.com/sirupsen/logrus"
+	"github.com/taisho6339/gord/storage"
 	"math/rand"
 	"time"
 )
@@ -18,16 +19,32 @@ type Process struct {
 	AliveStabilizer       Stabilizer
 	SuccessorStabilizer   Stabilizer
 	FingerTableStabilizer Stabilizer
-	Transport             Transport
-	IsShutdown            bool
+	RingMergeStabilizer   Stabilizer
+	// AntiEntropyStabilizer is set on start only if anti-entropy is enabled.
+	AntiEntropyStabilizer Stabilizer
+	// ReaperStabilizer is set on start only if the reaper is enabled.
+	ReaperStabilizer Stabilizer
+	Transport        Transport
+	// Storage keeps records on the host. Every virtual node on the host shares it.
+	Storage    storage.Engine
+	IsShutdown bool
 
-	opt *processOption
+	opt          *processOption
+	virtualNodes []*Process
 }
 
 type processOption struct {
-	stabilizerInterval time.Duration
-	timeoutConnNode    time.Duration
-	existNode          RingNode
+	minStabilizerInterval time.Duration
+	maxStabilizerInterval time.Duration
+	timeoutConnNode       time.Duration
+	existNode             RingNode
+	proximitySelection    bool
+	successorPolicy       SuccessorPolicy
+	storage               storage.Engine
+	replicationFactor     int
+	conflictPolicy        storage.ConflictPolicy
+	antiEntropyInterval   time.Duration
+	reapInterval          time.Duration
 }
 
 // ProcessOptionFunc is function to apply options to a process
@@ -35,14 +52,32 @@ type ProcessOptionFunc func(option *processOption)
 
 func newDefaultProcessOption() *processOption {
 	return &processOption{
-		stabilizerInterval: 50 * time.Millisecond,
-		timeoutConnNode:    1 * time.Second,
+		minStabilizerInterval: 50 * time.Millisecond,
+		maxStabilizerInterval: 2 * time.Second,
+		timeoutConnNode:       1 * time.Second,
+		replicationFactor:     defaultReplicationFactor,
 	}
 }
 
+// WithStabilizeInterval makes stabilizers run at a fixed interval.
 func WithStabilizeInterval(duration time.Duration) ProcessOptionFunc {
 	return func(option *processOption) {
-		option.stabilizerInterval = duration
+		option.minStabilizerInterval = duration
+		option.maxStabilizerInterval = duration
+	}
+}
+
+// WithMinStabilizeInterval sets the interval stabilizers use while the ring is changing.
+func WithMinStabilizeInterval(duration time.Duration) ProcessOptionFunc {
+	return func(option *processOption) {
+		option.minStabilizerInterval = duration
+	}
+}
+
+// WithMaxStabilizeInterval sets the upper limit of the interval stabilizers back off to while the ring is stable.
+func WithMaxStabilizeInterval(duration time.Duration) ProcessOptionFunc {
+	return func(option *processOption) {
+		option.maxStabilizerInterval = duration
 	}
 }
 
@@ -52,18 +87,133 @@ func WithExistNode(node RingNode) ProcessOptionFunc {
 	}
 }
 
+// WithProximitySelection makes the finger table stabilizer pick the closest node by RTT among valid candidates.
+func WithProximitySelection() ProcessOptionFunc {
+	return func(option *processOption) {
+		option.proximitySelection = true
+	}
+}
+
+// WithSuccessorPolicy sets a policy to choose preferred successors for replication and failover.
+func WithSuccessorPolicy(policy SuccessorPolicy) ProcessOptionFunc {
+	return func(option *processOption) {
+		option.successorPolicy = policy
+	}
+}
+
+// WithStorage sets an engine to keep records on the host.
+// Without it, records are kept in memory and lost when the process exits.
+func WithStorage(engine storage.Engine) ProcessOptionFunc {
+	return func(option *processOption) {
+		option.storage = engine
+	}
+}
+
+// WithReplicationFactor sets how many hosts keep each record, including the owner.
+func WithReplicationFactor(n int) ProcessOptionFunc {
+	return func(option *processOption) {
+		option.replicationFactor = n
+	}
+}
+
+// WithConflictPolicy sets which of concurrent values of a key replicas keep. It defaults to last-writer-wins.
+// Every node of a ring must use the same policy.
+func WithConflictPolicy(policy storage.ConflictPolicy) ProcessOptionFunc {
+	return func(option *processOption) {
+		option.conflictPolicy = policy
+	}
+}
+
+// WithAntiEntropy makes a process compare its records with replicas and repair them every interval.
+func WithAntiEntropy(interval time.Duration) ProcessOptionFunc {
+	return func(option *processOption) {
+		option.antiEntropyInterval = interval
+	}
+}
+
+// WithReaper makes a process delete expired records from the storage every interval.
+func WithReaper(interval time.Duration) ProcessOptionFunc {
+	return func(option *processOption) {
+		option.reapInterval = interval
+	}
+}
+
 // NewProcess creates a process.
 func NewProcess(localNode *LocalNode, transport Transport) *Process {
 	process := &Process{
 		LocalNode: localNode,
 		Transport: transport,
+		Storage:   storage.NewMemoryEngine(),
 	}
+	localNode.storage = process.Storage
 	process.AliveStabilizer = NewAliveStabilizer(localNode)
 	process.SuccessorStabilizer = NewSuccessorStabilizer(localNode)
 	process.FingerTableStabilizer = NewFingerTableStabilizer(localNode)
+	process.RingMergeStabilizer = NewRingMergeStabilizer(localNode)
 	return process
 }
 
+// NewWeightedProcess creates a process which runs weight virtual nodes on a host.
+// The more virtual nodes a host runs, the more of the key space it owns.
+// newTransport creates a transport for each virtual node.
+func NewWeightedProcess(host string, weight int, newTransport func(node *LocalNode) Transport, opts ...LocalNodeOptionFunc) *Process {
+	if weight > 1 {
+		// Peers which can't route requests to virtual nodes would mis-route them.
+		opts = append(append([]LocalNodeOptionFunc{}, opts...), WithRequiredFeatures(FeatureVirtualNodes))
+	}
+	localNode := NewLocalNode(host, opts...)
+	process := NewProcess(localNode, newTransport(localNode))
+	for i := 1; i < weight; i++ {
+		vnodeOpts := append(append([]LocalNodeOptionFunc{}, opts...), WithVirtualNode(i))
+		vnode := NewLocalNode(host, vnodeOpts...)
+		process.virtualNodes = append(process.virtualNodes, NewProcess(vnode, newTransport(vnode)))
+	}
+	return process
+}
+
+// VirtualNodes returns processes of every virtual node on the host, including this process.
+func (p *Process) VirtualNodes() []*Process {
+	return append([]*Process{p}, p.virtualNodes...)
+}
+
+// VirtualNode returns the process of the virtual node of index.
+// It returns nil if the host doesn't run the virtual node.
+func (p *Process) VirtualNode(index int) *Process {
+	for _, vp := range p.VirtualNodes() {
+		if vp.VNode == index {
+			return vp
+		}
+	}
+	return nil
+}
+
+// Watch registers a handler called on every ring membership change of every virtual node on the host.
+// It returns a function to unregister the handler.
+func (p *Process) Watch(handler RingEventHandler) func() {
+	unwatches := make([]func(), 0, len(p.virtualNodes)+1)
+	for _, vp := range p.VirtualNodes() {
+		unwatches = append(unwatches, vp.LocalNode.Watch(handler))
+	}
+	return func() {
+		for _, unwatch := range unwatches {
+			unwatch()
+		}
+	}
+}
+
+// OwnershipShare returns a fraction of the key space which every virtual node on the host owns in total.
+func (p *Process) OwnershipShare(ctx context.Context) (float64, error) {
+	var total float64
+	for _, vp := range p.VirtualNodes() {
+		share, err := vp.LocalNode.OwnershipShare(ctx)
+		if err != nil {
+			return 0, err
+		}
+		total += share
+	}
+	return total, nil
+}
+
 // Start starts a process.
 // Creates or joins in chord ring and starts some stabilizers of a process.
 func (p *Process) Start(ctx context.Context, opts ...ProcessOptionFunc) error {
@@ -71,13 +221,43 @@ func (p *Process) Start(ctx context.Context, opts ...ProcessOptionFunc) error {
 	for _, opt := range opts {
 		opt(p.opt)
 	}
-	if p.opt.existNode != nil && p.opt.existNode.Reference().Host == p.Host {
+	if p.opt.existNode != nil && p.opt.existNode.Reference().Key() == p.Key() {
 		log.Fatalf("exist node must be different from local node.")
 	}
+	if p.opt.successorPolicy != nil {
+		p.LocalNode.policy = p.opt.successorPolicy
+	}
+	p.LocalNode.replicationFactor = p.opt.replicationFactor
+	p.LocalNode.conflictPolicy = p.opt.conflictPolicy
+	if p.opt.storage != nil {
+		p.Storage = p.opt.storage
+		p.LocalNode.storage = p.opt.storage
+	}
+	if s, ok := p.FingerTableStabilizer.(*FingerTableStabilizer); ok && p.opt.proximitySelection {
+		s.EnableProximitySelection()
+	}
 	if err := p.activate(ctx, p.opt.existNode); err != nil {
 		return err
 	}
-	p.scheduleStabilizers(ctx, p.opt.stabilizerInterval, p.SuccessorStabilizer, p.FingerTableStabilizer, p.AliveStabilizer)
+	interval := newStabilizeInterval(p.opt.minStabilizerInterval, p.opt.maxStabilizerInterval)
+	stabilizers := []Stabilizer{p.SuccessorStabilizer, p.FingerTableStabilizer, p.AliveStabilizer, p.RingMergeStabilizer}
+	if p.opt.antiEntropyInterval > 0 {
+		p.AntiEntropyStabilizer = NewAntiEntropyStabilizer(p.LocalNode, p.opt.replicationFactor, p.opt.antiEntropyInterval)
+		stabilizers = append(stabilizers, p.AntiEntropyStabilizer)
+	}
+	// Virtual nodes share the storage, so only the first one reaps it.
+	if p.opt.reapInterval > 0 && p.VNode == 0 {
+		p.ReaperStabilizer = NewReaperStabilizer(p.LocalNode, p.opt.reapInterval)
+		stabilizers = append(stabilizers, p.ReaperStabilizer)
+	}
+	p.scheduleStabilizers(ctx, interval, stabilizers...)
+	// Other virtual nodes join in the ring via this node.
+	for _, vp := range p.virtualNodes {
+		vnodeOpts := append(append([]ProcessOptionFunc{}, opts...), WithExistNode(p.LocalNode), WithStorage(p.Storage))
+		if err := vp.Start(ctx, vnodeOpts...); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -92,23 +272,43 @@ func (p *Process) activate(ctx context.Context, existNode RingNode) error {
 	return nil
 }
 
-// Shutdown stops process
+// Shutdown stops process and its virtual nodes.
+// The storage is closed once, after every virtual node sharing it has stopped.
 func (p *Process) Shutdown() {
+	for _, vp := range p.VirtualNodes() {
+		vp.stop()
+	}
+	if err := p.Storage.Close(); err != nil {
+		log.Errorf("failed to close storage. err = %v", err)
+	}
+}
+
+// stop stops the node and its transport, leaving the storage open.
+func (p *Process) stop() {
 	p.IsShutdown = true
 	p.LocalNode.Shutdown()
 	p.Transport.Shutdown()
 }
 
-func (p *Process) scheduleStabilizers(ctx context.Context, interval time.Duration, stabilizers ...Stabilizer) {
-	if p.IsShutdown {
-		return
-	}
+// scheduleStabilizers runs stabilizers until the process shuts down.
+// While stabilizers change nothing, the interval between rounds grows exponentially.
+// When a change is detected, even while sleeping, the interval snaps back to the minimum.
+func (p *Process) scheduleStabilizers(ctx context.Context, interval *stabilizeInterval, stabilizers ...Stabilizer) {
 	go func() {
-		for _, s := range stabilizers {
-			s.Stabilize(ctx)
+		for !p.IsShutdown {
+			for _, s := range stabilizers {
+				s.Stabilize(ctx)
+			}
+			timer := time.NewTimer(interval.next(p.popChanged()))
+			select {
+			case <-timer.C:
+			case <-p.changeCh:
+				timer.Stop()
+				interval.reset()
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
 		}
-		time.AfterFunc(interval, func() {
-			p.scheduleStabilizers(ctx, interval, stabilizers...)
-		})
 	}()
 }
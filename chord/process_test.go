@@ -5,6 +5,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/taisho6339/gord/pkg/model"
 	"github.com/taisho6339/gord/pkg/test"
+	"github.com/taisho6339/gord/storage"
 	"math/big"
 	"testing"
 	"time"
@@ -175,3 +176,70 @@ func TestProcess_Node_Failure(t *testing.T) {
 		assert.Equal(t, process3.ID, suc.Reference().ID)
 	})
 }
+
+func TestProcess_WeightedNode(t *testing.T) {
+	ctx := context.Background()
+	newTransport := func(node *LocalNode) Transport {
+		return mockTransport
+	}
+	process1 := NewWeightedProcess("weighted1", 3, newTransport)
+	process2 := NewWeightedProcess("weighted2", 1, newTransport)
+	defer process1.Shutdown()
+	defer process2.Shutdown()
+	assert.Equal(t, 3, len(process1.VirtualNodes()))
+	assert.Equal(t, 1, len(process2.VirtualNodes()))
+	assert.Equal(t, "weighted1#2", process1.VirtualNode(2).Key())
+	assert.Nil(t, process1.VirtualNode(3))
+
+	// Fingers of real hash IDs take a round for each, so stabilize quickly.
+	engine := storage.NewMemoryEngine()
+	assert.NoError(t, process1.Start(ctx, WithStabilizeInterval(5*time.Millisecond), WithStorage(engine)))
+	assert.NoError(t, process2.Start(ctx, WithStabilizeInterval(5*time.Millisecond), WithExistNode(process1.LocalNode)))
+	// Virtual nodes on a host share its storage.
+	for _, vp := range process1.VirtualNodes() {
+		assert.True(t, engine == vp.Storage)
+	}
+	assert.False(t, engine == process2.Storage)
+	test.WaitCheckFuncWithTimeout(func() {
+		t.Fatal("test failed by timeout.")
+	}, func() bool {
+		for _, vp := range process1.VirtualNodes() {
+			succ, err := process2.FindSuccessorByTable(ctx, vp.ID)
+			if err != nil || vp.Key() != succ.Reference().Key() {
+				return false
+			}
+		}
+		share1, err1 := process1.OwnershipShare(ctx)
+		share2, err2 := process2.OwnershipShare(ctx)
+		return err1 == nil && err2 == nil && share1+share2 > 0.999999 && share1+share2 < 1.000001
+	}, 10*time.Second)
+}
+
+// closeCountingEngine counts how many times it is closed, and whether every node had stopped by then.
+type closeCountingEngine struct {
+	storage.Engine
+	process    *Process
+	closes     int
+	allStopped bool
+}
+
+func (e *closeCountingEngine) Close() error {
+	e.closes++
+	e.allStopped = true
+	for _, vp := range e.process.VirtualNodes() {
+		e.allStopped = e.allStopped && vp.IsShutdown
+	}
+	return e.Engine.Close()
+}
+
+func TestProcess_Shutdown_ClosesStorageOnce(t *testing.T) {
+	process := NewWeightedProcess("weighted", 3, func(node *LocalNode) Transport {
+		return mockTransport
+	})
+	engine := &closeCountingEngine{Engine: storage.NewMemoryEngine(), process: process}
+	assert.NoError(t, process.Start(context.Background(), WithStorage(engine)))
+
+	process.Shutdown()
+	assert.Equal(t, 1, engine.closes)
+	assert.True(t, engine.allStopped)
+}
@@ -0,0 +1,132 @@
+package chord
+
+import (
+	"fmt"
+	"github.com/taisho6339/gord/pkg/model"
+	"sync"
+)
+
+const (
+	// ProtocolMajorVersion is the major version of the protocol this node speaks.
+	// Bump it on a change to the internal protocol or to routing semantics which older nodes can't work with.
+	// Peers of another major version are refused, whether they are older or newer.
+	ProtocolMajorVersion = 1
+	// ProtocolVersion is the minor version of the protocol this node speaks.
+	// Bump it on a change which older nodes can work with, such as a new feature.
+	ProtocolVersion = 1
+	// MinCompatibleProtocolVersion is the oldest minor version of peers this node works with.
+	// Peers built before versioning are version 0, so that a ring can be upgraded node by node.
+	// New behaviour is switched on through features, which those peers lack.
+	MinCompatibleProtocolVersion = 0
+	// legacyProtocolMajorVersion is the major version of peers built before versioning.
+	legacyProtocolMajorVersion = 1
+)
+
+// Feature represents an optional behaviour of the protocol.
+type Feature string
+
+const (
+	// FeatureZones represents that a node propagates zone labels.
+	FeatureZones Feature = "zones"
+	// FeatureMetadata represents that a node propagates metadata labels.
+	FeatureMetadata Feature = "metadata"
+	// FeatureVirtualNodes represents that a node routes requests to virtual nodes.
+	FeatureVirtualNodes Feature = "vnodes"
+)
+
+// SupportedFeatures are features this node supports.
+var SupportedFeatures = []Feature{FeatureZones, FeatureMetadata, FeatureVirtualNodes}
+
+// Protocol represents a protocol version and a feature set of a node.
+type Protocol struct {
+	Major    int
+	Version  int
+	Features []Feature
+}
+
+// LocalProtocol returns the protocol of this build.
+func LocalProtocol() Protocol {
+	return Protocol{
+		Major:    ProtocolMajorVersion,
+		Version:  ProtocolVersion,
+		Features: SupportedFeatures,
+	}
+}
+
+// LegacyProtocol returns the protocol of peers built before versioning, which is version 0 with no features.
+// Such peers send no protocol, and have no Handshake RPC.
+func LegacyProtocol() Protocol {
+	return Protocol{
+		Major: legacyProtocolMajorVersion,
+	}
+}
+
+// Supports reports whether the protocol has a feature.
+func (p Protocol) Supports(feature Feature) bool {
+	for _, f := range p.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// protocolTable keeps protocols of peers and decides which features are negotiated.
+type protocolTable struct {
+	local    Protocol
+	required []Feature
+	peers    map[string]Protocol
+	lock     sync.Mutex
+}
+
+func newProtocolTable() *protocolTable {
+	return &protocolTable{
+		local: LocalProtocol(),
+		peers: map[string]Protocol{},
+	}
+}
+
+// check returns an error if a peer is incompatible with this node.
+func (t *protocolTable) check(peer *model.NodeRef, protocol Protocol) error {
+	if protocol.Major != ProtocolMajorVersion {
+		return fmt.Errorf("%w: Host[%s] speaks protocol major version %d, but version %d is required",
+			ErrIncompatibleProtocol, peer.Key(), protocol.Major, ProtocolMajorVersion)
+	}
+	if protocol.Version < MinCompatibleProtocolVersion {
+		return fmt.Errorf("%w: Host[%s] speaks protocol version %d, but version %d or later is required",
+			ErrIncompatibleProtocol, peer.Key(), protocol.Version, MinCompatibleProtocolVersion)
+	}
+	for _, feature := range t.required {
+		if !protocol.Supports(feature) {
+			return fmt.Errorf("%w: Host[%s] doesn't support required feature %q",
+				ErrIncompatibleProtocol, peer.Key(), feature)
+		}
+	}
+	return nil
+}
+
+func (t *protocolTable) observe(peer *model.NodeRef, protocol Protocol) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.peers[peer.Key()] = protocol
+}
+
+// enabled reports whether every given neighbour is known to support a feature, as well as this node.
+func (t *protocolTable) enabled(feature Feature, neighbours []RingNode) bool {
+	if !t.local.Supports(feature) {
+		return false
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, node := range neighbours {
+		// Local nodes run the same build.
+		if _, ok := node.(*LocalNode); ok {
+			continue
+		}
+		protocol, ok := t.peers[node.Reference().Key()]
+		if !ok || !protocol.Supports(feature) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package chord
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// peerNode is a node which speaks a given protocol.
+type peerNode struct {
+	*LocalNode
+	protocol Protocol
+}
+
+func (p *peerNode) Handshake(_ context.Context, _ RingNode) (Protocol, error) {
+	return p.protocol, nil
+}
+
+func TestProtocolTable_Check(t *testing.T) {
+	node := NewLocalNode("gord", WithRequiredFeatures(FeatureVirtualNodes))
+	peer := NewLocalNode("peer")
+	testcases := []struct {
+		protocol   Protocol
+		compatible bool
+	}{
+		{
+			protocol:   LocalProtocol(),
+			compatible: true,
+		},
+		{
+			protocol:   Protocol{Major: ProtocolMajorVersion, Version: ProtocolVersion, Features: []Feature{FeatureVirtualNodes}},
+			compatible: true,
+		},
+		{
+			protocol:   Protocol{Major: ProtocolMajorVersion, Version: ProtocolVersion + 1, Features: SupportedFeatures},
+			compatible: true,
+		},
+		{
+			protocol:   Protocol{Major: ProtocolMajorVersion, Version: ProtocolVersion, Features: []Feature{FeatureZones}},
+			compatible: false,
+		},
+		{
+			protocol:   Protocol{Major: ProtocolMajorVersion, Version: MinCompatibleProtocolVersion - 1, Features: SupportedFeatures},
+			compatible: false,
+		},
+		{
+			protocol:   Protocol{Major: ProtocolMajorVersion + 1, Version: ProtocolVersion, Features: SupportedFeatures},
+			compatible: false,
+		},
+		{
+			protocol:   Protocol{Major: ProtocolMajorVersion - 1, Version: ProtocolVersion, Features: SupportedFeatures},
+			compatible: false,
+		},
+		{
+			// A peer built before versioning is compatible, but lacks the required feature.
+			protocol:   LegacyProtocol(),
+			compatible: false,
+		},
+	}
+	for _, testcase := range testcases {
+		err := node.NegotiateProtocol(peer.NodeRef, testcase.protocol)
+		if testcase.compatible {
+			assert.Nil(t, err)
+			continue
+		}
+		assert.True(t, errors.Is(err, ErrIncompatibleProtocol))
+	}
+}
+
+func TestLocalNode_FeatureEnabled(t *testing.T) {
+	nodes := createNodes(2)
+	peer := &peerNode{
+		LocalNode: nodes[1],
+		protocol:  Protocol{Major: ProtocolMajorVersion, Version: MinCompatibleProtocolVersion},
+	}
+	nodes[0].predecessor = peer
+	nodes[0].initSuccessors(peer)
+
+	// The protocol of the peer is unknown yet.
+	assert.False(t, nodes[0].FeatureEnabled(FeatureZones))
+
+	assert.Nil(t, nodes[0].NegotiateProtocol(peer.NodeRef, peer.protocol))
+	assert.False(t, nodes[0].FeatureEnabled(FeatureZones))
+
+	assert.Nil(t, nodes[0].NegotiateProtocol(peer.NodeRef, LocalProtocol()))
+	assert.True(t, nodes[0].FeatureEnabled(FeatureZones))
+}
+
+func TestLocalNode_JoinRing_IncompatiblePeer(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(2)
+	nodes[0].CreateRing()
+	peer := &peerNode{
+		LocalNode: nodes[0],
+		protocol:  Protocol{Major: ProtocolMajorVersion, Version: ProtocolVersion, Features: []Feature{FeatureZones}},
+	}
+
+	joining := NewLocalNode("gord-vnode", WithRequiredFeatures(FeatureVirtualNodes))
+	err := joining.JoinRing(ctx, peer)
+	assert.True(t, errors.Is(err, ErrIncompatibleProtocol))
+	assert.Nil(t, joining.successors)
+
+	assert.Nil(t, nodes[1].JoinRing(ctx, nodes[0]))
+}
+
+func TestLocalNode_JoinRing_LegacyPeer(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(2)
+	nodes[0].CreateRing()
+	legacy := &peerNode{
+		LocalNode: nodes[0],
+		protocol:  LegacyProtocol(),
+	}
+
+	// A ring of nodes built before versioning can be upgraded node by node, with new features switched off.
+	assert.Nil(t, nodes[1].JoinRing(ctx, legacy))
+	nodes[1].predecessor = legacy
+	assert.False(t, nodes[1].FeatureEnabled(FeatureZones))
+}
@@ -0,0 +1,73 @@
+package chord
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// maxProximityCandidates limits how many nodes are measured for a finger.
+	maxProximityCandidates = 8
+	// rttSmoothingFactor is a weight of a new sample in the smoothed RTT.
+	rttSmoothingFactor = 0.2
+)
+
+// rttTable keeps smoothed round trip times to nodes.
+type rttTable struct {
+	rtts map[string]time.Duration
+	lock sync.Mutex
+}
+
+func newRTTTable() *rttTable {
+	return &rttTable{
+		rtts: map[string]time.Duration{},
+	}
+}
+
+// measure pings a node and returns the smoothed round trip time to it.
+func (t *rttTable) measure(ctx context.Context, node RingNode) (time.Duration, error) {
+	start := time.Now()
+	if err := node.Ping(ctx); err != nil {
+		t.lock.Lock()
+		delete(t.rtts, node.Reference().Host)
+		t.lock.Unlock()
+		return 0, err
+	}
+	sample := time.Since(start)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	host := node.Reference().Host
+	rtt, ok := t.rtts[host]
+	if !ok {
+		t.rtts[host] = sample
+		return sample, nil
+	}
+	rtt = time.Duration((1-rttSmoothingFactor)*float64(rtt) + rttSmoothingFactor*float64(sample))
+	t.rtts[host] = rtt
+	return rtt, nil
+}
+
+// closest returns the candidate with the lowest round trip time.
+// If no candidate answers, it returns the fallback.
+func (t *rttTable) closest(ctx context.Context, fallback RingNode, candidates []RingNode) RingNode {
+	var (
+		closest RingNode
+		minRTT  time.Duration
+	)
+	for _, candidate := range candidates {
+		rtt, err := t.measure(ctx, candidate)
+		if err != nil {
+			continue
+		}
+		if closest == nil || rtt < minRTT {
+			closest = candidate
+			minRTT = rtt
+		}
+	}
+	if closest == nil {
+		return fallback
+	}
+	return closest
+}
@@ -0,0 +1,78 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/pkg/model"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// latencyNode is a ring node which answers ping after its latency.
+type latencyNode struct {
+	*LocalNode
+	latency    time.Duration
+	successors []RingNode
+}
+
+func (n *latencyNode) Ping(_ context.Context) error {
+	time.Sleep(n.latency)
+	return nil
+}
+
+func (n *latencyNode) GetSuccessors(_ context.Context) ([]RingNode, error) {
+	return n.successors, nil
+}
+
+func newLatencyNode(id int64, latency time.Duration) *latencyNode {
+	node := NewLocalNode(fmt.Sprintf("gord%d", id))
+	node.ID = model.BytesToHashID(big.NewInt(id).Bytes())
+	return &latencyNode{
+		LocalNode: node,
+		latency:   latency,
+	}
+}
+
+func TestFingerTableStabilizer_SelectFinger(t *testing.T) {
+	ctx := context.Background()
+	local := NewLocalNode("local")
+	local.ID = model.BytesToHashID(big.NewInt(1).Bytes())
+	local.fingerTable = NewFingerTable(local.ID)
+
+	// The interval of finger 3 is [9, 17).
+	succ := newLatencyNode(10, 20*time.Millisecond)
+	near := newLatencyNode(12, time.Millisecond)
+	far := newLatencyNode(16, 10*time.Millisecond)
+	outside := newLatencyNode(20, 0)
+	succ.successors = []RingNode{near, far, outside}
+
+	stabilizer := NewFingerTableStabilizer(local)
+	candidates := stabilizer.proximityCandidates(ctx, succ)
+	assert.Nil(t, candidates)
+	assert.Equal(t, succ, stabilizer.selectFinger(ctx, 3, succ, succ.successors))
+
+	stabilizer.EnableProximitySelection()
+	candidates = stabilizer.proximityCandidates(ctx, succ)
+	assert.Equal(t, near, stabilizer.selectFinger(ctx, 3, succ, candidates))
+	// The first finger must be the exact successor.
+	assert.Equal(t, succ, stabilizer.selectFinger(ctx, 0, succ, candidates))
+	// The successor is out of the interval of finger 2, [5, 9).
+	assert.Equal(t, succ, stabilizer.selectFinger(ctx, 2, succ, candidates))
+}
+
+func TestRTTTable_Measure(t *testing.T) {
+	ctx := context.Background()
+	node := newLatencyNode(1, 10*time.Millisecond)
+	rtts := newRTTTable()
+	first, err := rtts.measure(ctx, node)
+	assert.NoError(t, err)
+	assert.True(t, first >= 10*time.Millisecond)
+
+	node.latency = 0
+	second, err := rtts.measure(ctx, node)
+	assert.NoError(t, err)
+	assert.True(t, second < first)
+	assert.True(t, second >= 8*time.Millisecond)
+}
@@ -0,0 +1,301 @@
+package chord
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultReplicationFactor is N, how many hosts keep each record by default, including the owner.
+	defaultReplicationFactor = 3
+	// replicaRequestTimeout bounds requests to replicas.
+	// They outlive the request of a client, so that writes and read repairs complete in the background.
+	replicaRequestTimeout = 5 * time.Second
+)
+
+// Consistency represents how many of N replicas must answer a request before it returns.
+type Consistency int
+
+const (
+	// ConsistencyQuorum waits for a majority of replicas. It is the default.
+	ConsistencyQuorum Consistency = iota
+	// ConsistencyOne waits for any replica.
+	ConsistencyOne
+	// ConsistencyAll waits for every replica.
+	ConsistencyAll
+)
+
+// required returns how many of n replicas must answer.
+func (c Consistency) required(n int) int {
+	switch c {
+	case ConsistencyOne:
+		return 1
+	case ConsistencyAll:
+		return n
+	default:
+		return n/2 + 1
+	}
+}
+
+type quorumOption struct {
+	consistency Consistency
+	n           int
+	r           int
+	w           int
+	// ownerResolved is called with the owner of the key once the preference list is found.
+	ownerResolved func(owner *model.NodeRef)
+}
+
+// QuorumOptionFunc is function to apply options to a read or a write of records
+type QuorumOptionFunc func(option *quorumOption)
+
+// WithConsistency sets how many replicas a request waits for, unless R or W is set explicitly.
+func WithConsistency(consistency Consistency) QuorumOptionFunc {
+	return func(option *quorumOption) {
+		option.consistency = consistency
+	}
+}
+
+// WithReplicas sets N, how many replicas of the preference list of a key a request is sent to.
+func WithReplicas(n int) QuorumOptionFunc {
+	return func(option *quorumOption) {
+		option.n = n
+	}
+}
+
+// WithReadQuorum sets R, how many replicas must answer a read.
+func WithReadQuorum(r int) QuorumOptionFunc {
+	return func(option *quorumOption) {
+		option.r = r
+	}
+}
+
+// WithWriteQuorum sets W, how many replicas must acknowledge a write.
+func WithWriteQuorum(w int) QuorumOptionFunc {
+	return func(option *quorumOption) {
+		option.w = w
+	}
+}
+
+// WithOwnerResolved calls f with the owner of the key, which heads the preference list, once a request has found the list.
+func WithOwnerResolved(f func(owner *model.NodeRef)) QuorumOptionFunc {
+	return func(option *quorumOption) {
+		option.ownerResolved = f
+	}
+}
+
+func (l *LocalNode) newQuorumOption(opts []QuorumOptionFunc) (*quorumOption, error) {
+	option := &quorumOption{
+		n: l.replicationFactor,
+	}
+	for _, opt := range opts {
+		opt(option)
+	}
+	if option.n < 1 {
+		return nil, fmt.Errorf("%w: N = %d", ErrInvalidQuorum, option.n)
+	}
+	if option.r == 0 {
+		option.r = option.consistency.required(option.n)
+	}
+	if option.w == 0 {
+		option.w = option.consistency.required(option.n)
+	}
+	if option.r < 1 || option.r > option.n || option.w < 1 || option.w > option.n {
+		return nil, fmt.Errorf("%w: N = %d, R = %d, W = %d", ErrInvalidQuorum, option.n, option.r, option.w)
+	}
+	return option, nil
+}
+
+// resolved reports the owner at the head of a preference list.
+func (o *quorumOption) resolved(replicas []RingNode) {
+	if o.ownerResolved != nil && len(replicas) > 0 {
+		o.ownerResolved(replicas[0].Reference())
+	}
+}
+
+type replicaResponse struct {
+	replica RingNode
+	record  *storage.Record
+	err     error
+}
+
+// fanOut sends a request to every replica in parallel, and returns responses as soon as required replicas have answered.
+// Requests still in flight go on in the background, and their responses arrive on the returned channel,
+// which is closed once every replica has answered.
+func fanOut(ctx context.Context, replicas []RingNode, required int, request func(ctx context.Context, replica RingNode) (*storage.Record, error)) ([]*replicaResponse, <-chan *replicaResponse, error) {
+	if len(replicas) < required {
+		return nil, nil, fmt.Errorf("%w: %d replicas are alive, but %d are required", ErrQuorumNotReached, len(replicas), required)
+	}
+	requestCtx, cancel := context.WithTimeout(context.Background(), replicaRequestTimeout)
+	responses := make(chan *replicaResponse, len(replicas))
+	wg := &sync.WaitGroup{}
+	for _, replica := range replicas {
+		wg.Add(1)
+		go func(replica RingNode) {
+			defer wg.Done()
+			record, err := request(requestCtx, replica)
+			responses <- &replicaResponse{replica: replica, record: record, err: err}
+		}(replica)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(responses)
+	}()
+
+	var answered []*replicaResponse
+	failed := 0
+	for len(answered) < required {
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case res := <-responses:
+			if res.err == nil {
+				answered = append(answered, res)
+				continue
+			}
+			log.Warnf("Host[%s] failed to answer a replica request. err = %v", res.replica.Reference().Key(), res.err)
+			failed++
+			if len(replicas)-failed < required {
+				return nil, nil, fmt.Errorf("%w: %d of %d replicas failed. last err = %v", ErrQuorumNotReached, failed, len(replicas), res.err)
+			}
+		}
+	}
+	return answered, responses, nil
+}
+
+// Get reads a record of a key from R of N replicas in the preference list of the key.
+// Records replicas return are merged by their versions following the conflict policy,
+// so the record may hold siblings, which a client resolves by writing a value with the record's Context.
+// Replicas found stale, including ones answering after the read has returned, are repaired with the merged record in the background.
+func (l *LocalNode) Get(ctx context.Context, key string, opts ...QuorumOptionFunc) (*storage.Record, error) {
+	if l.isShutdown {
+		return nil, ErrNodeUnavailable
+	}
+	option, err := l.newQuorumOption(opts)
+	if err != nil {
+		return nil, err
+	}
+	replicas, err := l.FindReplicas(ctx, model.NewHashID(key), option.n)
+	if err != nil {
+		return nil, err
+	}
+	option.resolved(replicas)
+	answered, late, err := fanOut(ctx, replicas, option.r, func(ctx context.Context, replica RingNode) (*storage.Record, error) {
+		records, err := replica.GetRecords(ctx, []string{key})
+		if err != nil || len(records) == 0 {
+			return nil, err
+		}
+		return records[0], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	merged := l.resolveRecords(answered)
+	if merged == nil {
+		return nil, storage.ErrNotFound
+	}
+	go l.readRepair(answered, late)
+	return merged, nil
+}
+
+// resolveRecords merges records in responses, or returns nil if no replica holds the key.
+func (l *LocalNode) resolveRecords(responses []*replicaResponse) *storage.Record {
+	var merged *storage.Record
+	for _, res := range responses {
+		switch {
+		case res.record == nil:
+		case merged == nil:
+			merged = res.record
+		default:
+			merged = storage.Resolve(l.conflictPolicy, merged, res.record)
+		}
+	}
+	return merged
+}
+
+// readRepair merges records every replica has answered a read with,
+// and writes the merged record to replicas which answered with another record or none.
+func (l *LocalNode) readRepair(answered []*replicaResponse, late <-chan *replicaResponse) {
+	ctx, cancel := context.WithTimeout(context.Background(), replicaRequestTimeout)
+	defer cancel()
+	responses := answered
+	for res := range late {
+		if res.err == nil {
+			responses = append(responses, res)
+		}
+	}
+	merged := l.resolveRecords(responses)
+	digest := merged.Digest()
+	for _, res := range responses {
+		if res.record != nil && bytes.Equal(res.record.Digest(), digest) {
+			continue
+		}
+		if err := res.replica.PutRecords(ctx, []*storage.Record{merged}); err != nil {
+			log.Warnf("Host[%s] failed to repair a replica of %s on Host[%s]. err = %v", l.Key(), merged.Key, res.replica.Reference().Key(), err)
+			continue
+		}
+		log.Infof("Host[%s] repaired a stale replica of %s on Host[%s].", l.Key(), merged.Key, res.replica.Reference().Key())
+	}
+}
+
+// Put writes the value of a record to N replicas in the preference list of its key, and returns once W of them acknowledge it.
+// The write goes on to the other replicas in the background.
+// The version of the record is the context the value is based on, which is the Context of a record read before,
+// or nil for a blind write. The value is written with a new version descending from the context, which is returned.
+// The value expires at ExpiresAt of the record, unless it is 0.
+func (l *LocalNode) Put(ctx context.Context, record *storage.Record, opts ...QuorumOptionFunc) (*storage.Version, error) {
+	if l.isShutdown {
+		return nil, ErrNodeUnavailable
+	}
+	option, err := l.newQuorumOption(opts)
+	if err != nil {
+		return nil, err
+	}
+	replicas, err := l.FindReplicas(ctx, record.ID(), option.n)
+	if err != nil {
+		return nil, err
+	}
+	option.resolved(replicas)
+	version, err := l.newVersion(record.Version)
+	if err != nil {
+		return nil, err
+	}
+	versioned := &storage.Record{
+		Key:       record.Key,
+		Value:     record.Value,
+		Version:   version,
+		ExpiresAt: record.ExpiresAt,
+	}
+	_, _, err = fanOut(ctx, replicas, option.w, func(ctx context.Context, replica RingNode) (*storage.Record, error) {
+		return nil, replica.PutRecords(ctx, []*storage.Record{versioned})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versioned.Version, nil
+}
+
+// newVersion returns a version of a write this node coordinates, which descends from context.
+// A context with a timestamp too far ahead of the clock of this node is refused, since clients may send any context.
+func (l *LocalNode) newVersion(context *storage.Version) (*storage.Version, error) {
+	version := storage.MergeVersions(context)
+	// The timestamp must be later than any write the context has seen, so that last-writer-wins respects causality.
+	for _, timestamp := range version.Clock {
+		if err := l.clock.observe(timestamp); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.clock.observe(version.Timestamp); err != nil {
+		return nil, err
+	}
+	version.Timestamp = l.clock.next()
+	version.Clock[l.Key()] = version.Timestamp
+	return version, nil
+}
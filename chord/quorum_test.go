@@ -0,0 +1,215 @@
+package chord
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
+	"math"
+	"testing"
+	"time"
+)
+
+// failingWriteNode refuses every write.
+type failingWriteNode struct {
+	*LocalNode
+}
+
+func (f *failingWriteNode) PutRecords(_ context.Context, _ []*storage.Record) error {
+	return ErrNodeUnavailable
+}
+
+// waitForRecord waits until a node stores a value of a key.
+func waitForRecord(node *LocalNode, key string, value string) bool {
+	for i := 0; i < 100; i++ {
+		if record, err := node.storage.Get(key); err == nil && string(record.Value) == value {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestLocalNode_PutGet(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(3)
+	key := ownedKeys(nodes[0], nodes[2], 1)[0]
+
+	_, err := nodes[1].Get(ctx, key)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	version, err := nodes[1].Put(ctx, &storage.Record{Key: key, Value: []byte("value")}, WithConsistency(ConsistencyAll))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]uint64{nodes[1].Key(): version.Timestamp}, version.Clock)
+	for _, node := range nodes {
+		record, err := node.storage.Get(key)
+		assert.Nil(t, err, "%s misses %s", node.Key(), key)
+		if err == nil {
+			assert.Equal(t, []byte("value"), record.Value)
+			assert.Equal(t, version, record.Version)
+		}
+	}
+	for _, consistency := range []Consistency{ConsistencyOne, ConsistencyQuorum, ConsistencyAll} {
+		record, err := nodes[0].Get(ctx, key, WithConsistency(consistency))
+		assert.Nil(t, err)
+		if err == nil {
+			assert.Equal(t, []byte("value"), record.Value)
+			assert.Equal(t, version, record.Version)
+		}
+	}
+}
+
+func TestLocalNode_OwnerResolved(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(3)
+	key := ownedKeys(nodes[0], nodes[2], 1)[0]
+
+	var owners []string
+	ownerResolved := WithOwnerResolved(func(owner *model.NodeRef) {
+		owners = append(owners, owner.Key())
+	})
+	_, err := nodes[1].Put(ctx, &storage.Record{Key: key, Value: []byte("value")}, ownerResolved)
+	assert.Nil(t, err)
+	_, err = nodes[2].Get(ctx, key, ownerResolved)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{nodes[0].Key(), nodes[0].Key()}, owners)
+}
+
+func TestLocalNode_Put_Expiry(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(3)
+	key := ownedKeys(nodes[0], nodes[2], 1)[0]
+
+	// Replicas hold the same absolute expiry the coordinator has set.
+	expiresAt := storage.ExpiresAt(time.Now().Add(time.Hour))
+	_, err := nodes[1].Put(ctx, &storage.Record{Key: key, Value: []byte("value"), ExpiresAt: expiresAt}, WithConsistency(ConsistencyAll))
+	assert.Nil(t, err)
+	for _, node := range nodes {
+		record, err := node.storage.Get(key)
+		assert.Nil(t, err, "%s misses %s", node.Key(), key)
+		if err == nil {
+			assert.Equal(t, expiresAt, record.ExpiresAt)
+		}
+	}
+
+	_, err = nodes[1].Put(ctx, &storage.Record{Key: key, Value: []byte("value"), ExpiresAt: storage.ExpiresAt(time.Now())}, WithConsistency(ConsistencyAll))
+	assert.Nil(t, err)
+	_, err = nodes[0].Get(ctx, key, WithConsistency(ConsistencyAll))
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestLocalNode_Put_Conflict(t *testing.T) {
+	ctx := context.Background()
+	for _, policy := range []storage.ConflictPolicy{storage.LastWriterWins, storage.KeepSiblings} {
+		nodes := createReplicatedRing(3)
+		for _, node := range nodes {
+			node.conflictPolicy = policy
+		}
+		key := ownedKeys(nodes[0], nodes[2], 1)[0]
+
+		// Two coordinators write the key concurrently, without knowing each other's value.
+		v1, err := nodes[1].Put(ctx, &storage.Record{Key: key, Value: []byte("value1")}, WithConsistency(ConsistencyAll))
+		assert.Nil(t, err)
+		v2, err := nodes[2].Put(ctx, &storage.Record{Key: key, Value: []byte("value2")}, WithConsistency(ConsistencyAll))
+		assert.Nil(t, err)
+		assert.Equal(t, storage.Concurrent, v1.Compare(v2))
+
+		record, err := nodes[0].Get(ctx, key, WithConsistency(ConsistencyOne))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("value2"), record.Value)
+		if policy == storage.LastWriterWins {
+			assert.Len(t, record.Siblings, 0)
+			continue
+		}
+		assert.Equal(t, []*storage.Sibling{{Value: []byte("value1"), Version: v1}}, record.Siblings)
+
+		// A value written with the context of the siblings resolves them.
+		v3, err := nodes[0].Put(ctx, &storage.Record{Key: key, Value: []byte("value3"), Version: record.Context()}, WithConsistency(ConsistencyAll))
+		assert.Nil(t, err)
+		assert.Equal(t, storage.After, v3.Compare(v1))
+		assert.Equal(t, storage.After, v3.Compare(v2))
+		record, err = nodes[0].Get(ctx, key, WithConsistency(ConsistencyOne))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("value3"), record.Value)
+		assert.Len(t, record.Siblings, 0)
+	}
+}
+
+func TestLocalNode_Put_TimestampAhead(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(3)
+	key := ownedKeys(nodes[0], nodes[2], 1)[0]
+
+	// A context with the largest timestamp is refused, instead of breaking the clock of the coordinator.
+	future := &storage.Version{Clock: map[string]uint64{"client": math.MaxUint64}, Timestamp: math.MaxUint64}
+	_, err := nodes[0].Put(ctx, &storage.Record{Key: key, Value: []byte("value"), Version: future})
+	assert.True(t, errors.Is(err, ErrTimestampAhead), "err = %v", err)
+
+	// Later writes are still ordered by the wall clock.
+	v1, err := nodes[0].Put(ctx, &storage.Record{Key: key, Value: []byte("value1")})
+	assert.Nil(t, err)
+	v2, err := nodes[0].Put(ctx, &storage.Record{Key: key, Value: []byte("value2")})
+	assert.Nil(t, err)
+	assert.True(t, v1.Timestamp < v2.Timestamp)
+	assert.True(t, v2.Timestamp < uint64(time.Now().Add(time.Second).UnixNano()/int64(time.Millisecond))<<16)
+}
+
+func TestLocalNode_Get_ReadRepair(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(3)
+	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
+	key := ownedKeys(node1, node3, 1)[0]
+
+	// node2 holds a stale record, and node3 has missed the write.
+	stale := &storage.Version{Clock: map[string]uint64{node1.Key(): 1}, Timestamp: 1}
+	latest := &storage.Version{Clock: map[string]uint64{node1.Key(): 2}, Timestamp: 2}
+	assert.Nil(t, node1.storage.Put(&storage.Record{Key: key, Value: []byte("value"), Version: latest}))
+	assert.Nil(t, node2.storage.Put(&storage.Record{Key: key, Value: []byte("stale"), Version: stale}))
+
+	record, err := node1.Get(ctx, key, WithConsistency(ConsistencyAll))
+	assert.Nil(t, err)
+	if err == nil {
+		assert.Equal(t, []byte("value"), record.Value)
+	}
+	assert.True(t, waitForRecord(node2, key, "value"), "node2 isn't repaired")
+	assert.True(t, waitForRecord(node3, key, "value"), "node3 isn't repaired")
+}
+
+func TestLocalNode_Put_QuorumNotReached(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(3)
+	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
+	key := ownedKeys(node1, node3, 1)[0]
+	record := &storage.Record{Key: key, Value: []byte("value")}
+
+	// node2 is alive, but fails to write.
+	node1.setSuccessors(&failingWriteNode{LocalNode: node2}, []RingNode{node3})
+	_, err := node1.Put(ctx, record, WithConsistency(ConsistencyAll))
+	assert.True(t, errors.Is(err, ErrQuorumNotReached), "err = %v", err)
+	_, err = node1.Put(ctx, record, WithConsistency(ConsistencyQuorum))
+	assert.Nil(t, err)
+	_, err = node1.Put(ctx, record, WithWriteQuorum(2))
+	assert.Nil(t, err)
+
+	// node3 is down, so only 2 replicas are in the preference list once the ring is stabilized.
+	node3.Shutdown()
+	node1.setSuccessors(node2, []RingNode{node1})
+	node1.predecessor = node2
+	node2.setSuccessors(node1, []RingNode{node2})
+	_, err = node1.Put(ctx, record, WithConsistency(ConsistencyAll))
+	assert.True(t, errors.Is(err, ErrQuorumNotReached), "err = %v", err)
+	_, err = node1.Put(ctx, record, WithConsistency(ConsistencyQuorum))
+	assert.Nil(t, err)
+	_, err = node1.Get(ctx, key, WithReplicas(5), WithReadQuorum(3))
+	assert.True(t, errors.Is(err, ErrQuorumNotReached), "err = %v", err)
+}
+
+func TestLocalNode_InvalidQuorum(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(3)
+	_, err := nodes[0].Get(ctx, "key", WithReadQuorum(4))
+	assert.True(t, errors.Is(err, ErrInvalidQuorum), "err = %v", err)
+	_, err = nodes[0].Put(ctx, &storage.Record{Key: "key"}, WithReplicas(0))
+	assert.True(t, errors.Is(err, ErrInvalidQuorum), "err = %v", err)
+}
@@ -0,0 +1,47 @@
+package chord
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// ReaperStabilizer deletes expired records from the storage of a local node.
+// Reads never return expired records, so reaping only reclaims space, and runs at most once per interval.
+type ReaperStabilizer struct {
+	Node     *LocalNode
+	interval time.Duration
+	lastRun  time.Time
+}
+
+// NewReaperStabilizer creates a reaper stabilizer, which reaps expired records at most once per interval.
+func NewReaperStabilizer(node *LocalNode, interval time.Duration) *ReaperStabilizer {
+	return &ReaperStabilizer{
+		Node:     node,
+		interval: interval,
+	}
+}
+
+// Stabilize is implemented for Stabilizer interface.
+func (s *ReaperStabilizer) Stabilize(_ context.Context) {
+	if time.Since(s.lastRun) < s.interval {
+		return
+	}
+	s.lastRun = time.Now()
+	s.Node.stabilizers.record(reaperStabilizerName, s.reap())
+}
+
+func (s *ReaperStabilizer) reap() error {
+	l := s.Node
+	if l.storage == nil {
+		return ErrNoStorage
+	}
+	deleted, err := l.storage.DeleteExpired()
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Infof("Host[%s] reaped %d expired records.", l.Key(), deleted)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package chord
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/storage"
+	"testing"
+	"time"
+)
+
+func TestReaperStabilizer(t *testing.T) {
+	node := NewLocalNode("gord")
+	node.storage = storage.NewMemoryEngine()
+	node.CreateRing()
+	expired := storage.ExpiresAt(time.Now().Add(-time.Second))
+	assert.Nil(t, node.storage.Put(&storage.Record{Key: "expired", ExpiresAt: expired}))
+	assert.Nil(t, node.storage.Put(&storage.Record{Key: "permanent"}))
+
+	s := NewReaperStabilizer(node, time.Minute)
+	s.Stabilize(context.Background())
+	states := node.stabilizers.snapshot()
+	assert.Equal(t, reaperStabilizerName, states[0].Name)
+	assert.Equal(t, "", states[0].LastError)
+	var keys []string
+	assert.Nil(t, node.storage.Range(node.ID, node.ID, func(record *storage.Record) bool {
+		keys = append(keys, record.Key)
+		return true
+	}))
+	assert.Equal(t, []string{"permanent"}, keys)
+	deleted, err := node.storage.DeleteExpired()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, deleted)
+}
@@ -3,6 +3,7 @@ package chord
 import (
 	"context"
 	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
 )
 
 // RemoteNode represents remote nodes.
@@ -19,6 +20,14 @@ func NewRemoteNode(host string, transport Transport) RingNode {
 	}
 }
 
+// NewRemoteNodeFromRef creates a remote node from a reference received from other nodes.
+func NewRemoteNodeFromRef(ref *model.NodeRef, transport Transport) RingNode {
+	return &RemoteNode{
+		NodeRef:   ref,
+		Transport: transport,
+	}
+}
+
 func (r *RemoteNode) Ping(ctx context.Context) error {
 	return r.PingRPC(ctx, r.NodeRef)
 }
@@ -50,3 +59,23 @@ func (r *RemoteNode) FindClosestPrecedingNode(ctx context.Context, id model.Hash
 func (r *RemoteNode) Notify(ctx context.Context, node RingNode) error {
 	return r.NotifyRPC(ctx, r.NodeRef, node.Reference())
 }
+
+func (r *RemoteNode) Handshake(ctx context.Context, node RingNode) (Protocol, error) {
+	return r.HandshakeRPC(ctx, r.NodeRef, node.Reference())
+}
+
+func (r *RemoteNode) MerkleHashes(ctx context.Context, from model.HashID, to model.HashID, level int, indexes []int) ([][]byte, error) {
+	return r.MerkleHashesRPC(ctx, r.NodeRef, from, to, level, indexes)
+}
+
+func (r *RemoteNode) Digests(ctx context.Context, from model.HashID, to model.HashID) ([]storage.Digest, error) {
+	return r.DigestsRPC(ctx, r.NodeRef, from, to)
+}
+
+func (r *RemoteNode) GetRecords(ctx context.Context, keys []string) ([]*storage.Record, error) {
+	return r.GetRecordsRPC(ctx, r.NodeRef, keys)
+}
+
+func (r *RemoteNode) PutRecords(ctx context.Context, records []*storage.Record) error {
+	return r.PutRecordsRPC(ctx, r.NodeRef, records)
+}
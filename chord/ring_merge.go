@@ -0,0 +1,148 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+const (
+	// peerHistorySize limits how many previously seen peers a node remembers on each side of it.
+	peerHistorySize = 16
+	// ringMergeProbeRounds is how many rounds of stabilizers pass between probes.
+	ringMergeProbeRounds = 10
+)
+
+// peerSide is the side of a node which a peer was seen on.
+type peerSide int
+
+const (
+	successorSide peerSide = iota
+	predecessorSide
+)
+
+// peerHistory remembers peers a node has seen, so that it can find them again after a partition.
+// It remembers a peer per host, and keeps successors and predecessors apart,
+// so that successors seen every round don't push out peers on the other side of the ring.
+// The oldest peer of a side is forgotten first.
+type peerHistory struct {
+	sides [2][]RingNode
+	next  int
+	lock  sync.Mutex
+}
+
+func newPeerHistory() *peerHistory {
+	return &peerHistory{
+		sides: [2][]RingNode{
+			make([]RingNode, 0, peerHistorySize),
+			make([]RingNode, 0, peerHistorySize),
+		},
+	}
+}
+
+// add remembers nodes seen on a side. Nodes on the host of a local node are skipped.
+func (h *peerHistory) add(self string, side peerSide, nodes ...RingNode) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, node := range nodes {
+		if node == nil || node.Reference().Host == self {
+			continue
+		}
+		// A host moves to the side it's seen on last.
+		for s, peers := range h.sides {
+			for i, n := range peers {
+				if n.Reference().Host == node.Reference().Host {
+					h.sides[s] = append(peers[:i], peers[i+1:]...)
+					break
+				}
+			}
+		}
+		peers := h.sides[side]
+		if len(peers) >= peerHistorySize {
+			peers = peers[1:]
+		}
+		h.sides[side] = append(peers, node)
+	}
+}
+
+// probe returns peers of both sides in turn.
+func (h *peerHistory) probe() (RingNode, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	successors, predecessors := h.sides[successorSide], h.sides[predecessorSide]
+	total := len(successors) + len(predecessors)
+	if total == 0 {
+		return nil, false
+	}
+	i := h.next % total
+	h.next = (i + 1) % total
+	if i < len(successors) {
+		return successors[i], true
+	}
+	return predecessors[i-len(successors)], true
+}
+
+// RememberPeer makes a local node remember a peer, such as a seed node, to probe for split rings.
+// It's kept with predecessors, which change less often than successors.
+func (l *LocalNode) RememberPeer(node RingNode) {
+	l.peers.add(l.Host, predecessorSide, node)
+}
+
+// RingMergeStabilizer detects that a local node and a previously seen peer belong to different rings, and merges them.
+// Such split rings form when a partition lasts long enough for each side to drop the other side.
+type RingMergeStabilizer struct {
+	Node   *LocalNode
+	rounds int
+}
+
+// NewRingMergeStabilizer creates a ring merge stabilizer.
+func NewRingMergeStabilizer(node *LocalNode) *RingMergeStabilizer {
+	return &RingMergeStabilizer{
+		Node: node,
+	}
+}
+
+// Stabilize is implemented for Stabilizer interface.
+func (s *RingMergeStabilizer) Stabilize(ctx context.Context) {
+	s.rounds++
+	if s.rounds%ringMergeProbeRounds != 0 {
+		return
+	}
+	s.Node.stabilizers.record(ringMergeStabilizerName, s.stabilize(ctx))
+}
+
+func (s *RingMergeStabilizer) stabilize(ctx context.Context) error {
+	peer, ok := s.Node.peers.probe()
+	if !ok {
+		return nil
+	}
+	// In the same ring, the peer finds this node as the successor of this node's ID.
+	found, err := peer.FindSuccessorByTable(ctx, s.Node.ID)
+	if err != nil {
+		// The peer may be gone, or still partitioned.
+		return nil
+	}
+	if found.Reference().Key() == s.Node.Key() {
+		return nil
+	}
+	suc, err := s.Node.successors.head()
+	if err != nil {
+		return err
+	}
+	if found.Reference().Key() == suc.Reference().Key() {
+		return nil
+	}
+	if err := s.Node.VerifyPeer(found.Reference()); err != nil {
+		return fmt.Errorf("refused Host[%s] in another ring. err = %w", found.Reference().Key(), err)
+	}
+	log.Warnf("Host[%s] found Host[%s] in another ring via Host[%s]. merging rings.", s.Node.Key(), found.Reference().Key(), peer.Reference().Key())
+	// Re-run join logic: take the found node as the successor if it is closer, and notify it.
+	if found.Reference().ID.Between(s.Node.ID, suc.Reference().ID) {
+		s.Node.PutSuccessor(found)
+	}
+	if err := found.Notify(ctx, s.Node); err != nil {
+		return fmt.Errorf("notify Host[%s] in another ring failed. err = %w", found.Reference().Key(), err)
+	}
+	return nil
+}
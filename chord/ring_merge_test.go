@@ -0,0 +1,101 @@
+package chord
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPeerHistory(t *testing.T) {
+	history := newPeerHistory()
+	nodes := createNodes(peerHistorySize + 2)
+	history.add(nodes[0].Host, successorSide, nodes[0], nil)
+	_, ok := history.probe()
+	assert.False(t, ok)
+
+	for _, node := range nodes {
+		history.add("self", successorSide, node)
+	}
+	// Re-adding a node makes it the newest one.
+	history.add("self", successorSide, nodes[2])
+	successors := history.sides[successorSide]
+	assert.Equal(t, peerHistorySize, len(successors))
+	assert.Equal(t, nodes[3].Key(), successors[0].Reference().Key())
+	assert.Equal(t, nodes[2].Key(), successors[peerHistorySize-1].Reference().Key())
+
+	seen := map[string]struct{}{}
+	for i := 0; i < peerHistorySize; i++ {
+		node, ok := history.probe()
+		assert.True(t, ok)
+		seen[node.Reference().Key()] = struct{}{}
+	}
+	assert.Equal(t, peerHistorySize, len(seen))
+}
+
+func TestPeerHistory_Sides(t *testing.T) {
+	history := newPeerHistory()
+	nodes := createNodes(2*peerHistorySize + 1)
+	predecessor := nodes[0]
+	history.add("self", predecessorSide, predecessor)
+
+	// Successors don't push out the predecessor.
+	for _, node := range nodes[1:] {
+		history.add("self", successorSide, node)
+	}
+	assert.Equal(t, peerHistorySize, len(history.sides[successorSide]))
+	seen := map[string]struct{}{}
+	for i := 0; i < peerHistorySize+1; i++ {
+		node, ok := history.probe()
+		assert.True(t, ok)
+		seen[node.Reference().Key()] = struct{}{}
+	}
+	assert.Equal(t, peerHistorySize+1, len(seen))
+	assert.Contains(t, seen, predecessor.Key())
+
+	// Virtual nodes of a host are remembered once.
+	vnode := NewLocalNode(predecessor.Host, WithVirtualNode(1))
+	history.add("self", successorSide, vnode)
+	assert.Equal(t, 0, len(history.sides[predecessorSide]))
+	assert.Equal(t, vnode.Key(), history.sides[successorSide][peerHistorySize-1].Reference().Key())
+
+	// Nodes on the local host are skipped.
+	history.add(nodes[1].Host, predecessorSide, nodes[1])
+	assert.Equal(t, 0, len(history.sides[predecessorSide]))
+}
+
+func TestRingMergeStabilizer(t *testing.T) {
+	ctx := context.Background()
+	nodes := createNodes(2)
+	nodeA, nodeB := nodes[0], nodes[1]
+	nodeA.CreateRing()
+	nodeB.CreateRing()
+	nodeA.RememberPeer(nodeB)
+
+	stabilizer := NewRingMergeStabilizer(nodeA)
+	for i := 0; i < ringMergeProbeRounds; i++ {
+		stabilizer.Stabilize(ctx)
+	}
+	suc, err := nodeA.successors.head()
+	assert.Nil(t, err)
+	assert.Equal(t, nodeB.Key(), suc.Reference().Key())
+	assert.Equal(t, nodeA.Key(), nodeB.predecessor.Reference().Key())
+
+	// Once merged, stabilizers complete the ring.
+	for i := 0; i < 2; i++ {
+		NewSuccessorStabilizer(nodeA).Stabilize(ctx)
+		NewSuccessorStabilizer(nodeB).Stabilize(ctx)
+	}
+	suc, err = nodeB.successors.head()
+	assert.Nil(t, err)
+	assert.Equal(t, nodeA.Key(), suc.Reference().Key())
+	assert.Equal(t, nodeB.Key(), nodeA.predecessor.Reference().Key())
+}
+
+func TestLocalNode_JoinRing_RemembersExistNode(t *testing.T) {
+	nodes := createNodes(2)
+	nodes[0].CreateRing()
+	assert.Nil(t, nodes[1].JoinRing(context.Background(), nodes[0]))
+	peer, ok := nodes[1].peers.probe()
+	assert.True(t, ok)
+	assert.Equal(t, nodes[0].Key(), peer.Reference().Key())
+}
@@ -0,0 +1,37 @@
+package sim
+
+import "time"
+
+// Clock is a virtual clock which advances only when a simulator advances it.
+type Clock struct {
+	now time.Time
+}
+
+// NewClock creates a clock starting at the Unix epoch.
+func NewClock() *Clock {
+	return &Clock{
+		now: time.Unix(0, 0),
+	}
+}
+
+// Now returns the current virtual time.
+func (c *Clock) Now() time.Time {
+	return c.now
+}
+
+// Since returns the virtual time elapsed since t.
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.now.Sub(t)
+}
+
+// AdvanceTo moves the clock forward to t. The clock never goes backward.
+func (c *Clock) AdvanceTo(t time.Time) {
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.AdvanceTo(c.now.Add(d))
+}
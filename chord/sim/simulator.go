@@ -0,0 +1,422 @@
+// Package sim simulates a chord ring in memory under a virtual clock.
+// A simulation is single-threaded and driven by seeded randomness, so the same seed always reproduces the same run.
+package sim
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"github.com/taisho6339/gord/chord"
+	"github.com/taisho6339/gord/pkg/model"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Simulator runs chord nodes connected by an in-memory network.
+// Each node runs a round of its stabilizers at an interval of virtual time.
+// RPCs don't advance the clock during a round, but their latency delays the next round of the node.
+type Simulator struct {
+	clock     *Clock
+	rand      *rand.Rand
+	opt       *simulatorOption
+	nodes     map[string]*simNode
+	hosts     []string
+	schedule  scheduleQueue
+	seq       int
+	partition map[string]int
+	// elapsed is virtual time spent on RPCs in the current round.
+	elapsed time.Duration
+}
+
+type simNode struct {
+	node        *chord.LocalNode
+	transport   *Transport
+	stabilizers []chord.Stabilizer
+	alive       bool
+}
+
+type simulatorOption struct {
+	seed              int64
+	latency           time.Duration
+	jitter            time.Duration
+	lossRate          float64
+	rpcTimeout        time.Duration
+	stabilizeInterval time.Duration
+}
+
+// OptionFunc is function to apply options to a simulator
+type OptionFunc func(option *simulatorOption)
+
+func newDefaultSimulatorOption() *simulatorOption {
+	return &simulatorOption{
+		seed:              1,
+		latency:           time.Millisecond,
+		rpcTimeout:        time.Second,
+		stabilizeInterval: 50 * time.Millisecond,
+	}
+}
+
+// WithSeed sets a seed of the randomness of a simulation.
+func WithSeed(seed int64) OptionFunc {
+	return func(option *simulatorOption) {
+		option.seed = seed
+	}
+}
+
+// WithLatency sets a round trip time of each RPC, which varies by up to jitter.
+func WithLatency(latency time.Duration, jitter time.Duration) OptionFunc {
+	return func(option *simulatorOption) {
+		option.latency = latency
+		option.jitter = jitter
+	}
+}
+
+// WithLossRate sets a probability that an RPC is lost and times out.
+func WithLossRate(rate float64) OptionFunc {
+	return func(option *simulatorOption) {
+		option.lossRate = rate
+	}
+}
+
+// WithRPCTimeout sets the time an RPC waits before it fails with context.DeadlineExceeded.
+func WithRPCTimeout(duration time.Duration) OptionFunc {
+	return func(option *simulatorOption) {
+		option.rpcTimeout = duration
+	}
+}
+
+// WithStabilizeInterval sets the interval between rounds of stabilizers of each node.
+func WithStabilizeInterval(duration time.Duration) OptionFunc {
+	return func(option *simulatorOption) {
+		option.stabilizeInterval = duration
+	}
+}
+
+// NewSimulator creates a simulator without nodes.
+func NewSimulator(opts ...OptionFunc) *Simulator {
+	opt := newDefaultSimulatorOption()
+	for _, o := range opts {
+		o(opt)
+	}
+	return &Simulator{
+		clock:     NewClock(),
+		rand:      rand.New(rand.NewSource(opt.seed)),
+		opt:       opt,
+		nodes:     map[string]*simNode{},
+		partition: map[string]int{},
+	}
+}
+
+// Clock returns the virtual clock of a simulation.
+func (s *Simulator) Clock() *Clock {
+	return s.clock
+}
+
+// Rand returns the seeded randomness of a simulation, for tests to make reproducible choices.
+func (s *Simulator) Rand() *rand.Rand {
+	return s.rand
+}
+
+// AddNode creates a node on host and makes it join in the ring via a random alive node.
+// The first node creates a ring.
+func (s *Simulator) AddNode(host string, opts ...chord.LocalNodeOptionFunc) (*chord.LocalNode, error) {
+	var exist string
+	if alive := s.aliveHosts(); len(alive) > 0 {
+		exist = alive[s.rand.Intn(len(alive))]
+	}
+	return s.AddNodeVia(host, exist, opts...)
+}
+
+// AddNodeVia creates a node on host and makes it join in a ring via the node of key exist.
+// If exist is empty, the node creates a new ring.
+func (s *Simulator) AddNodeVia(host string, exist string, opts ...chord.LocalNodeOptionFunc) (*chord.LocalNode, error) {
+	node := chord.NewLocalNode(host, opts...)
+	if _, ok := s.nodes[node.Key()]; ok {
+		return nil, fmt.Errorf("sim: node %s already exists", node.Key())
+	}
+	sn := &simNode{
+		node:  node,
+		alive: true,
+		stabilizers: []chord.Stabilizer{
+			chord.NewSuccessorStabilizer(node),
+			chord.NewFingerTableStabilizer(node),
+			chord.NewAliveStabilizer(node),
+			chord.NewRingMergeStabilizer(node),
+		},
+	}
+	sn.transport = &Transport{sim: s, from: node}
+
+	if exist == "" {
+		node.CreateRing()
+	} else {
+		existNode, ok := s.nodes[exist]
+		if !ok {
+			return nil, fmt.Errorf("sim: node %s doesn't exist", exist)
+		}
+		if err := node.JoinRing(context.Background(), sn.transport.remoteNode(existNode.node.NodeRef)); err != nil {
+			return nil, err
+		}
+	}
+	s.nodes[node.Key()] = sn
+	s.hosts = append(s.hosts, node.Key())
+	s.scheduleNode(node.Key(), time.Duration(s.rand.Int63n(int64(s.opt.stabilizeInterval))))
+	return node, nil
+}
+
+// Introduce makes the node of key remember the node of peer, as if it had seen the peer before.
+func (s *Simulator) Introduce(key string, peer string) {
+	sn, ok := s.nodes[key]
+	if !ok {
+		return
+	}
+	if p, ok := s.nodes[peer]; ok {
+		sn.node.RememberPeer(sn.transport.remoteNode(p.node.NodeRef))
+	}
+}
+
+// Kill crashes a node. A crashed node never responds again.
+func (s *Simulator) Kill(key string) {
+	sn, ok := s.nodes[key]
+	if !ok {
+		return
+	}
+	sn.alive = false
+	sn.node.Shutdown()
+}
+
+// Node returns a node by its key, or nil.
+func (s *Simulator) Node(key string) *chord.LocalNode {
+	sn, ok := s.nodes[key]
+	if !ok {
+		return nil
+	}
+	return sn.node
+}
+
+// Nodes returns alive nodes sorted by their IDs.
+func (s *Simulator) Nodes() []*chord.LocalNode {
+	var nodes []*chord.LocalNode
+	for _, key := range s.hosts {
+		if sn := s.nodes[key]; sn.alive {
+			nodes = append(nodes, sn.node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID.LessThan(nodes[j].ID)
+	})
+	return nodes
+}
+
+// RandomNode returns a random alive node, or nil.
+func (s *Simulator) RandomNode() *chord.LocalNode {
+	alive := s.aliveHosts()
+	if len(alive) == 0 {
+		return nil
+	}
+	return s.nodes[alive[s.rand.Intn(len(alive))]].node
+}
+
+// Partition splits the network into groups of node keys.
+// Nodes in different groups can't reach each other. Nodes in no group form a group of their own.
+func (s *Simulator) Partition(groups ...[]string) {
+	s.partition = map[string]int{}
+	for i, group := range groups {
+		for _, key := range group {
+			s.partition[key] = i + 1
+		}
+	}
+}
+
+// Heal removes partitions.
+func (s *Simulator) Heal() {
+	s.partition = map[string]int{}
+}
+
+// Step runs the next scheduled round of stabilizers.
+// It returns false if no node is scheduled.
+func (s *Simulator) Step() bool {
+	for s.schedule.Len() > 0 {
+		next := heap.Pop(&s.schedule).(*scheduledRound)
+		sn := s.nodes[next.key]
+		if !sn.alive {
+			continue
+		}
+		s.clock.AdvanceTo(next.at)
+		s.elapsed = 0
+		ctx := context.Background()
+		for _, stabilizer := range sn.stabilizers {
+			stabilizer.Stabilize(ctx)
+		}
+		s.scheduleNode(next.key, s.elapsed+s.opt.stabilizeInterval)
+		return true
+	}
+	return false
+}
+
+// RunFor runs rounds of stabilizers for d of virtual time.
+func (s *Simulator) RunFor(d time.Duration) {
+	deadline := s.clock.Now().Add(d)
+	for s.schedule.Len() > 0 && !s.schedule[0].at.After(deadline) {
+		s.Step()
+	}
+	s.clock.AdvanceTo(deadline)
+}
+
+// RunUntil runs rounds of stabilizers until cond is satisfied, checking it every interval of virtual time.
+// It returns false if cond isn't satisfied within limit.
+func (s *Simulator) RunUntil(cond func() bool, interval time.Duration, limit time.Duration) bool {
+	deadline := s.clock.Now().Add(limit)
+	for !cond() {
+		if !s.clock.Now().Before(deadline) {
+			return false
+		}
+		s.RunFor(interval)
+	}
+	return true
+}
+
+// Lookup finds the node which id belongs to, starting from the node of key.
+func (s *Simulator) Lookup(key string, id model.HashID) (*model.NodeRef, error) {
+	sn, ok := s.nodes[key]
+	if !ok || !sn.alive {
+		return nil, chord.ErrNodeUnavailable
+	}
+	node, err := sn.node.FindSuccessorByTable(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	return node.Reference(), nil
+}
+
+// Owner returns the alive node which id belongs to in a correct ring.
+func (s *Simulator) Owner(id model.HashID) *model.NodeRef {
+	nodes := s.Nodes()
+	if len(nodes) == 0 {
+		return nil
+	}
+	for _, node := range nodes {
+		if id.LessThanEqual(node.ID) {
+			return node.NodeRef
+		}
+	}
+	return nodes[0].NodeRef
+}
+
+// SuccessorsConverged reports whether every alive node points to its correct successor.
+// Lookups are correct once successors are.
+func (s *Simulator) SuccessorsConverged() bool {
+	nodes := s.Nodes()
+	ctx := context.Background()
+	for i, node := range nodes {
+		successors, err := node.GetSuccessors(ctx)
+		if err != nil || len(successors) == 0 {
+			return false
+		}
+		if successors[0].Reference().Key() != nodes[(i+1)%len(nodes)].Key() {
+			return false
+		}
+	}
+	return true
+}
+
+// Converged reports whether every alive node points to its correct successor and predecessor.
+func (s *Simulator) Converged() bool {
+	if !s.SuccessorsConverged() {
+		return false
+	}
+	nodes := s.Nodes()
+	ctx := context.Background()
+	for i, node := range nodes {
+		pred, err := node.GetPredecessor(ctx)
+		if err != nil || pred == nil {
+			return false
+		}
+		if pred.Reference().Key() != nodes[(i+len(nodes)-1)%len(nodes)].Key() {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Simulator) aliveHosts() []string {
+	var alive []string
+	for _, key := range s.hosts {
+		if s.nodes[key].alive {
+			alive = append(alive, key)
+		}
+	}
+	return alive
+}
+
+func (s *Simulator) scheduleNode(key string, after time.Duration) {
+	s.seq++
+	heap.Push(&s.schedule, &scheduledRound{
+		key: key,
+		at:  s.clock.Now().Add(after),
+		seq: s.seq,
+	})
+}
+
+// deliver decides the fate of an RPC from a node to another, and charges its latency to the current round.
+func (s *Simulator) deliver(ctx context.Context, from *chord.LocalNode, to *model.NodeRef) (*simNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	target, ok := s.nodes[to.Key()]
+	if !ok || !target.alive {
+		s.elapsed += s.opt.latency
+		return nil, chord.ErrNodeUnavailable
+	}
+	if s.partition[from.Key()] != s.partition[to.Key()] {
+		s.elapsed += s.opt.rpcTimeout
+		return nil, context.DeadlineExceeded
+	}
+	if s.opt.lossRate > 0 && s.rand.Float64() < s.opt.lossRate {
+		s.elapsed += s.opt.rpcTimeout
+		return nil, context.DeadlineExceeded
+	}
+	latency := s.opt.latency
+	if s.opt.jitter > 0 {
+		latency += time.Duration(s.rand.Int63n(int64(s.opt.jitter) + 1))
+	}
+	if latency > s.opt.rpcTimeout {
+		s.elapsed += s.opt.rpcTimeout
+		return nil, context.DeadlineExceeded
+	}
+	s.elapsed += latency
+	return target, nil
+}
+
+// scheduledRound represents a round of stabilizers of a node at a virtual time.
+type scheduledRound struct {
+	key string
+	at  time.Time
+	seq int
+}
+
+// scheduleQueue orders rounds by time. Rounds at the same time run in the order they were scheduled.
+type scheduleQueue []*scheduledRound
+
+func (q scheduleQueue) Len() int { return len(q) }
+
+func (q scheduleQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+
+func (q scheduleQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *scheduleQueue) Push(x interface{}) {
+	*q = append(*q, x.(*scheduledRound))
+}
+
+func (q *scheduleQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	round := old[n-1]
+	*q = old[:n-1]
+	return round
+}
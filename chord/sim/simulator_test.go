@@ -0,0 +1,307 @@
+package sim
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/chord"
+	"github.com/taisho6339/gord/pkg/model"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var largeSimulation = flag.Bool("sim.large", false, "run simulations of 10k-node rings")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	// Stabilizers log every change, which is too much for large rings.
+	log.SetLevel(log.ErrorLevel)
+	os.Exit(m.Run())
+}
+
+// buildRing adds nodes one by one, waiting for the ring to converge after each join.
+func buildRing(t *testing.T, s *Simulator, n int) {
+	for i := 0; i < n; i++ {
+		_, err := s.AddNode(fmt.Sprintf("gord%d", i))
+		assert.Nil(t, err)
+		assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour), "ring didn't converge after gord%d joined", i)
+	}
+}
+
+// fingerprint summarizes the ring state seen by every node.
+func fingerprint(s *Simulator) string {
+	var b strings.Builder
+	ctx := context.Background()
+	fmt.Fprintf(&b, "%d;", s.Clock().Now().UnixNano())
+	for _, node := range s.Nodes() {
+		successors, _ := node.GetSuccessors(ctx)
+		pred, _ := node.GetPredecessor(ctx)
+		fmt.Fprintf(&b, "%s:", node.Key())
+		if pred != nil {
+			fmt.Fprintf(&b, "%s", pred.Reference().Key())
+		}
+		for _, suc := range successors {
+			fmt.Fprintf(&b, ",%s", suc.Reference().Key())
+		}
+		b.WriteString(";")
+	}
+	return b.String()
+}
+
+func assertLookups(t *testing.T, s *Simulator, count int) {
+	for i := 0; i < count; i++ {
+		from := s.RandomNode()
+		id := model.NewHashID(fmt.Sprintf("key%d", s.Rand().Int()))
+		node, err := s.Lookup(from.Key(), id)
+		assert.Nil(t, err)
+		if err == nil {
+			assert.Equal(t, s.Owner(id).Key(), node.Key())
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	clock := NewClock()
+	start := clock.Now()
+	clock.Advance(time.Second)
+	assert.Equal(t, time.Second, clock.Since(start))
+	clock.AdvanceTo(start)
+	assert.Equal(t, time.Second, clock.Since(start))
+}
+
+func TestSimulator_Converge(t *testing.T) {
+	s := NewSimulator(WithLatency(2*time.Millisecond, 3*time.Millisecond))
+	buildRing(t, s, 32)
+	assert.True(t, s.RunUntil(func() bool {
+		for _, node := range s.Nodes() {
+			if node.DebugState().Fingers[model.BitSize-1].Node == nil {
+				return false
+			}
+		}
+		return true
+	}, time.Second, time.Hour))
+	assertLookups(t, s, 100)
+}
+
+func TestSimulator_Deterministic(t *testing.T) {
+	run := func(seed int64) string {
+		s := NewSimulator(WithSeed(seed), WithLatency(time.Millisecond, 5*time.Millisecond), WithLossRate(0.05))
+		for i := 0; i < 16; i++ {
+			if _, err := s.AddNode(fmt.Sprintf("gord%d", i)); err != nil {
+				s.RunFor(time.Second)
+			}
+			s.RunFor(100 * time.Millisecond)
+		}
+		s.Kill(s.RandomNode().Key())
+		s.RunFor(30 * time.Second)
+		return fingerprint(s)
+	}
+	assert.Equal(t, run(42), run(42))
+}
+
+func TestSimulator_Latency(t *testing.T) {
+	s := NewSimulator(WithLatency(10*time.Millisecond, 0), WithRPCTimeout(50*time.Millisecond))
+	buildRing(t, s, 2)
+	nodes := s.Nodes()
+	transport := &Transport{sim: s, from: nodes[0]}
+
+	s.elapsed = 0
+	assert.Nil(t, transport.PingRPC(context.Background(), nodes[1].NodeRef))
+	assert.Equal(t, 10*time.Millisecond, s.elapsed)
+
+	s.opt.latency = time.Second
+	s.elapsed = 0
+	assert.Equal(t, context.DeadlineExceeded, transport.PingRPC(context.Background(), nodes[1].NodeRef))
+	assert.Equal(t, 50*time.Millisecond, s.elapsed)
+}
+
+func TestSimulator_Loss(t *testing.T) {
+	s := NewSimulator()
+	buildRing(t, s, 2)
+	nodes := s.Nodes()
+	transport := &Transport{sim: s, from: nodes[0]}
+
+	s.opt.lossRate = 1
+	assert.Equal(t, context.DeadlineExceeded, transport.PingRPC(context.Background(), nodes[1].NodeRef))
+	s.opt.lossRate = 0
+	assert.Nil(t, transport.PingRPC(context.Background(), nodes[1].NodeRef))
+}
+
+func TestSimulator_Partition(t *testing.T) {
+	s := NewSimulator()
+	buildRing(t, s, 4)
+	nodes := s.Nodes()
+	transport := &Transport{sim: s, from: nodes[0]}
+
+	s.Partition([]string{nodes[0].Key(), nodes[1].Key()})
+	assert.Nil(t, transport.PingRPC(context.Background(), nodes[1].NodeRef))
+	assert.Equal(t, context.DeadlineExceeded, transport.PingRPC(context.Background(), nodes[2].NodeRef))
+	assert.Equal(t, context.DeadlineExceeded, transport.PingRPC(context.Background(), nodes[3].NodeRef))
+
+	s.Heal()
+	assert.Nil(t, transport.PingRPC(context.Background(), nodes[2].NodeRef))
+	assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour))
+}
+
+func TestSimulator_Churn(t *testing.T) {
+	s := NewSimulator()
+	buildRing(t, s, 24)
+	for i := 0; i < 4; i++ {
+		s.Kill(s.RandomNode().Key())
+	}
+	assert.Equal(t, chord.ErrNodeUnavailable, func() error {
+		_, err := s.Lookup("gord-missing", model.NewHashID("key"))
+		return err
+	}())
+	assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour))
+	assertLookups(t, s, 100)
+}
+
+// The following tests replay counterexamples to the original Chord stabilization, found by Zave.
+
+func TestSimulator_ConcurrentJoins(t *testing.T) {
+	s := NewSimulator()
+	buildRing(t, s, 3)
+	exist := s.Nodes()[0].Key()
+	// Nodes join via the same node without waiting for each other, so many of them join into the same gap.
+	for i := 3; i < 64; i++ {
+		_, err := s.AddNodeVia(fmt.Sprintf("gord%d", i), exist)
+		assert.Nil(t, err)
+	}
+	assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour))
+	assertLookups(t, s, 100)
+}
+
+func TestSimulator_JoinThenSuccessorFails(t *testing.T) {
+	s := NewSimulator()
+	buildRing(t, s, 8)
+	node, err := s.AddNode("joining")
+	assert.Nil(t, err)
+	successors, err := node.GetSuccessors(context.Background())
+	assert.Nil(t, err)
+	// The successor crashes before its predecessor learns the new node.
+	s.Kill(successors[0].Reference().Key())
+	assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour))
+	assertLookups(t, s, 100)
+}
+
+func TestSimulator_JoinThenPredecessorFails(t *testing.T) {
+	s := NewSimulator()
+	buildRing(t, s, 8)
+	node, err := s.AddNode("joining")
+	assert.Nil(t, err)
+	nodes := s.Nodes()
+	for i, n := range nodes {
+		if n.Key() == node.Key() {
+			// The predecessor crashes before the new node is notified by it.
+			s.Kill(nodes[(i+len(nodes)-1)%len(nodes)].Key())
+			break
+		}
+	}
+	assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour))
+	assertLookups(t, s, 100)
+}
+
+func TestSimulator_DeadPredecessorReplaced(t *testing.T) {
+	s := NewSimulator()
+	buildRing(t, s, 4)
+	nodes := s.Nodes()
+	s.Kill(nodes[0].Key())
+	assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour))
+	pred, err := nodes[1].GetPredecessor(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, nodes[3].Key(), pred.Reference().Key())
+}
+
+func TestSimulator_ShortSuccessorList(t *testing.T) {
+	const r = 3
+	s := NewSimulator()
+	for i := 0; i < 12; i++ {
+		_, err := s.AddNode(fmt.Sprintf("gord%d", i), chord.WithSuccessorListSize(r))
+		assert.Nil(t, err)
+		assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour))
+	}
+	// A ring of at least r+1 nodes survives r-1 consecutive failures.
+	nodes := s.Nodes()
+	for _, node := range nodes[4 : 4+r-1] {
+		s.Kill(node.Key())
+	}
+	assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour))
+	assertLookups(t, s, 100)
+}
+
+func TestSimulator_LoopyRing(t *testing.T) {
+	ctx := context.Background()
+	s := NewSimulator()
+	for i := 0; i < 5; i++ {
+		_, err := s.AddNodeVia(fmt.Sprintf("gord%d", i), "")
+		assert.Nil(t, err)
+	}
+	// Following successors goes around the ID space twice, and every predecessor agrees with it,
+	// so the original stabilization never repairs the ring.
+	nodes := s.Nodes()
+	loop := []*chord.LocalNode{nodes[0], nodes[2], nodes[4], nodes[1], nodes[3]}
+	for i, node := range loop {
+		// Only successor stabilization runs, since lookups and ring merges may repair the ring by other means.
+		s.nodes[node.Key()].stabilizers = []chord.Stabilizer{chord.NewSuccessorStabilizer(node), chord.NewAliveStabilizer(node)}
+		next := loop[(i+1)%len(loop)]
+		node.PutSuccessor(s.nodes[node.Key()].transport.remoteNode(next.NodeRef))
+		assert.Nil(t, next.Notify(ctx, s.nodes[next.Key()].transport.remoteNode(node.NodeRef)))
+	}
+	assert.False(t, s.SuccessorsConverged())
+
+	// The ring has fewer than r+1 nodes, so successor lists cover it and reveal the nodes skipped.
+	assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour))
+	assertLookups(t, s, 100)
+}
+
+// TestSimulator_LargeRing simulates a ring with churn. It has 500 nodes by default,
+// and 10k nodes with -sim.large, which takes a long time.
+func TestSimulator_LargeRing(t *testing.T) {
+	n := 500
+	if *largeSimulation {
+		n = 10000
+	}
+	s := NewSimulator(WithLatency(time.Millisecond, 10*time.Millisecond))
+	for i := 0; i < n; i++ {
+		for {
+			// A short list keeps the simulation fast, and fingers route lookups anyway.
+			if _, err := s.AddNode(fmt.Sprintf("gord%d", i), chord.WithSuccessorListSize(8)); err == nil {
+				break
+			}
+			s.RunFor(50 * time.Millisecond)
+		}
+		// Churn: a node crashes as often as 100 nodes join.
+		if i%100 == 99 {
+			s.Kill(s.RandomNode().Key())
+		}
+	}
+	assert.True(t, s.RunUntil(s.SuccessorsConverged, 10*time.Second, 24*time.Hour))
+	assertLookups(t, s, 1000)
+}
+
+func TestSimulator_SplitRingMerge(t *testing.T) {
+	s := NewSimulator()
+	for _, prefix := range []string{"a", "b"} {
+		for i := 0; i < 8; i++ {
+			exist := ""
+			if i > 0 {
+				exist = fmt.Sprintf("%s0", prefix)
+			}
+			_, err := s.AddNodeVia(fmt.Sprintf("%s%d", prefix, i), exist)
+			assert.Nil(t, err)
+			s.RunFor(time.Second)
+		}
+	}
+	s.RunFor(time.Minute)
+	// Two rings never merge by themselves.
+	assert.False(t, s.SuccessorsConverged())
+
+	s.Introduce("b0", "a0")
+	assert.True(t, s.RunUntil(s.Converged, time.Second, time.Hour))
+	assertLookups(t, s, 100)
+}
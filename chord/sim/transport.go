@@ -0,0 +1,187 @@
+package sim
+
+import (
+	"context"
+	"github.com/taisho6339/gord/chord"
+	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
+)
+
+// Transport is an in-memory chord.Transport of a simulated node.
+// Every RPC is delivered to the target node directly, unless the simulator drops it.
+type Transport struct {
+	sim  *Simulator
+	from *chord.LocalNode
+}
+
+// remoteNode returns a node seen from the sender, in the same way as gRPC transports do.
+func (t *Transport) remoteNode(ref *model.NodeRef) chord.RingNode {
+	if ref.Key() == t.from.Key() {
+		return t.from
+	}
+	return chord.NewRemoteNodeFromRef(ref, t)
+}
+
+// ringNode converts a node returned from a peer into a node seen from the sender.
+func (t *Transport) ringNode(node chord.RingNode) chord.RingNode {
+	if node == nil {
+		return nil
+	}
+	return t.remoteNode(node.Reference())
+}
+
+func (t *Transport) ringNodes(nodes []chord.RingNode) []chord.RingNode {
+	if nodes == nil {
+		return nil
+	}
+	converted := make([]chord.RingNode, len(nodes))
+	for i, node := range nodes {
+		converted[i] = t.ringNode(node)
+	}
+	return converted
+}
+
+// PingRPC is implemented for chord.Transport.
+func (t *Transport) PingRPC(ctx context.Context, to *model.NodeRef) error {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return err
+	}
+	return target.node.Ping(ctx)
+}
+
+// SuccessorsRPC is implemented for chord.Transport.
+func (t *Transport) SuccessorsRPC(ctx context.Context, to *model.NodeRef) ([]chord.RingNode, error) {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return nil, err
+	}
+	successors, err := target.node.GetSuccessors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.ringNodes(successors), nil
+}
+
+// PredecessorRPC is implemented for chord.Transport.
+func (t *Transport) PredecessorRPC(ctx context.Context, to *model.NodeRef) (chord.RingNode, error) {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return nil, err
+	}
+	pred, err := target.node.GetPredecessor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pred == nil {
+		return nil, chord.ErrNotFound
+	}
+	return t.ringNode(pred), nil
+}
+
+// FindSuccessorByTableRPC is implemented for chord.Transport.
+func (t *Transport) FindSuccessorByTableRPC(ctx context.Context, to *model.NodeRef, id model.HashID) (chord.RingNode, error) {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return nil, err
+	}
+	successor, err := target.node.FindSuccessorByTable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return t.ringNode(successor), nil
+}
+
+// FindSuccessorByListRPC is implemented for chord.Transport.
+func (t *Transport) FindSuccessorByListRPC(ctx context.Context, to *model.NodeRef, id model.HashID) (chord.RingNode, error) {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return nil, err
+	}
+	successor, err := target.node.FindSuccessorByList(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return t.ringNode(successor), nil
+}
+
+// FindClosestPrecedingNodeRPC is implemented for chord.Transport.
+func (t *Transport) FindClosestPrecedingNodeRPC(ctx context.Context, to *model.NodeRef, id model.HashID) (chord.RingNode, error) {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return nil, err
+	}
+	node, err := target.node.FindClosestPrecedingNode(ctx, id)
+	if err == chord.ErrStabilizeNotCompleted {
+		// A gRPC server reports it as NotFound.
+		return nil, chord.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t.ringNode(node), nil
+}
+
+// NotifyRPC is implemented for chord.Transport.
+func (t *Transport) NotifyRPC(ctx context.Context, to *model.NodeRef, node *model.NodeRef) error {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return err
+	}
+	if err := target.node.NegotiateProtocol(node, chord.LocalProtocol()); err != nil {
+		return err
+	}
+	if err := target.node.Notify(ctx, target.transport.remoteNode(node)); err != nil {
+		return err
+	}
+	return t.from.NegotiateProtocol(to, chord.LocalProtocol())
+}
+
+// HandshakeRPC is implemented for chord.Transport.
+func (t *Transport) HandshakeRPC(ctx context.Context, to *model.NodeRef, node *model.NodeRef) (chord.Protocol, error) {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return chord.Protocol{}, err
+	}
+	return target.node.Handshake(ctx, target.transport.remoteNode(node))
+}
+
+// MerkleHashesRPC is implemented for chord.Transport.
+func (t *Transport) MerkleHashesRPC(ctx context.Context, to *model.NodeRef, rangeFrom model.HashID, rangeTo model.HashID, level int, indexes []int) ([][]byte, error) {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return nil, err
+	}
+	return target.node.MerkleHashes(ctx, rangeFrom, rangeTo, level, indexes)
+}
+
+// DigestsRPC is implemented for chord.Transport.
+func (t *Transport) DigestsRPC(ctx context.Context, to *model.NodeRef, rangeFrom model.HashID, rangeTo model.HashID) ([]storage.Digest, error) {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return nil, err
+	}
+	return target.node.Digests(ctx, rangeFrom, rangeTo)
+}
+
+// GetRecordsRPC is implemented for chord.Transport.
+func (t *Transport) GetRecordsRPC(ctx context.Context, to *model.NodeRef, keys []string) ([]*storage.Record, error) {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return nil, err
+	}
+	return target.node.GetRecords(ctx, keys)
+}
+
+// PutRecordsRPC is implemented for chord.Transport.
+func (t *Transport) PutRecordsRPC(ctx context.Context, to *model.NodeRef, records []*storage.Record) error {
+	target, err := t.sim.deliver(ctx, t.from, to)
+	if err != nil {
+		return err
+	}
+	return target.node.PutRecords(ctx, records)
+}
+
+// Shutdown is implemented for chord.Transport.
+func (t *Transport) Shutdown() {
+}
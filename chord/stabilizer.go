@@ -2,6 +2,7 @@ package chord
 
 import (
 	"context"
+	"fmt"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -25,22 +26,42 @@ func NewAliveStabilizer(node *LocalNode) AliveStabilizer {
 
 // Stabilize is implemented for Stabilizer interface.
 func (a AliveStabilizer) Stabilize(ctx context.Context) {
+	a.Node.stabilizers.record(aliveStabilizerName, a.stabilize(ctx))
+}
+
+func (a AliveStabilizer) stabilize(ctx context.Context) error {
 	aliveNodes := emptyNodes(cap(a.Node.successors.nodes))
+	var deadEvents []RingEvent
+	prev := a.Node.NodeRef
 	for _, suc := range a.Node.successors.nodes {
 		if err := suc.Ping(ctx); err == nil {
 			aliveNodes = append(aliveNodes, suc)
+			prev = suc.Reference()
 			continue
 		}
 		log.Warnf("Host:[%s] is dead.", suc.Reference().Host)
+		deadEvents = append(deadEvents, RingEvent{
+			Type: NodeDead,
+			Node: suc.Reference(),
+			From: prev.ID,
+			To:   suc.Reference().ID,
+		})
+		prev = suc.Reference()
+	}
+	if len(deadEvents) > 0 {
+		a.Node.removeDeadSuccessors(aliveNodes, deadEvents)
 	}
-	if len(aliveNodes) < len(a.Node.successors.nodes) {
-		a.Node.JoinSuccessors(0, aliveNodes)
+	a.Node.checkPredecessor(ctx)
+	if len(aliveNodes) == 0 {
+		return ErrNoSuccessorAlive
 	}
+	return nil
 }
 
 // SuccessorStabilizer checks new successors.
 // If this stabilizer finds new successor, adds a new one to a successor list of a local node.
 // In addition, this notify a successor to check its predecessor.
+// It follows Zave's corrected stabilization, which keeps a ring from getting loopy or disconnected.
 type SuccessorStabilizer struct {
 	Node *LocalNode
 }
@@ -54,47 +75,84 @@ func NewSuccessorStabilizer(node *LocalNode) SuccessorStabilizer {
 
 // Stabilize is implemented for Stabilizer interface.
 func (s SuccessorStabilizer) Stabilize(ctx context.Context) {
-	suc, err := s.Node.successors.head()
+	s.Node.stabilizers.record(successorStabilizerName, s.stabilize(ctx))
+}
+
+func (s SuccessorStabilizer) stabilize(ctx context.Context) error {
+	suc, successors, err := s.firstLiveSuccessor(ctx)
 	if err != nil {
+		// Keep the list rather than falling back to a ring of this node alone, which would split the ring.
 		log.Errorf("no successor is alive. err = %#v", err)
-		return
+		return err
+	}
+	if n := s.Node.skippedSuccessor(suc, successors); n != nil {
+		log.Warnf("Host[%s] found the ring loopy, since its successors skip Host[%s].", s.Node.Host, n.Reference().Host)
+		suc, successors = s.adopt(ctx, suc, successors, n)
 	}
 	// Check new successor
 	n, err := suc.GetPredecessor(ctx)
 	if err != nil && err != ErrNotFound {
 		log.Errorf("successor stabilizer failed. err = %#v", err)
-		return
+		return fmt.Errorf("get predecessor of Host[%s] failed. err = %w", suc.Reference().Key(), err)
 	}
-	if n != nil && n.Reference().ID.Between(s.Node.ID, suc.Reference().ID) {
-		if err := n.Ping(ctx); err == nil {
-			log.Infof("Host[%s] updated its successor.", s.Node.Host)
-			s.Node.PutSuccessor(n)
-		}
+	if n != nil && n.Reference().Key() != s.Node.Key() && n.Reference().ID.Between(s.Node.ID, suc.Reference().ID) {
+		suc, successors = s.adopt(ctx, suc, successors, n)
 	}
-	// Notify successor
+	s.Node.setSuccessors(suc, successors)
+	if suc.Reference().Key() == s.Node.Key() {
+		return nil
+	}
+	// Notify successor, which rectifies its predecessor
 	err = suc.Notify(ctx, s.Node)
 	if err != nil {
 		log.Errorf("Host[%s] couldn't notify Host[%s]. err = %#v", s.Node.Host, suc.Reference().Host, err)
-		return
+		return fmt.Errorf("notify Host[%s] failed. err = %w", suc.Reference().Key(), err)
 	}
-	if s.Node.ID.Equals(suc.Reference().ID) {
-		return
+	return nil
+}
+
+// adopt makes n the new successor, taking over its successor list, only if it proves its identity and answers.
+// Otherwise, it returns the current successor and its list.
+func (s SuccessorStabilizer) adopt(ctx context.Context, suc RingNode, successors []RingNode, n RingNode) (RingNode, []RingNode) {
+	if err := s.Node.VerifyPeer(n.Reference()); err != nil {
+		log.Warnf("Host[%s] refused a new successor. err = %v", s.Node.Host, err)
+		return suc, successors
 	}
-	// Update successor list
-	successors, err := suc.GetSuccessors(ctx)
+	nSuccessors, err := n.GetSuccessors(ctx)
 	if err != nil {
-		log.Warnf("Host[%s] couldn't get successors from Host[%s]. err = %#v", s.Node.Host, suc.Reference().Host, err)
-		return
+		return suc, successors
 	}
+	log.Infof("Host[%s] updated its successor.", s.Node.Host)
+	return n, nSuccessors
+}
+
+// firstLiveSuccessor returns the first successor which answers, and its successor list.
+// Successors before it are dead, and they drop out when the list is rebuilt.
+func (s SuccessorStabilizer) firstLiveSuccessor(ctx context.Context) (RingNode, []RingNode, error) {
 	s.Node.lock.Lock()
-	defer s.Node.lock.Unlock()
-	s.Node.successors.join(1, successors)
+	candidates := s.Node.successors.nodes
+	s.Node.lock.Unlock()
+	for _, suc := range candidates {
+		if suc.Reference().Key() == s.Node.Key() {
+			return s.Node, nil, nil
+		}
+		successors, err := suc.GetSuccessors(ctx)
+		if err != nil {
+			log.Warnf("Host[%s] couldn't get successors from Host[%s]. err = %#v", s.Node.Host, suc.Reference().Host, err)
+			continue
+		}
+		return suc, successors, nil
+	}
+	return nil, nil, ErrNoSuccessorAlive
 }
 
 // FingerTableStabilizer maintains a finger table of a local node.
+// With proximity neighbour selection, each finger is the closest node among valid candidates,
+// instead of the exact successor of the finger's ID.
 type FingerTableStabilizer struct {
 	Node                *LocalNode
 	lastStabilizedIndex int
+	rtts                *rttTable
 }
 
 // NewFingerTableStabilizer creates a finger table stabilizer.
@@ -105,24 +163,86 @@ func NewFingerTableStabilizer(node *LocalNode) *FingerTableStabilizer {
 	}
 }
 
+// EnableProximitySelection makes this stabilizer pick the closest valid node for each finger.
+func (s *FingerTableStabilizer) EnableProximitySelection() {
+	s.rtts = newRTTTable()
+}
+
+// LastStabilizedIndex returns the last finger this stabilizer updated, or -1 before the first update.
+func (s *FingerTableStabilizer) LastStabilizedIndex() int {
+	return s.Node.stabilizers.lastFinger()
+}
+
 // Stabilize is implemented for Stabilizer interface.
 func (s *FingerTableStabilizer) Stabilize(ctx context.Context) {
+	s.Node.stabilizers.record(fingerTableStabilizerName, s.stabilize(ctx))
+}
+
+func (s *FingerTableStabilizer) stabilize(ctx context.Context) error {
 	index := (s.lastStabilizedIndex + 1) % cap(s.Node.fingerTable)
 	succ, err := s.Node.FindSuccessorByTable(ctx, s.Node.fingerTable[index].ID)
 	if err != nil {
-		return
+		return fmt.Errorf("find successor of finger %d failed. err = %w", index, err)
 	}
-	s.Node.fingerTable[index].Node = succ
+	candidates := s.proximityCandidates(ctx, succ)
+	s.Node.updateFinger(index, s.selectFinger(ctx, index, succ, candidates))
 	s.lastStabilizedIndex = index
 	// Try to update as many finger entries as possible
 	for i := index + 1; i < cap(s.Node.fingerTable); i++ {
 		finger := s.Node.fingerTable[i]
 		if finger.ID.LessThanEqual(succ.Reference().ID) {
-			s.Node.fingerTable[i].Node = succ
+			s.Node.updateFinger(i, s.selectFinger(ctx, i, succ, candidates))
 			s.lastStabilizedIndex = i
 			continue
 		}
-		s.Node.fingerTable[i].Node = succ
+		s.Node.updateFinger(i, succ)
 		break
 	}
+	s.Node.stabilizers.recordFinger(s.lastStabilizedIndex)
+	return nil
+}
+
+// proximityCandidates returns nodes following succ, which may be valid for fingers whose successor is succ.
+func (s *FingerTableStabilizer) proximityCandidates(ctx context.Context, succ RingNode) []RingNode {
+	if s.rtts == nil {
+		return nil
+	}
+	successors, err := succ.GetSuccessors(ctx)
+	if err != nil {
+		log.Warnf("Host[%s] couldn't get proximity candidates from Host[%s]. err = %#v", s.Node.Host, succ.Reference().Host, err)
+		return nil
+	}
+	return successors
+}
+
+// selectFinger returns a node for the finger of index.
+// Any node in [finger.ID, next finger.ID) is valid, because it still halves the distance to a target.
+// The first finger always is the exact successor, which lookups rely on.
+func (s *FingerTableStabilizer) selectFinger(ctx context.Context, index int, succ RingNode, candidates []RingNode) RingNode {
+	if s.rtts == nil || index == 0 {
+		return succ
+	}
+	upper := s.Node.ID
+	if index+1 < len(s.Node.fingerTable) {
+		upper = s.Node.fingerTable[index+1].ID
+	}
+	lower := s.Node.fingerTable[index].ID
+	succID := succ.Reference().ID
+	// No node lies in the finger's interval, so no other candidate can be valid.
+	if !succID.Equals(lower) && !succID.Between(lower, upper) {
+		return succ
+	}
+	valid := []RingNode{succ}
+	for _, candidate := range candidates {
+		if len(valid) >= maxProximityCandidates {
+			break
+		}
+		if candidate.Reference().ID.Between(succ.Reference().ID, upper) {
+			valid = append(valid, candidate)
+		}
+	}
+	if len(valid) == 1 {
+		return succ
+	}
+	return s.rtts.closest(ctx, succ, valid)
 }
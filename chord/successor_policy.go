@@ -0,0 +1,69 @@
+package chord
+
+// SuccessorPolicy chooses preferred nodes for replication and failover from a successor list in ring order.
+// Policies must keep the first successor at the head, so that the head is always the exact successor.
+type SuccessorPolicy interface {
+	Select(successors []RingNode, r int) []RingNode
+}
+
+// featureDependentPolicy is a policy which works only once every neighbour supports a feature.
+// Until then, successors are preferred in ring order.
+type featureDependentPolicy interface {
+	RequiredFeature() Feature
+}
+
+// RingOrderPolicy prefers successors in ring order.
+type RingOrderPolicy struct{}
+
+// Select is implemented for SuccessorPolicy interface.
+func (RingOrderPolicy) Select(successors []RingNode, r int) []RingNode {
+	if len(successors) < r {
+		r = len(successors)
+	}
+	selected := emptyNodes(r)
+	return append(selected, successors[:r]...)
+}
+
+// ZoneDiversePolicy prefers successors in distinct zones for the first r entries.
+// It walks the successor list in ring order and picks nodes whose zone has not been picked yet.
+// If there are not enough zones, the remaining entries are filled in ring order.
+type ZoneDiversePolicy struct{}
+
+// RequiredFeature returns a feature this policy relies on, since peers without it don't propagate zones.
+func (ZoneDiversePolicy) RequiredFeature() Feature {
+	return FeatureZones
+}
+
+// Select is implemented for SuccessorPolicy interface.
+func (ZoneDiversePolicy) Select(successors []RingNode, r int) []RingNode {
+	if len(successors) < r {
+		r = len(successors)
+	}
+	selected := emptyNodes(r)
+	if r == 0 {
+		return selected
+	}
+	picked := make([]bool, len(successors))
+	zones := map[string]struct{}{}
+	for i, suc := range successors {
+		if len(selected) >= r {
+			return selected
+		}
+		zone := suc.Reference().Zone
+		if _, ok := zones[zone]; ok && i > 0 {
+			continue
+		}
+		zones[zone] = struct{}{}
+		picked[i] = true
+		selected = append(selected, suc)
+	}
+	for i, suc := range successors {
+		if len(selected) >= r {
+			break
+		}
+		if !picked[i] {
+			selected = append(selected, suc)
+		}
+	}
+	return selected
+}
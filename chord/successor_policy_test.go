@@ -0,0 +1,88 @@
+package chord
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func createZonedNodes(zones ...string) []RingNode {
+	nodes := createNodes(len(zones))
+	ringNodes := make([]RingNode, len(nodes))
+	for i, node := range nodes {
+		node.Zone = zones[i]
+		ringNodes[i] = node
+	}
+	return ringNodes
+}
+
+func TestRingOrderPolicy_Select(t *testing.T) {
+	nodes := createZonedNodes("a", "a", "b", "c")
+	assert.Equal(t, nodes[:2], RingOrderPolicy{}.Select(nodes, 2))
+	assert.Equal(t, nodes, RingOrderPolicy{}.Select(nodes, 10))
+}
+
+func TestZoneDiversePolicy_Select(t *testing.T) {
+	nodes := createZonedNodes("a", "a", "b", "a", "c", "b")
+	testcases := []struct {
+		r        int
+		expected []RingNode
+	}{
+		{
+			r:        0,
+			expected: []RingNode{},
+		},
+		{
+			r:        1,
+			expected: []RingNode{nodes[0]},
+		},
+		{
+			r:        3,
+			expected: []RingNode{nodes[0], nodes[2], nodes[4]},
+		},
+		{
+			r:        5,
+			expected: []RingNode{nodes[0], nodes[2], nodes[4], nodes[1], nodes[3]},
+		},
+		{
+			r:        10,
+			expected: []RingNode{nodes[0], nodes[2], nodes[4], nodes[1], nodes[3], nodes[5]},
+		},
+	}
+	for _, testcase := range testcases {
+		assert.Equal(t, testcase.expected, ZoneDiversePolicy{}.Select(nodes, testcase.r))
+	}
+}
+
+func TestLocalNode_PreferredSuccessors(t *testing.T) {
+	ctx := context.Background()
+	nodes := createZonedNodes("a", "a", "a", "b")
+	node1 := nodes[0].(*LocalNode)
+	node1.CreateRing()
+	node1.JoinSuccessors(0, nodes[1:])
+	node1.policy = ZoneDiversePolicy{}
+
+	successors, err := node1.PreferredSuccessors(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []RingNode{nodes[1], nodes[3]}, successors)
+	// Ring order is kept in the successor list itself.
+	assert.Equal(t, nodes[1:], node1.successors.nodes)
+}
+
+func TestLocalNode_FindReplicas_ZoneDiverse(t *testing.T) {
+	ctx := context.Background()
+	nodes := createReplicatedRing(5)
+	for i, zone := range []string{"a", "a", "a", "b", "c"} {
+		nodes[i].Zone = zone
+	}
+
+	replicas, err := nodes[0].FindReplicas(ctx, nodes[0].ID, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{nodes[0].Key(), nodes[1].Key(), nodes[2].Key()}, nodeKeys(replicas))
+
+	// Replicas are spread over zones, even though the next successors share the owner's zone.
+	nodes[0].policy = ZoneDiversePolicy{}
+	replicas, err = nodes[0].FindReplicas(ctx, nodes[0].ID, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{nodes[0].Key(), nodes[3].Key(), nodes[4].Key()}, nodeKeys(replicas))
+}
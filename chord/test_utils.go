@@ -99,3 +99,12 @@ func waitGenerateProcesses(ctx context.Context, processCount int) []*Process {
 	wg.Wait()
 	return processes
 }
+
+// nodeKeys returns keys of nodes, which are easier to compare than nodes.
+func nodeKeys(nodes []RingNode) []string {
+	keys := make([]string, len(nodes))
+	for i, node := range nodes {
+		keys[i] = node.Reference().Key()
+	}
+	return keys
+}
@@ -0,0 +1,553 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
+	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/server"
+	"github.com/taisho6339/gord/storage"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultPort is the port of gord's external gRPC server.
+	DefaultPort = "26041"
+)
+
+// Client is a gord client.
+// It load-balances requests across gord nodes and fails over to other nodes on Unavailable.
+// Requests refused by overloaded nodes are retried after the delay the nodes hint.
+type Client struct {
+	endpoints []*endpoint
+	next      uint32
+	opt       *clientOption
+	closeOnce sync.Once
+	table     *routingTable
+	tableLock sync.RWMutex
+	// tableGeneration increases on every Invalidate, so that a snapshot downloaded before it is discarded.
+	tableGeneration uint64
+	cancelWatch     context.CancelFunc
+}
+
+type endpoint struct {
+	address string
+	conn    *grpc.ClientConn
+	client  server.ExternalServiceClient
+}
+
+type clientOption struct {
+	timeout      time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	dialOpts     []grpc.DialOption
+	routingCache bool
+}
+
+// ClientOptionFunc is function to apply options to a client
+type ClientOptionFunc func(option *clientOption)
+
+func newDefaultClientOption() *clientOption {
+	return &clientOption{
+		timeout:      3 * time.Second,
+		maxRetries:   3,
+		retryBackoff: 100 * time.Millisecond,
+		dialOpts:     []grpc.DialOption{grpc.WithInsecure()},
+	}
+}
+
+// WithTimeout sets the timeout of each attempt.
+func WithTimeout(duration time.Duration) ClientOptionFunc {
+	return func(option *clientOption) {
+		option.timeout = duration
+	}
+}
+
+// WithMaxRetries sets how many times a request is retried after trying every endpoint.
+func WithMaxRetries(retries int) ClientOptionFunc {
+	return func(option *clientOption) {
+		option.maxRetries = retries
+	}
+}
+
+// WithRetryBackoff sets the wait before retrying every endpoint again.
+func WithRetryBackoff(duration time.Duration) ClientOptionFunc {
+	return func(option *clientOption) {
+		option.retryBackoff = duration
+	}
+}
+
+// WithDialOptions replaces gRPC dial options.
+func WithDialOptions(opts ...grpc.DialOption) ClientOptionFunc {
+	return func(option *clientOption) {
+		option.dialOpts = opts
+	}
+}
+
+// WithRoutingCache makes a client resolve keys locally with a snapshot of the ring.
+// The snapshot is refreshed lazily after ring membership changes, after gord reports that a node the snapshot
+// resolves a key of Get or Put to doesn't own it, or after Invalidate calls.
+func WithRoutingCache() ClientOptionFunc {
+	return func(option *clientOption) {
+		option.routingCache = true
+	}
+}
+
+// NewClient creates a client for the given gord endpoints.
+// An endpoint without a port is connected to DefaultPort.
+func NewClient(endpoints []string, opts ...ClientOptionFunc) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	opt := newDefaultClientOption()
+	for _, o := range opts {
+		o(opt)
+	}
+	c := &Client{
+		opt: opt,
+	}
+	for _, address := range endpoints {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, DefaultPort)
+		}
+		conn, err := grpc.Dial(address, opt.dialOpts...)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.endpoints = append(c.endpoints, &endpoint{
+			address: address,
+			conn:    conn,
+			client:  server.NewExternalServiceClient(conn),
+		})
+	}
+	if opt.routingCache {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.cancelWatch = cancel
+		go c.watchRing(ctx)
+	}
+	return c, nil
+}
+
+// Close closes connections to every endpoint.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		if c.cancelWatch != nil {
+			c.cancelWatch()
+		}
+		for _, e := range c.endpoints {
+			e.conn.Close()
+		}
+	})
+}
+
+// FindHostForKey returns the node which a given key belongs to.
+// With the routing cache, it resolves the key locally and only falls back to gord when no snapshot is available.
+func (c *Client) FindHostForKey(ctx context.Context, key string) (*model.NodeRef, error) {
+	if c.opt.routingCache {
+		node, err := c.lookupRoutingTable(ctx, model.NewHashID(key))
+		if err == nil {
+			return node, nil
+		}
+		log.Warnf("routing table lookup failed. fall back to gord. err = %#v", err)
+	}
+	var node *server.Node
+	err := c.invoke(ctx, func(ctx context.Context, client server.ExternalServiceClient) error {
+		var err error
+		node, err = client.FindHostForKey(ctx, &server.FindHostRequest{Key: key})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toNodeRef(node), nil
+}
+
+// FindReplicasForKey returns the node which a given key belongs to, followed by live successors on distinct hosts.
+// It returns up to n nodes in order of preference, for applications which replicate the key by themselves.
+func (c *Client) FindReplicasForKey(ctx context.Context, key string, n int) ([]*model.NodeRef, error) {
+	var replicas *server.Replicas
+	err := c.invoke(ctx, func(ctx context.Context, client server.ExternalServiceClient) error {
+		var err error
+		replicas, err = client.FindReplicasForKey(ctx, &server.FindReplicasRequest{Key: key, N: int32(n)})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*model.NodeRef, len(replicas.Nodes))
+	for i, node := range replicas.Nodes {
+		nodes[i] = toNodeRef(node)
+	}
+	return nodes, nil
+}
+
+// Consistency represents how many of N replicas must answer a request before it returns.
+type Consistency int
+
+const (
+	// Quorum waits for a majority of replicas. It is the default.
+	Quorum Consistency = iota
+	// One waits for any replica.
+	One
+	// All waits for every replica.
+	All
+)
+
+type requestOption struct {
+	consistency Consistency
+	n           int
+	r           int
+	w           int
+	context     *storage.Version
+	ttl         time.Duration
+}
+
+// RequestOptionFunc is function to apply options to a read or a write of a key
+type RequestOptionFunc func(option *requestOption)
+
+// WithConsistency sets how many replicas a request waits for, unless R or W is set explicitly.
+func WithConsistency(consistency Consistency) RequestOptionFunc {
+	return func(option *requestOption) {
+		option.consistency = consistency
+	}
+}
+
+// WithReplicas sets N, how many replicas of a key a request is sent to. The server decides it by default.
+func WithReplicas(n int) RequestOptionFunc {
+	return func(option *requestOption) {
+		option.n = n
+	}
+}
+
+// WithReadQuorum sets R, how many replicas must answer a read.
+func WithReadQuorum(r int) RequestOptionFunc {
+	return func(option *requestOption) {
+		option.r = r
+	}
+}
+
+// WithWriteQuorum sets W, how many replicas must acknowledge a write.
+func WithWriteQuorum(w int) RequestOptionFunc {
+	return func(option *requestOption) {
+		option.w = w
+	}
+}
+
+// WithCausalContext makes a write supersede every value the context descends from.
+// Pass the Context of a record read before, so that the write resolves its siblings instead of adding another one.
+func WithCausalContext(context *storage.Version) RequestOptionFunc {
+	return func(option *requestOption) {
+		option.context = context
+	}
+}
+
+// WithTTL makes a written value expire after ttl. gord turns it into an absolute expiry, which replicas share.
+func WithTTL(ttl time.Duration) RequestOptionFunc {
+	return func(option *requestOption) {
+		option.ttl = ttl
+	}
+}
+
+func newRequestOption(opts []RequestOptionFunc) *requestOption {
+	option := &requestOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
+	return option
+}
+
+func (o *requestOption) consistencyProto() server.Consistency {
+	switch o.consistency {
+	case One:
+		return server.Consistency_ONE
+	case All:
+		return server.Consistency_ALL
+	default:
+		return server.Consistency_QUORUM
+	}
+}
+
+// Get reads the record of a key from its replicas. Stale replicas found on the way are repaired by gord.
+// The record holds siblings if gord keeps concurrent values. It returns ErrKeyNotFound if no replica holds the key.
+func (c *Client) Get(ctx context.Context, key string, opts ...RequestOptionFunc) (*storage.Record, error) {
+	option := newRequestOption(opts)
+	ctx, header, checkOwner := c.expectOwner(ctx, key)
+	var record *server.Record
+	err := c.invoke(ctx, func(ctx context.Context, client server.ExternalServiceClient) error {
+		var err error
+		record, err = client.Get(ctx, &server.GetRequest{
+			Key:         key,
+			Consistency: option.consistencyProto(),
+			N:           int32(option.n),
+			R:           int32(option.r),
+		}, header)
+		return err
+	})
+	checkOwner()
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toStorageRecord(record), nil
+}
+
+// Put writes the value of a key to its replicas, and returns the version it is written with.
+func (c *Client) Put(ctx context.Context, key string, value []byte, opts ...RequestOptionFunc) (*storage.Version, error) {
+	option := newRequestOption(opts)
+	req := &server.PutRequest{
+		Record:      &server.Record{Key: key, Value: value, Version: toVersionProto(option.context)},
+		Consistency: option.consistencyProto(),
+		N:           int32(option.n),
+		W:           int32(option.w),
+	}
+	if option.ttl > 0 {
+		req.Ttl = ptypes.DurationProto(option.ttl)
+	}
+	ctx, header, checkOwner := c.expectOwner(ctx, key)
+	var res *server.PutResponse
+	err := c.invoke(ctx, func(ctx context.Context, client server.ExternalServiceClient) error {
+		var err error
+		res, err = client.Put(ctx, req, header)
+		return err
+	})
+	checkOwner()
+	if err != nil {
+		return nil, err
+	}
+	return toStorageVersion(res.Version), nil
+}
+
+// Invalidate drops the snapshot of the ring, so that the next lookup downloads it again.
+// The client calls it by itself when gord reports a stale snapshot. Call it when a node returned by FindHostForKey
+// reports that it does not own the key by other means, such as a protocol of the application.
+func (c *Client) Invalidate() {
+	c.tableLock.Lock()
+	defer c.tableLock.Unlock()
+	c.table = nil
+	c.tableGeneration++
+}
+
+// expectOwner tells gord the node which the snapshot of the ring resolves a key to, if the client has a snapshot.
+// It returns a call option receiving the response header, and a function which drops the snapshot
+// if gord has reported in the header that the node doesn't own the key.
+func (c *Client) expectOwner(ctx context.Context, key string) (context.Context, grpc.CallOption, func()) {
+	header := &metadata.MD{}
+	noop := func() {}
+	if !c.opt.routingCache {
+		return ctx, grpc.Header(header), noop
+	}
+	c.tableLock.RLock()
+	table := c.table
+	c.tableLock.RUnlock()
+	if table == nil {
+		return ctx, grpc.Header(header), noop
+	}
+	owner, err := table.lookup(model.NewHashID(key))
+	if err != nil {
+		return ctx, grpc.Header(header), noop
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, server.ExpectedOwnerHeader, owner.Key())
+	return ctx, grpc.Header(header), func() {
+		if actual := header.Get(server.NotOwnerHeader); len(actual) > 0 {
+			log.Infof("%s doesn't own %s, but %s does. refresh the snapshot of the ring.", owner.Key(), key, actual[0])
+			c.Invalidate()
+		}
+	}
+}
+
+func (c *Client) lookupRoutingTable(ctx context.Context, id model.HashID) (*model.NodeRef, error) {
+	table, err := c.loadRoutingTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return table.lookup(id)
+}
+
+func (c *Client) loadRoutingTable(ctx context.Context) (*routingTable, error) {
+	c.tableLock.RLock()
+	table := c.table
+	c.tableLock.RUnlock()
+	if table != nil {
+		return table, nil
+	}
+
+	c.tableLock.RLock()
+	generation := c.tableGeneration
+	c.tableLock.RUnlock()
+	// The snapshot is downloaded without the lock, so that lookups and invalidations don't wait for gord.
+	var snapshot *server.RingSnapshot
+	err := c.invoke(ctx, func(ctx context.Context, client server.ExternalServiceClient) error {
+		var err error
+		snapshot, err = client.GetRingSnapshot(ctx, &empty.Empty{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]*model.NodeRef, len(snapshot.Nodes))
+	for i, node := range snapshot.Nodes {
+		refs[i] = toNodeRef(node)
+	}
+	table = newRoutingTable(refs)
+
+	c.tableLock.Lock()
+	defer c.tableLock.Unlock()
+	// A snapshot downloaded before Invalidate may be stale, so it serves only this lookup.
+	if c.tableGeneration == generation {
+		c.table = table
+	}
+	return table, nil
+}
+
+// watchRing invalidates the snapshot of the ring whenever a watched node reports a membership change.
+// When the stream breaks, it invalidates the snapshot too and watches another node.
+func (c *Client) watchRing(ctx context.Context) {
+	for ctx.Err() == nil {
+		e := c.pick()
+		stream, err := e.client.WatchRing(ctx, &empty.Empty{})
+		if err == nil {
+			for {
+				if _, err = stream.Recv(); err != nil {
+					break
+				}
+				c.Invalidate()
+			}
+		}
+		c.Invalidate()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.opt.retryBackoff):
+		}
+	}
+}
+
+// invoke calls f against endpoints in round robin.
+// It moves on to the next endpoint when an endpoint is unavailable or overloaded,
+// and after trying every endpoint, waits for the backoff, or the longest delay overloaded endpoints hint, and retries.
+func (c *Client) invoke(ctx context.Context, f func(ctx context.Context, client server.ExternalServiceClient) error) error {
+	var lastErr error
+	var wait time.Duration
+	for retry := 0; retry <= c.opt.maxRetries; retry++ {
+		if retry > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		wait = c.opt.retryBackoff
+		for range c.endpoints {
+			e := c.pick()
+			err := c.call(ctx, e, f)
+			if err == nil {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !isRetryable(err) {
+				return err
+			}
+			if delay := retryDelay(err); delay > wait {
+				wait = delay
+			}
+			lastErr = err
+		}
+	}
+	return fmt.Errorf("%w. last err = %#v", ErrAllEndpointsUnavailable, lastErr)
+}
+
+func (c *Client) call(ctx context.Context, e *endpoint, f func(ctx context.Context, client server.ExternalServiceClient) error) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opt.timeout)
+	defer cancel()
+	return f(ctx, e.client)
+}
+
+func (c *Client) pick() *endpoint {
+	n := atomic.AddUint32(&c.next, 1) - 1
+	return c.endpoints[n%uint32(len(c.endpoints))]
+}
+
+func toNodeRef(node *server.Node) *model.NodeRef {
+	ref := model.NewVirtualNodeRef(node.Host, int(node.Vnode))
+	ref.Zone = node.Zone
+	ref.Metadata = node.Metadata
+	if len(node.PublicKey) > 0 {
+		ref.SetIdentity(node.PublicKey, node.Signature)
+	}
+	return ref
+}
+
+func toStorageRecord(record *server.Record) *storage.Record {
+	converted := &storage.Record{
+		Key:       record.Key,
+		Value:     record.Value,
+		Version:   toStorageVersion(record.Version),
+		ExpiresAt: record.ExpiresAt,
+	}
+	for _, sibling := range record.Siblings {
+		converted.Siblings = append(converted.Siblings, &storage.Sibling{
+			Value:     sibling.Value,
+			Version:   toStorageVersion(sibling.Version),
+			ExpiresAt: sibling.ExpiresAt,
+		})
+	}
+	return converted
+}
+
+func toStorageVersion(version *server.Version) *storage.Version {
+	if version == nil {
+		return nil
+	}
+	return &storage.Version{
+		Clock:     version.Clock,
+		Timestamp: version.Timestamp,
+	}
+}
+
+func toVersionProto(version *storage.Version) *server.Version {
+	if version == nil {
+		return nil
+	}
+	return &server.Version{
+		Clock:     version.Clock,
+		Timestamp: version.Timestamp,
+	}
+}
+
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryDelay returns how long a server asks a client to wait before retrying, or 0 without a hint.
+func retryDelay(err error) time.Duration {
+	for _, detail := range status.Convert(err).Details() {
+		info, ok := detail.(*errdetails.RetryInfo)
+		if !ok {
+			continue
+		}
+		if delay, err := ptypes.Duration(info.RetryDelay); err == nil {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,314 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/pkg/test"
+	"github.com/taisho6339/gord/server"
+	"github.com/taisho6339/gord/storage"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeExternalServer struct {
+	server.UnimplementedExternalServiceServer
+	host      string
+	calls     int
+	snapshots int
+	ring      []string
+	events    chan *server.RingEvent
+	records   map[string]*server.Record
+	requests  []*server.PutRequest
+	// owner is reported as the owner of every key to a client expecting another node.
+	owner string
+	// exhausted is how many lookups are refused as overloaded, with a hint to retry after retryDelay.
+	exhausted  int
+	retryDelay time.Duration
+}
+
+func (f *fakeExternalServer) GetRingSnapshot(_ context.Context, _ *empty.Empty) (*server.RingSnapshot, error) {
+	f.snapshots++
+	nodes := make([]*server.Node, len(f.ring))
+	for i, host := range f.ring {
+		nodes[i] = &server.Node{Host: host}
+	}
+	return &server.RingSnapshot{Nodes: nodes}, nil
+}
+
+func (f *fakeExternalServer) WatchRing(_ *empty.Empty, stream server.ExternalService_WatchRingServer) error {
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-f.events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (f *fakeExternalServer) FindHostForKey(_ context.Context, req *server.FindHostRequest) (*server.Node, error) {
+	f.calls++
+	if f.exhausted > 0 {
+		f.exhausted--
+		st, _ := status.New(codes.ResourceExhausted, "overloaded").WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(f.retryDelay)})
+		return nil, st.Err()
+	}
+	return &server.Node{Host: f.host, Metadata: map[string]string{"port": "8080"}}, nil
+}
+
+func (f *fakeExternalServer) FindReplicasForKey(_ context.Context, req *server.FindReplicasRequest) (*server.Replicas, error) {
+	f.calls++
+	nodes := make([]*server.Node, 0, req.N)
+	for _, host := range f.ring {
+		if len(nodes) >= int(req.N) {
+			break
+		}
+		nodes = append(nodes, &server.Node{Host: host, Zone: "zone-" + host})
+	}
+	return &server.Replicas{Nodes: nodes}, nil
+}
+
+func (f *fakeExternalServer) Get(ctx context.Context, req *server.GetRequest) (*server.Record, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if expected := md.Get(server.ExpectedOwnerHeader); len(expected) > 0 && f.owner != "" && expected[0] != f.owner {
+		grpc.SetHeader(ctx, metadata.Pairs(server.NotOwnerHeader, f.owner))
+	}
+	record, ok := f.records[req.Key]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	return record, nil
+}
+
+func (f *fakeExternalServer) Put(_ context.Context, req *server.PutRequest) (*server.PutResponse, error) {
+	f.requests = append(f.requests, req)
+	version := &server.Version{Clock: map[string]uint64{f.host: uint64(len(f.requests))}, Timestamp: uint64(len(f.requests))}
+	f.records[req.Record.Key] = &server.Record{
+		Key:      req.Record.Key,
+		Value:    req.Record.Value,
+		Version:  version,
+		Siblings: []*server.Sibling{{Value: []byte("sibling")}},
+	}
+	return &server.PutResponse{Version: version}, nil
+}
+
+func runFakeServer(t *testing.T, host string) (string, *fakeExternalServer, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	fake := &fakeExternalServer{host: host, events: make(chan *server.RingEvent), records: map[string]*server.Record{}}
+	s := grpc.NewServer()
+	server.RegisterExternalServiceServer(s, fake)
+	go s.Serve(lis)
+	return lis.Addr().String(), fake, s.Stop
+}
+
+func TestClient_FindHostForKey_LoadBalance(t *testing.T) {
+	address1, fake1, stop1 := runFakeServer(t, "gord1")
+	defer stop1()
+	address2, fake2, stop2 := runFakeServer(t, "gord2")
+	defer stop2()
+
+	c, err := NewClient([]string{address1, address2})
+	assert.NoError(t, err)
+	defer c.Close()
+	for i := 0; i < 4; i++ {
+		_, err := c.FindHostForKey(context.Background(), "key")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, fake1.calls)
+	assert.Equal(t, 2, fake2.calls)
+}
+
+func TestClient_FindHostForKey_Failover(t *testing.T) {
+	address1, _, stop1 := runFakeServer(t, "gord1")
+	address2, fake2, stop2 := runFakeServer(t, "gord2")
+	defer stop2()
+	stop1()
+
+	c, err := NewClient([]string{address1, address2}, WithTimeout(time.Second))
+	assert.NoError(t, err)
+	defer c.Close()
+	for i := 0; i < 2; i++ {
+		node, err := c.FindHostForKey(context.Background(), "key")
+		assert.NoError(t, err)
+		assert.Equal(t, "gord2", node.Host)
+		assert.Equal(t, "8080", node.Metadata["port"])
+	}
+	assert.Equal(t, 2, fake2.calls)
+}
+
+func TestClient_FindHostForKey_AllUnavailable(t *testing.T) {
+	address1, _, stop1 := runFakeServer(t, "gord1")
+	stop1()
+
+	c, err := NewClient([]string{address1}, WithMaxRetries(1), WithRetryBackoff(time.Millisecond))
+	assert.NoError(t, err)
+	defer c.Close()
+	_, err = c.FindHostForKey(context.Background(), "key")
+	assert.True(t, errors.Is(err, ErrAllEndpointsUnavailable))
+}
+
+func TestClient_FindHostForKey_ResourceExhausted(t *testing.T) {
+	address, fake, stop := runFakeServer(t, "gord1")
+	defer stop()
+	fake.exhausted = 1
+	fake.retryDelay = 200 * time.Millisecond
+
+	c, err := NewClient([]string{address}, WithRetryBackoff(time.Millisecond))
+	assert.NoError(t, err)
+	defer c.Close()
+	start := time.Now()
+	node, err := c.FindHostForKey(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "gord1", node.Host)
+	assert.Equal(t, 2, fake.calls)
+	// The client waits for the hinted delay rather than its own backoff.
+	assert.True(t, time.Since(start) >= fake.retryDelay)
+
+	c, err = NewClient([]string{address}, WithMaxRetries(1), WithRetryBackoff(time.Millisecond))
+	assert.NoError(t, err)
+	defer c.Close()
+	fake.exhausted = 2
+	_, err = c.FindHostForKey(context.Background(), "key")
+	assert.True(t, errors.Is(err, ErrAllEndpointsUnavailable))
+}
+
+func TestClient_FindReplicasForKey(t *testing.T) {
+	address, fake, stop := runFakeServer(t, "gord1")
+	defer stop()
+	fake.ring = []string{"gord1", "gord2", "gord3"}
+
+	c, err := NewClient([]string{address})
+	assert.NoError(t, err)
+	defer c.Close()
+	nodes, err := c.FindReplicasForKey(context.Background(), "key", 2)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "gord1", nodes[0].Host)
+	assert.Equal(t, "gord2", nodes[1].Host)
+	assert.Equal(t, "zone-gord2", nodes[1].Zone)
+}
+
+func TestClient_GetPut(t *testing.T) {
+	address, fake, stop := runFakeServer(t, "gord1")
+	defer stop()
+
+	c, err := NewClient([]string{address})
+	assert.NoError(t, err)
+	defer c.Close()
+	ctx := context.Background()
+	_, err = c.Get(ctx, "key")
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	version, err := c.Put(ctx, "key", []byte("value"), WithConsistency(All), WithReplicas(5))
+	assert.NoError(t, err)
+	assert.Equal(t, &storage.Version{Clock: map[string]uint64{"gord1": 1}, Timestamp: 1}, version)
+	assert.Equal(t, server.Consistency_ALL, fake.requests[0].Consistency)
+	assert.Equal(t, int32(5), fake.requests[0].N)
+	assert.Nil(t, fake.requests[0].Record.Version)
+
+	record, err := c.Get(ctx, "key", WithConsistency(One))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), record.Value)
+	assert.Equal(t, version, record.Version)
+	assert.Equal(t, []*storage.Sibling{{Value: []byte("sibling")}}, record.Siblings)
+
+	// The context of the record read is sent along with the value resolving its siblings.
+	_, err = c.Put(ctx, "key", []byte("resolved"), WithWriteQuorum(2), WithCausalContext(record.Context()), WithTTL(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(60), fake.requests[1].Ttl.Seconds)
+	assert.Nil(t, fake.requests[0].Ttl)
+	assert.Equal(t, server.Consistency_QUORUM, fake.requests[1].Consistency)
+	assert.Equal(t, int32(2), fake.requests[1].W)
+	assert.Equal(t, map[string]uint64{"gord1": 1}, fake.requests[1].Record.Version.Clock)
+}
+
+func TestNewClient_NoEndpoints(t *testing.T) {
+	_, err := NewClient(nil)
+	assert.Equal(t, ErrNoEndpoints, err)
+}
+
+func TestClient_FindHostForKey_RoutingCache(t *testing.T) {
+	address, fake, stop := runFakeServer(t, "gord1")
+	defer stop()
+	fake.ring = []string{"gord1", "gord2", "gord3"}
+	table := newRoutingTable(newNodeRefs(fake.ring...))
+
+	c, err := NewClient([]string{address}, WithRoutingCache())
+	assert.NoError(t, err)
+	defer c.Close()
+	for _, key := range []string{"gord1", "gord2", "gord3", "key"} {
+		node, err := c.FindHostForKey(context.Background(), key)
+		assert.NoError(t, err)
+		expected, _ := table.lookup(model.NewHashID(key))
+		assert.Equal(t, expected.Host, node.Host)
+	}
+	assert.Equal(t, 0, fake.calls)
+	assert.Equal(t, 1, fake.snapshots)
+
+	// A ring event makes the client download the snapshot again.
+	fake.events <- &server.RingEvent{Type: server.RingEvent_NODE_DEAD, Node: &server.Node{Host: "gord3"}}
+	fake.ring = []string{"gord1", "gord2"}
+	test.WaitCheckFuncWithTimeout(func() {
+		t.Fatal("test failed by timeout.")
+	}, func() bool {
+		c.tableLock.RLock()
+		defer c.tableLock.RUnlock()
+		return c.table == nil
+	}, 5*time.Second)
+	_, err = c.FindHostForKey(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.snapshots)
+
+	c.Invalidate()
+	_, err = c.FindHostForKey(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fake.snapshots)
+}
+
+func TestClient_RoutingCache_NotOwner(t *testing.T) {
+	address, fake, stop := runFakeServer(t, "gord1")
+	defer stop()
+	fake.ring = []string{"gord1", "gord2", "gord3"}
+	table := newRoutingTable(newNodeRefs(fake.ring...))
+	owner, _ := table.lookup(model.NewHashID("key"))
+	fake.owner = owner.Key()
+
+	c, err := NewClient([]string{address}, WithRoutingCache())
+	assert.NoError(t, err)
+	defer c.Close()
+	ctx := context.Background()
+	_, err = c.FindHostForKey(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.snapshots)
+
+	// gord agrees with the snapshot, so it's kept.
+	_, err = c.Get(ctx, "key")
+	assert.Equal(t, ErrKeyNotFound, err)
+	c.tableLock.RLock()
+	assert.NotNil(t, c.table)
+	c.tableLock.RUnlock()
+
+	// gord reports another owner, so the snapshot is downloaded again by the next lookup.
+	fake.owner = "gord4"
+	_, err = c.Get(ctx, "key")
+	assert.Equal(t, ErrKeyNotFound, err)
+	c.tableLock.RLock()
+	assert.Nil(t, c.table)
+	c.tableLock.RUnlock()
+	_, err = c.FindHostForKey(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.snapshots)
+}
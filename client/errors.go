@@ -0,0 +1,14 @@
+package client
+
+import "errors"
+
+var (
+	// ErrNoEndpoints represents no endpoint is given to a client.
+	ErrNoEndpoints = errors.New("NoEndpoints")
+	// ErrAllEndpointsUnavailable represents every endpoint failed within the retries.
+	ErrAllEndpointsUnavailable = errors.New("AllEndpointsUnavailable")
+	// ErrEmptyRoutingTable represents a routing table has no node.
+	ErrEmptyRoutingTable = errors.New("EmptyRoutingTable")
+	// ErrKeyNotFound represents no replica holds a key.
+	ErrKeyNotFound = errors.New("KeyNotFound")
+)
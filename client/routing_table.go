@@ -0,0 +1,49 @@
+package client
+
+import (
+	"github.com/taisho6339/gord/pkg/model"
+	"sort"
+)
+
+// routingTable represents a snapshot of a chord ring.
+// Nodes are sorted by their IDs.
+type routingTable struct {
+	nodes []*model.NodeRef
+}
+
+func newRoutingTable(refs []*model.NodeRef) *routingTable {
+	seen := map[string]struct{}{}
+	nodes := make([]*model.NodeRef, 0, len(refs))
+	for _, ref := range refs {
+		if _, ok := seen[ref.Key()]; ok {
+			continue
+		}
+		seen[ref.Key()] = struct{}{}
+		nodes = append(nodes, ref)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID.LessThan(nodes[j].ID)
+	})
+	return &routingTable{
+		nodes: nodes,
+	}
+}
+
+// lookup returns the node which a given id belongs to.
+// A node owns ids in (its predecessor, itself], the same rule as LocalNode uses.
+func (t *routingTable) lookup(id model.HashID) (*model.NodeRef, error) {
+	if len(t.nodes) == 0 {
+		return nil, ErrEmptyRoutingTable
+	}
+	if len(t.nodes) == 1 {
+		return t.nodes[0], nil
+	}
+	pred := t.nodes[len(t.nodes)-1]
+	for _, node := range t.nodes {
+		if id.Between(pred.ID, node.ID.Add(1)) {
+			return node, nil
+		}
+		pred = node
+	}
+	return t.nodes[0], nil
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/pkg/model"
+	"testing"
+)
+
+func newNodeRefs(hosts ...string) []*model.NodeRef {
+	refs := make([]*model.NodeRef, len(hosts))
+	for i, host := range hosts {
+		refs[i] = model.NewNodeRef(host)
+	}
+	return refs
+}
+
+func TestRoutingTable_Lookup(t *testing.T) {
+	table := newRoutingTable(newNodeRefs("gord1", "gord2", "gord3", "gord2"))
+	assert.Equal(t, 3, len(table.nodes))
+	for i := 1; i < len(table.nodes); i++ {
+		assert.True(t, table.nodes[i-1].ID.LessThan(table.nodes[i].ID))
+	}
+	first, second, last := table.nodes[0], table.nodes[1], table.nodes[2]
+	testcases := []struct {
+		id       model.HashID
+		expected *model.NodeRef
+	}{
+		{
+			id:       first.ID,
+			expected: first,
+		},
+		{
+			id:       first.ID.Add(1),
+			expected: second,
+		},
+		{
+			id:       second.ID,
+			expected: second,
+		},
+		{
+			id:       last.ID,
+			expected: last,
+		},
+		{
+			id:       last.ID.Add(1),
+			expected: first,
+		},
+		{
+			id:       model.BytesToHashID([]byte{0}),
+			expected: first,
+		},
+	}
+	for _, testcase := range testcases {
+		node, err := table.lookup(testcase.id)
+		assert.NoError(t, err)
+		assert.Equal(t, testcase.expected.Host, node.Host)
+	}
+}
+
+func TestRoutingTable_Lookup_SingleNode(t *testing.T) {
+	table := newRoutingTable(newNodeRefs("gord1"))
+	node, err := table.lookup(model.NewHashID("key"))
+	assert.NoError(t, err)
+	assert.Equal(t, "gord1", node.Host)
+}
+
+func TestRoutingTable_Lookup_Empty(t *testing.T) {
+	table := newRoutingTable(nil)
+	_, err := table.lookup(model.NewHashID("key"))
+	assert.Equal(t, ErrEmptyRoutingTable, err)
+}
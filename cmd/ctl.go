@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/taisho6339/gord/chord"
+	"github.com/taisho6339/gord/client"
+	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/server"
+	"strconv"
+	"time"
+)
+
+var (
+	output    string
+	timeout   time.Duration
+	endpoints []string
+	vnode     int
+)
+
+// lookupResult represents a node which a key belongs to.
+type lookupResult struct {
+	Key  string         `json:"key"`
+	Node *model.NodeRef `json:"node"`
+}
+
+func addOutputFlags(command *cobra.Command) {
+	command.Flags().StringVarP(&output, "output", "o", outputTable, "output format. table or json.")
+	command.Flags().DurationVar(&timeout, "timeout", 3*time.Second, "timeout of each request.")
+}
+
+func addNodeFlags(command *cobra.Command) {
+	addOutputFlags(command)
+	addClusterFlags(command)
+	command.Flags().IntVar(&vnode, "vnode", 0, "index of the virtual node to inspect.")
+}
+
+func newLookupCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:          "lookup <key...>",
+		Short:        "Find nodes which keys belong to",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+			c, err := client.NewClient(endpoints, client.WithTimeout(timeout))
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			results := make([]lookupResult, 0, len(args))
+			for _, key := range args {
+				node, err := c.FindHostForKey(context.Background(), key)
+				if err != nil {
+					return fmt.Errorf("lookup %q failed. err = %w", key, err)
+				}
+				results = append(results, lookupResult{Key: key, Node: node})
+			}
+			if output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), results)
+			}
+			rows := make([][]string, len(results))
+			for i, result := range results {
+				rows[i] = []string{result.Key, result.Node.Host, strconv.Itoa(result.Node.VNode), formatZone(result.Node)}
+			}
+			return printTable(cmd.OutOrStdout(), []string{"KEY", "HOST", "VNODE", "ZONE"}, rows)
+		},
+	}
+	addOutputFlags(command)
+	command.Flags().StringSliceVar(&endpoints, "endpoints", []string{"127.0.0.1:" + client.DefaultPort}, "addresses of external servers of gord nodes.")
+	return command
+}
+
+// fetchDebugState returns a routing state of a node given as an address of its internal server.
+func fetchDebugState(address string) (chord.DebugState, error) {
+	secret, err := loadClusterSecret()
+	if err != nil {
+		return chord.DebugState{}, err
+	}
+	c, err := server.NewInspectClient(address, timeout, secret)
+	if err != nil {
+		return chord.DebugState{}, err
+	}
+	defer c.Close()
+	return c.DebugState(context.Background(), vnode)
+}
+
+func newStatusCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:          "status <node>",
+		Short:        "Show a routing state and stabilization results of a node",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+			state, err := fetchDebugState(args[0])
+			if err != nil {
+				return err
+			}
+			if output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), state)
+			}
+			w := cmd.OutOrStdout()
+			err = printTable(w, []string{"NODE", "PREDECESSOR", "SUCCESSORS", "LAST STABILIZED FINGER", "SHUTDOWN"}, [][]string{{
+				formatNode(state.Node),
+				formatNode(state.Predecessor),
+				strconv.Itoa(len(state.Successors)),
+				fmt.Sprintf("%d/%d", state.LastStabilizedIndex, len(state.Fingers)-1),
+				strconv.FormatBool(state.IsShutdown),
+			}})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			rows := make([][]string, len(state.Stabilizers))
+			for i, s := range state.Stabilizers {
+				lastError := s.LastError
+				if lastError == "" {
+					lastError = "-"
+				}
+				rows[i] = []string{s.Name, formatTime(s.LastRun), formatTime(s.LastSuccess), formatTime(s.LastErrorAt), lastError}
+			}
+			return printTable(w, []string{"STABILIZER", "LAST RUN", "LAST SUCCESS", "LAST ERROR AT", "LAST ERROR"}, rows)
+		},
+	}
+	addNodeFlags(command)
+	return command
+}
+
+func newSuccessorsCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:          "successors <node>",
+		Short:        "Show the successor list of a node",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+			state, err := fetchDebugState(args[0])
+			if err != nil {
+				return err
+			}
+			if output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), state.Successors)
+			}
+			rows := make([][]string, len(state.Successors))
+			for i, suc := range state.Successors {
+				rows[i] = []string{strconv.Itoa(i), suc.Host, strconv.Itoa(suc.VNode), formatZone(suc), suc.ID.String()}
+			}
+			return printTable(cmd.OutOrStdout(), []string{"INDEX", "HOST", "VNODE", "ZONE", "ID"}, rows)
+		},
+	}
+	addNodeFlags(command)
+	return command
+}
+
+func newFingersCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:          "fingers <node>",
+		Short:        "Show the finger table of a node",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+			state, err := fetchDebugState(args[0])
+			if err != nil {
+				return err
+			}
+			if output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), state.Fingers)
+			}
+			rows := make([][]string, len(state.Fingers))
+			for i, finger := range state.Fingers {
+				rows[i] = []string{strconv.Itoa(finger.Index), finger.ID.String(), formatNode(finger.Node)}
+			}
+			return printTable(cmd.OutOrStdout(), []string{"INDEX", "ID", "NODE"}, rows)
+		},
+	}
+	addNodeFlags(command)
+	return command
+}
@@ -1,22 +1,54 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/taisho6339/gord/chord"
 	"github.com/taisho6339/gord/server"
+	"github.com/taisho6339/gord/storage"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 )
 
 var (
-	sigs          = make(chan os.Signal, 1)
-	done          = make(chan bool, 1)
-	host          string
-	existNodeHost string
+	sigs                 = make(chan os.Signal, 1)
+	done                 = make(chan bool, 1)
+	host                 string
+	existNodeHost        string
+	minStabilizeInterval time.Duration
+	maxStabilizeInterval time.Duration
+	proximitySelection   bool
+	zone                 string
+	zoneAwareSuccessors  bool
+	metadata             map[string]string
+	weight               int
+	faultInjection       bool
+	faultInjector        = chord.NewFaultInjector(time.Now().UnixNano())
+	identityFile         string
+	verifyPeers          bool
+	clusterName          string
+	clusterSecretFile    string
+	clusterSecret        *server.ClusterSecret
+	clientRateLimit      float64
+	clientBurst          int
+	maxConcurrent        int
+	internalLoadLimit    int
+	lookupCacheTTL       time.Duration
+	lookupCacheSize      int
+	storageEngine        string
+	dataDir              string
+	syncWrites           bool
+	replicationFactor    int
+	antiEntropyInterval  time.Duration
+	conflictPolicy       string
+	reapInterval         time.Duration
 )
 
 const (
@@ -24,6 +56,67 @@ const (
 	internalServerPort = "26040" //TODO: to be configurable
 )
 
+func newTransport(node *chord.LocalNode) chord.Transport {
+	var opts []server.ApiClientOptionFunc
+	if clusterSecret != nil {
+		opts = append(opts, server.WithClientClusterSecret(clusterSecret))
+	}
+	transport := server.NewChordApiClient(node, internalServerPort, time.Second*3, opts...)
+	if faultInjection {
+		return chord.NewFaultTransport(transport, faultInjector)
+	}
+	return transport
+}
+
+func addClusterFlags(command *cobra.Command) {
+	command.Flags().StringVar(&clusterName, "cluster-name", "gord", "name of the cluster, which is signed along with the cluster secret.")
+	command.Flags().StringVar(&clusterSecretFile, "cluster-secret-file", "", "file of a secret shared in the cluster. requests to internal servers without an HMAC of the secret are rejected.")
+}
+
+// loadClusterSecret reads the cluster secret. It returns nil if no secret file is given.
+func loadClusterSecret() (*server.ClusterSecret, error) {
+	if clusterSecretFile == "" {
+		return nil, nil
+	}
+	secret, err := ioutil.ReadFile(clusterSecretFile)
+	if err != nil {
+		return nil, err
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("cluster secret file %s is empty", clusterSecretFile)
+	}
+	return server.NewClusterSecret(clusterName, secret), nil
+}
+
+// newConflictPolicy returns a policy deciding which of concurrent values of a key replicas keep.
+func newConflictPolicy() (storage.ConflictPolicy, error) {
+	switch conflictPolicy {
+	case "lww":
+		return storage.LastWriterWins, nil
+	case "siblings":
+		return storage.KeepSiblings, nil
+	default:
+		return 0, fmt.Errorf("unknown conflict policy %q. choose lww or siblings", conflictPolicy)
+	}
+}
+
+// newStorage creates an engine to keep records on this host.
+func newStorage() (storage.Engine, error) {
+	switch storageEngine {
+	case "memory":
+		return storage.NewMemoryEngine(), nil
+	case "disk":
+		var opts []storage.DiskOptionFunc
+		if syncWrites {
+			opts = append(opts, storage.WithSyncWrites())
+		}
+		return storage.NewDiskEngine(filepath.Join(dataDir, "data.log"), opts...)
+	default:
+		return nil, fmt.Errorf("unknown storage engine %q. choose memory or disk", storageEngine)
+	}
+}
+
 func main() {
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
@@ -33,26 +126,86 @@ func main() {
 	command := &cobra.Command{
 		Use:   "gordctl",
 		Short: "Run gord process and gRPC server",
-		Long:  "Run gord process and gRPC server",
-		Run: func(cmd *cobra.Command, args []string) {
+		Long:  "Run gord process and gRPC server, or inspect running gord nodes with subcommands",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if clusterSecret, err = loadClusterSecret(); err != nil {
+				return err
+			}
+			nodeOpts := []chord.LocalNodeOptionFunc{chord.WithZone(zone), chord.WithMetadata(metadata)}
+			if identityFile != "" {
+				identity, err := chord.LoadIdentity(identityFile)
+				if err != nil {
+					return err
+				}
+				nodeOpts = append(nodeOpts, chord.WithIdentity(identity))
+			}
+			if verifyPeers {
+				nodeOpts = append(nodeOpts, chord.WithVerifiedPeers())
+			}
+			engine, err := newStorage()
+			if err != nil {
+				return err
+			}
+			policy, err := newConflictPolicy()
+			if err != nil {
+				return err
+			}
 			var (
 				ctx, cancel = context.WithCancel(context.Background())
-				localNode   = chord.NewLocalNode(host)
-				transport   = server.NewChordApiClient(localNode, internalServerPort, time.Second*3)
-				process     = chord.NewProcess(localNode, transport)
+				process     = chord.NewWeightedProcess(host, weight, newTransport, nodeOpts...)
 				opts        = []server.InternalServerOptionFunc{
 					server.WithNodeOption(host),
 					server.WithTimeoutConnNode(time.Second * 3),
+					server.WithProcessOptions(
+						chord.WithMinStabilizeInterval(minStabilizeInterval),
+						chord.WithMaxStabilizeInterval(maxStabilizeInterval),
+						chord.WithStorage(engine),
+						chord.WithReplicationFactor(replicationFactor),
+						chord.WithConflictPolicy(policy),
+					),
 				}
 			)
 			defer cancel()
+			if proximitySelection {
+				opts = append(opts, server.WithProcessOptions(chord.WithProximitySelection()))
+			}
+			if zoneAwareSuccessors {
+				opts = append(opts, server.WithProcessOptions(chord.WithSuccessorPolicy(chord.ZoneDiversePolicy{})))
+			}
+			if antiEntropyInterval > 0 {
+				opts = append(opts, server.WithProcessOptions(chord.WithAntiEntropy(antiEntropyInterval)))
+			}
+			if reapInterval > 0 {
+				opts = append(opts, server.WithProcessOptions(chord.WithReaper(reapInterval)))
+			}
+			if clusterSecret != nil {
+				opts = append(opts, server.WithClusterSecret(clusterSecret))
+			}
+			if faultInjection {
+				log.Warn("fault injection is enabled. don't enable it in production.")
+				opts = append(opts, server.WithFaultInjector(faultInjector))
+			}
 			if existNodeHost != "" {
 				opts = append(opts, server.WithProcessOptions(chord.WithExistNode(
 					chord.NewRemoteNode(existNodeHost, process.Transport),
 				)))
 			}
+			var exOpts []server.ExternalServerOptionFunc
+			if clientRateLimit > 0 || maxConcurrent > 0 || internalLoadLimit > 0 {
+				admission := server.NewAdmissionController(
+					server.WithClientRateLimit(clientRateLimit, clientBurst),
+					server.WithMaxConcurrentRequests(maxConcurrent),
+					server.WithInternalLoadLimit(internalLoadLimit),
+				)
+				exOpts = append(exOpts, server.WithAdmission(admission))
+				opts = append(opts, server.WithStabilizerPriority(admission))
+			}
+			if lookupCacheTTL > 0 {
+				exOpts = append(exOpts, server.WithLookupCache(lookupCacheTTL, lookupCacheSize))
+			}
 			ins := server.NewChordServer(process, internalServerPort, opts...)
-			exs := server.NewExternalServer(process, externalServerPort)
+			exs := server.NewExternalServer(process, externalServerPort, exOpts...)
 			go ins.Run(ctx)
 			go exs.Run()
 
@@ -60,10 +213,36 @@ func main() {
 			ins.Shutdown()
 			exs.Shutdown()
 			process.Shutdown()
+			return nil
 		},
 	}
-	command.PersistentFlags().StringVarP(&host, "host", "l", "127.0.0.1", "host name to attach this process.")
-	command.PersistentFlags().StringVarP(&existNodeHost, "exist-node", "n", "", "host name of exist node in chord ring.")
+	command.Flags().StringVarP(&host, "host", "l", "127.0.0.1", "host name to attach this process.")
+	command.Flags().StringVarP(&existNodeHost, "exist-node", "n", "", "host name of exist node in chord ring.")
+	command.Flags().DurationVar(&minStabilizeInterval, "min-stabilize-interval", 50*time.Millisecond, "interval of stabilizers while the ring is changing.")
+	command.Flags().DurationVar(&maxStabilizeInterval, "max-stabilize-interval", 2*time.Second, "upper limit of the interval stabilizers back off to while the ring is stable.")
+	command.Flags().BoolVar(&proximitySelection, "proximity-selection", false, "pick the closest node by RTT for each finger.")
+	command.Flags().StringVar(&zone, "zone", "", "failure domain, such as an availability zone or a rack, of this process.")
+	command.Flags().BoolVar(&zoneAwareSuccessors, "zone-aware-successors", false, "prefer successors in distinct zones for replication and failover.")
+	command.Flags().StringToStringVar(&metadata, "metadata", map[string]string{}, "labels of this process returned to clients, such as port=8080,version=v1.")
+	command.Flags().IntVar(&weight, "weight", 1, "number of virtual nodes. the more virtual nodes, the more of the key space this process owns.")
+	command.Flags().BoolVar(&faultInjection, "fault-injection", false, "inject faults into RPCs following rules set via AdminService. for chaos testing only.")
+	command.Flags().StringVar(&identityFile, "identity-file", "", "file of an ed25519 key seed. the ring ID is derived from the key, and the file is created if it doesn't exist.")
+	command.Flags().BoolVar(&verifyPeers, "verify-peers", false, "accept only peers which prove their identities. every node needs --identity-file.")
+	command.Flags().Float64Var(&clientRateLimit, "client-rate-limit", 0, "requests per second each client may send to the external server. 0 disables the limit.")
+	command.Flags().IntVar(&clientBurst, "client-burst", 100, "requests each client may send at once over --client-rate-limit.")
+	command.Flags().IntVar(&maxConcurrent, "max-concurrent-requests", 0, "limit of external requests in flight. 0 disables the limit.")
+	command.Flags().IntVar(&internalLoadLimit, "internal-load-limit", 0, "shed external requests while more internal requests than this are in flight, giving priority to stabilizers. 0 disables it.")
+	command.Flags().DurationVar(&lookupCacheTTL, "lookup-cache-ttl", 0, "how long results of FindHostForKey are cached. 0 disables the cache.")
+	command.Flags().IntVar(&lookupCacheSize, "lookup-cache-size", 10000, "number of ID ranges the lookup cache keeps.")
+	command.Flags().StringVar(&storageEngine, "storage-engine", "memory", "engine to keep records on this host. memory loses records on exit, and disk keeps them in an append-only log under --data-dir.")
+	command.Flags().StringVar(&dataDir, "data-dir", "data", "directory of the disk storage engine.")
+	command.Flags().BoolVar(&syncWrites, "sync-writes", false, "flush every write of the disk storage engine to the disk before acknowledging it.")
+	command.Flags().IntVar(&replicationFactor, "replication-factor", 3, "number of hosts which hold a replica of each record, including its owner.")
+	command.Flags().DurationVar(&antiEntropyInterval, "anti-entropy-interval", 0, "interval of comparing records with replicas and repairing those which differ. 0 disables anti-entropy.")
+	command.Flags().StringVar(&conflictPolicy, "conflict-policy", "lww", "which of values written concurrently to a key replicas keep. lww keeps the latest, and siblings keeps all of them for clients to resolve. every node needs the same policy.")
+	command.Flags().DurationVar(&reapInterval, "reap-interval", time.Minute, "interval of deleting expired records from the storage. 0 disables it, and expired records are only hidden from reads.")
+	addClusterFlags(command)
+	command.AddCommand(newLookupCommand(), newStatusCommand(), newSuccessorsCommand(), newFingersCommand())
 	if err := command.Execute(); err != nil {
 		log.Fatalf("err(%#v)", err)
 	}
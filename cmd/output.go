@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/taisho6339/gord/pkg/model"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+func validateOutput(format string) error {
+	switch format {
+	case outputTable, outputJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q. use %q or %q", format, outputTable, outputJSON)
+	}
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printTable prints rows aligned in columns.
+func printTable(w io.Writer, header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func formatNode(ref *model.NodeRef) string {
+	if ref == nil {
+		return "-"
+	}
+	return ref.Key()
+}
+
+func formatZone(ref *model.NodeRef) string {
+	if ref == nil || ref.Zone == "" {
+		return "-"
+	}
+	return ref.Zone
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339Nano)
+}
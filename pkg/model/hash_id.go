@@ -3,6 +3,7 @@ package model
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/hex"
 	"math/big"
 )
 
@@ -27,11 +28,44 @@ func BytesToHashID(b []byte) HashID {
 	return append(buf[0:len(buf)-len(b)], b...)
 }
 
+// String returns a hex representation of an id.
+func (h HashID) String() string {
+	return hex.EncodeToString(h)
+}
+
+// MarshalText encodes an id in hex, so that it's readable in JSON.
+func (h HashID) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText decodes an id encoded in hex.
+func (h *HashID) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	*h = b
+	return nil
+}
+
 func (h HashID) Add(offset int64) HashID {
 	base := big.NewInt(0).SetBytes(h)
 	return BytesToHashID(base.Add(base, big.NewInt(offset)).Bytes())
 }
 
+// RingShare returns a fraction of the ring in (from, to].
+// If from equals to, the range covers the whole ring.
+func RingShare(from HashID, to HashID) float64 {
+	ring := big.NewInt(0).Lsh(big.NewInt(1), BitSize)
+	distance := big.NewInt(0).Sub(big.NewInt(0).SetBytes(to), big.NewInt(0).SetBytes(from))
+	distance.Mod(distance, ring)
+	if distance.Sign() == 0 {
+		return 1
+	}
+	share, _ := big.NewRat(0, 1).SetFrac(distance, ring).Float64()
+	return share
+}
+
 func (h HashID) Between(from HashID, to HashID) bool {
 	if from.GreaterThanEqual(to) {
 		return from.LessThan(h) || to.GreaterThan(h)
@@ -72,3 +72,26 @@ func TestGreaterThanEqual(t *testing.T) {
 		assert.Equal(t, tc.aIsBigger, tc.a.GreaterThanEqual(tc.b))
 	}
 }
+
+func TestRingShare(t *testing.T) {
+	ring := big.NewInt(0).Lsh(big.NewInt(1), BitSize)
+	quarter := BytesToHashID(big.NewInt(0).Rsh(ring, 2).Bytes())
+	half := BytesToHashID(big.NewInt(0).Rsh(ring, 1).Bytes())
+	zero := BytesToHashID([]byte{0})
+	assert.Equal(t, 0.25, RingShare(quarter, half))
+	assert.Equal(t, 0.75, RingShare(half, quarter))
+	assert.Equal(t, 0.5, RingShare(zero, half))
+	assert.Equal(t, 1.0, RingShare(half, half))
+}
+
+func TestHashID_MarshalText(t *testing.T) {
+	id := BytesToHashID([]byte{1, 255})
+	text, err := id.MarshalText()
+	assert.Nil(t, err)
+	assert.Equal(t, "00000000000000000000000000000000000000000000000000000000000001ff", string(text))
+
+	var decoded HashID
+	assert.Nil(t, decoded.UnmarshalText(text))
+	assert.Equal(t, id, decoded)
+	assert.NotNil(t, decoded.UnmarshalText([]byte("zz")))
+}
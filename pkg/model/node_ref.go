@@ -1,13 +1,73 @@
 package model
 
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
 type NodeRef struct {
-	ID   HashID
-	Host string
+	ID   HashID `json:"id"`
+	Host string `json:"host"`
+	// VNode is an index of virtual nodes which share a host.
+	// A host owns more of the key space by running more virtual nodes.
+	VNode int `json:"vnode,omitempty"`
+	// Zone is a failure domain label, such as an availability zone or a rack.
+	Zone string `json:"zone,omitempty"`
+	// Metadata is arbitrary labels of a node, such as an application port or a build version.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// PublicKey is an ed25519 public key of a node with a signed identity. Its ID is derived from the key.
+	PublicKey []byte `json:"public_key,omitempty"`
+	// Signature proves that the owner of PublicKey runs the node on Host.
+	Signature []byte `json:"signature,omitempty"`
 }
 
 func NewNodeRef(host string) *NodeRef {
-	return &NodeRef{
-		ID:   NewHashID(host),
-		Host: host,
+	return NewVirtualNodeRef(host, 0)
+}
+
+// NewVirtualNodeRef creates a reference to a virtual node of a host.
+// The first virtual node has the same ID as a node created by NewNodeRef.
+func NewVirtualNodeRef(host string, vnode int) *NodeRef {
+	ref := &NodeRef{
+		Host:  host,
+		VNode: vnode,
 	}
+	ref.ID = NewHashID(ref.Key())
+	return ref
+}
+
+// Key returns an identity of a node in a ring.
+func (n *NodeRef) Key() string {
+	if n.VNode == 0 {
+		return n.Host
+	}
+	return fmt.Sprintf("%s#%d", n.Host, n.VNode)
+}
+
+// IdentityHashID returns the ID of a virtual node owned by a public key.
+func IdentityHashID(publicKey []byte, vnode int) HashID {
+	key := hex.EncodeToString(publicKey)
+	if vnode == 0 {
+		return NewHashID(key)
+	}
+	return NewHashID(fmt.Sprintf("%s#%d", key, vnode))
+}
+
+// SetIdentity attaches a signed identity to a node, and derives its ID from the public key.
+func (n *NodeRef) SetIdentity(publicKey []byte, signature []byte) {
+	n.PublicKey = publicKey
+	n.Signature = signature
+	n.ID = IdentityHashID(publicKey, n.VNode)
+}
+
+// IdentityMessage returns bytes which a signature of a node covers.
+// It binds the ID to the host and the virtual node, but not to labels such as zone and metadata.
+func (n *NodeRef) IdentityMessage() []byte {
+	msg := []byte("gord-node-identity\x00")
+	msg = append(msg, n.ID...)
+	vnode := make([]byte, 4)
+	binary.BigEndian.PutUint32(vnode, uint32(n.VNode))
+	msg = append(msg, vnode...)
+	return append(msg, n.Host...)
 }
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNodeRef_Key(t *testing.T) {
+	assert.Equal(t, "gord1", NewNodeRef("gord1").Key())
+	assert.Equal(t, NewHashID("gord1"), NewVirtualNodeRef("gord1", 0).ID)
+	assert.Equal(t, "gord1#2", NewVirtualNodeRef("gord1", 2).Key())
+	assert.Equal(t, NewHashID("gord1#2"), NewVirtualNodeRef("gord1", 2).ID)
+}
+
+func TestNodeRef_SetIdentity(t *testing.T) {
+	ref := NewVirtualNodeRef("gord1", 2)
+	ref.SetIdentity([]byte("key"), []byte("signature"))
+	assert.Equal(t, IdentityHashID([]byte("key"), 2), ref.ID)
+	assert.NotEqual(t, IdentityHashID([]byte("key"), 0), ref.ID)
+	assert.Equal(t, []byte("signature"), ref.Signature)
+}
@@ -0,0 +1,325 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: admin.proto
+
+package server
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	duration "github.com/golang/protobuf/ptypes/duration"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type FaultRule_Action int32
+
+const (
+	FaultRule_ERROR   FaultRule_Action = 0
+	FaultRule_DELAY   FaultRule_Action = 1
+	FaultRule_TIMEOUT FaultRule_Action = 2
+	FaultRule_DROP    FaultRule_Action = 3
+)
+
+var FaultRule_Action_name = map[int32]string{
+	0: "ERROR",
+	1: "DELAY",
+	2: "TIMEOUT",
+	3: "DROP",
+}
+
+var FaultRule_Action_value = map[string]int32{
+	"ERROR":   0,
+	"DELAY":   1,
+	"TIMEOUT": 2,
+	"DROP":    3,
+}
+
+func (x FaultRule_Action) String() string {
+	return proto.EnumName(FaultRule_Action_name, int32(x))
+}
+
+func (FaultRule_Action) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{0, 0}
+}
+
+type FaultRule struct {
+	// method is a name of InternalService methods the rule applies to. Empty matches every method.
+	Method string `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	// peer is a host, or host#vnode for a virtual node, the rule applies to. Empty matches every peer.
+	Peer   string             `protobuf:"bytes,2,opt,name=peer,proto3" json:"peer,omitempty"`
+	Action FaultRule_Action   `protobuf:"varint,3,opt,name=action,proto3,enum=server.FaultRule_Action" json:"action,omitempty"`
+	Delay  *duration.Duration `protobuf:"bytes,4,opt,name=delay,proto3" json:"delay,omitempty"`
+	// probability is a chance that the rule applies to a matched RPC. Zero is treated as one.
+	Probability          float64  `protobuf:"fixed64,5,opt,name=probability,proto3" json:"probability,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FaultRule) Reset()         { *m = FaultRule{} }
+func (m *FaultRule) String() string { return proto.CompactTextString(m) }
+func (*FaultRule) ProtoMessage()    {}
+func (*FaultRule) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{0}
+}
+
+func (m *FaultRule) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FaultRule.Unmarshal(m, b)
+}
+func (m *FaultRule) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FaultRule.Marshal(b, m, deterministic)
+}
+func (m *FaultRule) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FaultRule.Merge(m, src)
+}
+func (m *FaultRule) XXX_Size() int {
+	return xxx_messageInfo_FaultRule.Size(m)
+}
+func (m *FaultRule) XXX_DiscardUnknown() {
+	xxx_messageInfo_FaultRule.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FaultRule proto.InternalMessageInfo
+
+func (m *FaultRule) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *FaultRule) GetPeer() string {
+	if m != nil {
+		return m.Peer
+	}
+	return ""
+}
+
+func (m *FaultRule) GetAction() FaultRule_Action {
+	if m != nil {
+		return m.Action
+	}
+	return FaultRule_ERROR
+}
+
+func (m *FaultRule) GetDelay() *duration.Duration {
+	if m != nil {
+		return m.Delay
+	}
+	return nil
+}
+
+func (m *FaultRule) GetProbability() float64 {
+	if m != nil {
+		return m.Probability
+	}
+	return 0
+}
+
+// FaultRules are applied in order. The first rule which applies to an RPC wins.
+type FaultRules struct {
+	Rules                []*FaultRule `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *FaultRules) Reset()         { *m = FaultRules{} }
+func (m *FaultRules) String() string { return proto.CompactTextString(m) }
+func (*FaultRules) ProtoMessage()    {}
+func (*FaultRules) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{1}
+}
+
+func (m *FaultRules) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FaultRules.Unmarshal(m, b)
+}
+func (m *FaultRules) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FaultRules.Marshal(b, m, deterministic)
+}
+func (m *FaultRules) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FaultRules.Merge(m, src)
+}
+func (m *FaultRules) XXX_Size() int {
+	return xxx_messageInfo_FaultRules.Size(m)
+}
+func (m *FaultRules) XXX_DiscardUnknown() {
+	xxx_messageInfo_FaultRules.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FaultRules proto.InternalMessageInfo
+
+func (m *FaultRules) GetRules() []*FaultRule {
+	if m != nil {
+		return m.Rules
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("server.FaultRule_Action", FaultRule_Action_name, FaultRule_Action_value)
+	proto.RegisterType((*FaultRule)(nil), "server.FaultRule")
+	proto.RegisterType((*FaultRules)(nil), "server.FaultRules")
+}
+
+func init() {
+	proto.RegisterFile("admin.proto", fileDescriptor_73a7fc70dcc2027c)
+}
+
+var fileDescriptor_73a7fc70dcc2027c = []byte{
+	// 359 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x91, 0xcf, 0x4e, 0xea, 0x40,
+	0x14, 0xc6, 0x19, 0xa0, 0xbd, 0x97, 0xd3, 0xcb, 0x4d, 0xef, 0x2c, 0x48, 0x2f, 0x37, 0xb9, 0xa9,
+	0x75, 0x61, 0x57, 0x53, 0x53, 0x82, 0x09, 0x0b, 0x17, 0x18, 0xaa, 0x31, 0xd1, 0x60, 0x06, 0x5c,
+	0xe8, 0xae, 0xa5, 0x63, 0x69, 0xd2, 0x3a, 0xcd, 0x74, 0x4a, 0xc2, 0x1b, 0xf8, 0xbe, 0xbe, 0x80,
+	0xe9, 0x1f, 0x09, 0x0a, 0xbb, 0xd3, 0xf3, 0x7d, 0xe7, 0x3b, 0xbf, 0xd3, 0x01, 0xcd, 0x0f, 0xd3,
+	0xf8, 0x95, 0x64, 0x82, 0x4b, 0x8e, 0xd5, 0x9c, 0x89, 0x0d, 0x13, 0xc3, 0xff, 0x11, 0xe7, 0x51,
+	0xc2, 0x9c, 0xaa, 0x1b, 0x14, 0x2f, 0x4e, 0x58, 0x08, 0x5f, 0xc6, 0xbc, 0xf1, 0x0d, 0xff, 0x7d,
+	0xd7, 0x59, 0x9a, 0xc9, 0x6d, 0x2d, 0x5a, 0xef, 0x08, 0x7a, 0xd7, 0x7e, 0x91, 0x48, 0x5a, 0x24,
+	0x0c, 0x0f, 0x40, 0x4d, 0x99, 0x5c, 0xf3, 0xd0, 0x40, 0x26, 0xb2, 0x7b, 0xb4, 0xf9, 0xc2, 0x18,
+	0xba, 0x19, 0x63, 0xc2, 0x68, 0x57, 0xdd, 0xaa, 0xc6, 0xe7, 0xa0, 0xfa, 0xab, 0x72, 0x8d, 0xd1,
+	0x31, 0x91, 0xfd, 0xdb, 0x35, 0x48, 0xcd, 0x43, 0x76, 0x71, 0x64, 0x5a, 0xe9, 0xb4, 0xf1, 0x61,
+	0x07, 0x94, 0x90, 0x25, 0xfe, 0xd6, 0xe8, 0x9a, 0xc8, 0xd6, 0xdc, 0xbf, 0xa4, 0x06, 0x23, 0x9f,
+	0x60, 0x64, 0xd6, 0x80, 0xd3, 0xda, 0x87, 0x4d, 0xd0, 0x32, 0xc1, 0x03, 0x3f, 0x88, 0x93, 0x58,
+	0x6e, 0x0d, 0xc5, 0x44, 0x36, 0xa2, 0xfb, 0x2d, 0x6b, 0x0c, 0x6a, 0xbd, 0x04, 0xf7, 0x40, 0xf1,
+	0x28, 0x9d, 0x53, 0xbd, 0x55, 0x96, 0x33, 0xef, 0x6e, 0xfa, 0xa4, 0x23, 0xac, 0xc1, 0x8f, 0xe5,
+	0xed, 0xbd, 0x37, 0x7f, 0x5c, 0xea, 0x6d, 0xfc, 0x13, 0xba, 0x33, 0x3a, 0x7f, 0xd0, 0x3b, 0xd6,
+	0x18, 0x60, 0x47, 0x99, 0xe3, 0x33, 0x50, 0x44, 0x59, 0x18, 0xc8, 0xec, 0xd8, 0x9a, 0xfb, 0xe7,
+	0xe0, 0x10, 0x5a, 0xeb, 0xee, 0x1b, 0x82, 0x5f, 0xd3, 0xf2, 0x05, 0x16, 0x4c, 0x6c, 0xe2, 0x15,
+	0xc3, 0x97, 0xd0, 0xbf, 0x61, 0x72, 0x2f, 0x6a, 0x70, 0x70, 0x93, 0x57, 0xfe, 0xec, 0x21, 0x3e,
+	0xc8, 0xcc, 0xad, 0x16, 0x9e, 0x40, 0x7f, 0xf1, 0x65, 0xfc, 0x88, 0xed, 0xf8, 0xe8, 0xd5, 0xe9,
+	0xf3, 0x49, 0x14, 0xcb, 0x75, 0x11, 0x90, 0x15, 0x4f, 0x1d, 0xe9, 0xc7, 0xf9, 0x9a, 0x5f, 0x8c,
+	0x46, 0x13, 0x27, 0xe2, 0x22, 0x74, 0xea, 0x89, 0x40, 0xad, 0x28, 0x46, 0x1f, 0x03, 0x00, 0x48,
+	0x66, 0x0e, 0x6d, 0x37, 0x02, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AdminServiceClient interface {
+	GetFaultRules(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*FaultRules, error)
+	SetFaultRules(ctx context.Context, in *FaultRules, opts ...grpc.CallOption) (*FaultRules, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) GetFaultRules(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*FaultRules, error) {
+	out := new(FaultRules)
+	err := c.cc.Invoke(ctx, "/server.AdminService/GetFaultRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) SetFaultRules(ctx context.Context, in *FaultRules, opts ...grpc.CallOption) (*FaultRules, error) {
+	out := new(FaultRules)
+	err := c.cc.Invoke(ctx, "/server.AdminService/SetFaultRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+type AdminServiceServer interface {
+	GetFaultRules(context.Context, *empty.Empty) (*FaultRules, error)
+	SetFaultRules(context.Context, *FaultRules) (*FaultRules, error)
+}
+
+// UnimplementedAdminServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedAdminServiceServer struct {
+}
+
+func (*UnimplementedAdminServiceServer) GetFaultRules(ctx context.Context, req *empty.Empty) (*FaultRules, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFaultRules not implemented")
+}
+func (*UnimplementedAdminServiceServer) SetFaultRules(ctx context.Context, req *FaultRules) (*FaultRules, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFaultRules not implemented")
+}
+
+func RegisterAdminServiceServer(s *grpc.Server, srv AdminServiceServer) {
+	s.RegisterService(&_AdminService_serviceDesc, srv)
+}
+
+func _AdminService_GetFaultRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetFaultRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.AdminService/GetFaultRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetFaultRules(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_SetFaultRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FaultRules)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetFaultRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.AdminService/SetFaultRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetFaultRules(ctx, req.(*FaultRules))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _AdminService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "server.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetFaultRules",
+			Handler:    _AdminService_GetFaultRules_Handler,
+		},
+		{
+			MethodName: "SetFaultRules",
+			Handler:    _AdminService_SetFaultRules_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin.proto",
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
+	"github.com/taisho6339/gord/chord"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var faultMethods = map[string]struct{}{
+	"":                                {},
+	chord.RPCPing:                     {},
+	chord.RPCSuccessors:               {},
+	chord.RPCPredecessor:              {},
+	chord.RPCFindSuccessorByTable:     {},
+	chord.RPCFindSuccessorByList:      {},
+	chord.RPCFindClosestPrecedingNode: {},
+	chord.RPCNotify:                   {},
+	chord.RPCHandshake:                {},
+	chord.RPCMerkleHashes:             {},
+	chord.RPCDigests:                  {},
+	chord.RPCGetRecords:               {},
+	chord.RPCPutRecords:               {},
+}
+
+// AdminServer changes fault rules of a node at runtime.
+type AdminServer struct {
+	injector *chord.FaultInjector
+}
+
+// NewAdminServer creates an admin server for the fault rules of injector.
+func NewAdminServer(injector *chord.FaultInjector) *AdminServer {
+	return &AdminServer{
+		injector: injector,
+	}
+}
+
+// GetFaultRules returns the fault rules which are active.
+// It is implemented for AdminService.
+func (a *AdminServer) GetFaultRules(_ context.Context, _ *empty.Empty) (*FaultRules, error) {
+	return toFaultRulesProto(a.injector.Rules()), nil
+}
+
+// SetFaultRules replaces the active fault rules with the rules of the request, and returns them.
+// The rules are left as they are if any of them is invalid.
+// It is implemented for AdminService.
+func (a *AdminServer) SetFaultRules(_ context.Context, req *FaultRules) (*FaultRules, error) {
+	rules := make([]chord.FaultRule, 0, len(req.Rules))
+	for _, r := range req.Rules {
+		if _, ok := faultMethods[r.Method]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "server: unknown method %q", r.Method)
+		}
+		if _, ok := FaultRule_Action_name[int32(r.Action)]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "server: unknown action %d", r.Action)
+		}
+		if r.Probability < 0 || r.Probability > 1 {
+			return nil, status.Errorf(codes.InvalidArgument, "server: probability must be in [0, 1], but %v", r.Probability)
+		}
+		rule := chord.FaultRule{
+			Method:      r.Method,
+			Peer:        r.Peer,
+			Action:      chord.FaultAction(r.Action),
+			Probability: r.Probability,
+		}
+		if r.Delay != nil {
+			delay, err := ptypes.Duration(r.Delay)
+			if err != nil || delay < 0 {
+				return nil, status.Errorf(codes.InvalidArgument, "server: invalid delay %v", r.Delay)
+			}
+			rule.Delay = delay
+		}
+		rules = append(rules, rule)
+	}
+	a.injector.SetRules(rules)
+	log.Warnf("fault rules replaced. %d rules are active.", len(rules))
+	return toFaultRulesProto(a.injector.Rules()), nil
+}
+
+func toFaultRulesProto(rules []chord.FaultRule) *FaultRules {
+	faultRules := &FaultRules{}
+	for _, rule := range rules {
+		faultRules.Rules = append(faultRules.Rules, &FaultRule{
+			Method:      rule.Method,
+			Peer:        rule.Peer,
+			Action:      FaultRule_Action(rule.Action),
+			Delay:       ptypes.DurationProto(rule.Delay),
+			Probability: rule.Probability,
+		})
+	}
+	return faultRules
+}
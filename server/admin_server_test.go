@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/chord"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestAdminServer_SetFaultRules(t *testing.T) {
+	admin := NewAdminServer(chord.NewFaultInjector(1))
+	ctx := context.Background()
+	rules, err := admin.SetFaultRules(ctx, &FaultRules{Rules: []*FaultRule{
+		{Method: chord.RPCPing, Peer: "gord2", Action: FaultRule_DELAY, Delay: ptypes.DurationProto(time.Second)},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rules.Rules))
+
+	testcases := []struct {
+		name string
+		rule *FaultRule
+	}{
+		{
+			name: "unknown method",
+			rule: &FaultRule{Method: "Unknown"},
+		},
+		{
+			name: "unknown action",
+			rule: &FaultRule{Action: FaultRule_Action(len(FaultRule_Action_name))},
+		},
+		{
+			name: "probability out of range",
+			rule: &FaultRule{Probability: 1.5},
+		},
+		{
+			name: "negative delay",
+			rule: &FaultRule{Action: FaultRule_DELAY, Delay: ptypes.DurationProto(-time.Second)},
+		},
+	}
+	for _, tc := range testcases {
+		_, err := admin.SetFaultRules(ctx, &FaultRules{Rules: []*FaultRule{tc.rule}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), tc.name)
+	}
+
+	// Invalid rules don't replace the active ones.
+	rules, err = admin.GetFaultRules(ctx, &empty.Empty{})
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(rules.Rules)) {
+		assert.Equal(t, FaultRule_DELAY, rules.Rules[0].Action)
+		assert.Equal(t, "gord2", rules.Rules[0].Peer)
+	}
+}
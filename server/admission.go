@@ -0,0 +1,202 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxClientBuckets bounds token buckets of clients kept in memory.
+	maxClientBuckets = 10000
+	// overloadRetryDelay is a retry hint when the server is overloaded rather than a client is over its limit.
+	overloadRetryDelay = 100 * time.Millisecond
+)
+
+// tokenBucket allows rate requests per second on average, and bursts of up to burst requests.
+type tokenBucket struct {
+	client string
+	tokens float64
+	last   time.Time
+}
+
+// take consumes a token. If no token is left, it returns how long to wait for the next token.
+func (b *tokenBucket) take(now time.Time, rate float64, burst int) (bool, time.Duration) {
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// AdmissionController protects the external server from overload.
+// It limits requests of each client by a token bucket and requests in flight on the server,
+// and gives priority to internal stabilizer traffic by shedding external requests while the internal server is busy.
+type AdmissionController struct {
+	rate              float64
+	burst             int
+	maxConcurrent     int32
+	internalLoadLimit int32
+	inFlight          int32
+	internalInFlight  int32
+	// clients holds elements of buckets, which buckets keeps from the most recently used one.
+	clients    map[string]*list.Element
+	buckets    *list.List
+	maxClients int
+	lock       sync.Mutex
+	now        func() time.Time
+}
+
+// AdmissionOptionFunc represents options of an admission controller
+type AdmissionOptionFunc func(controller *AdmissionController)
+
+// WithClientRateLimit allows each client rate requests per second, with bursts of up to burst requests.
+func WithClientRateLimit(rate float64, burst int) AdmissionOptionFunc {
+	return func(controller *AdmissionController) {
+		controller.rate = rate
+		controller.burst = burst
+	}
+}
+
+// WithMaxConcurrentRequests limits external requests in flight on the server.
+func WithMaxConcurrentRequests(n int) AdmissionOptionFunc {
+	return func(controller *AdmissionController) {
+		controller.maxConcurrent = int32(n)
+	}
+}
+
+// WithInternalLoadLimit sheds external requests while more than n internal requests are in flight,
+// so that stabilizers keep the ring healthy under a flood of lookups.
+// The internal server counts its requests with WithStabilizerPriority.
+func WithInternalLoadLimit(n int) AdmissionOptionFunc {
+	return func(controller *AdmissionController) {
+		controller.internalLoadLimit = int32(n)
+	}
+}
+
+// NewAdmissionController creates an admission controller. Limits which aren't given are disabled.
+func NewAdmissionController(opts ...AdmissionOptionFunc) *AdmissionController {
+	controller := &AdmissionController{
+		clients:    map[string]*list.Element{},
+		buckets:    list.New(),
+		maxClients: maxClientBuckets,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(controller)
+	}
+	return controller
+}
+
+// clientID returns an identity of a client which sends a request, which is its address.
+// A client can't choose it, unlike a header, so it can't get a new bucket on every request.
+func clientID(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// allow consumes a token of a client. If the client is over its limit, it returns how long to wait.
+func (a *AdmissionController) allow(client string) (bool, time.Duration) {
+	if a.rate <= 0 {
+		return true, 0
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := a.now()
+	elem, ok := a.clients[client]
+	if ok {
+		a.buckets.MoveToFront(elem)
+	} else {
+		// The least recently used bucket is dropped, whether it has been refilled or not, so that memory stays bounded.
+		if a.buckets.Len() >= a.maxClients {
+			oldest := a.buckets.Back()
+			a.buckets.Remove(oldest)
+			delete(a.clients, oldest.Value.(*tokenBucket).client)
+		}
+		elem = a.buckets.PushFront(&tokenBucket{client: client, tokens: float64(a.burst), last: now})
+		a.clients[client] = elem
+	}
+	return elem.Value.(*tokenBucket).take(now, a.rate, a.burst)
+}
+
+// admit decides whether a request is accepted. The returned function releases the request.
+func (a *AdmissionController) admit(ctx context.Context, method string, concurrent bool) (func(), error) {
+	client := clientID(ctx)
+	if ok, wait := a.allow(client); !ok {
+		log.Warnf("rate limited %s from client %s.", method, client)
+		return nil, resourceExhausted(wait, "server: client %s is over its rate limit", client)
+	}
+	if a.internalLoadLimit > 0 && atomic.LoadInt32(&a.internalInFlight) > a.internalLoadLimit {
+		log.Warnf("shed %s from client %s, giving priority to stabilizers.", method, client)
+		return nil, resourceExhausted(overloadRetryDelay, "server: node is busy with internal traffic")
+	}
+	if !concurrent || a.maxConcurrent <= 0 {
+		return func() {}, nil
+	}
+	if atomic.AddInt32(&a.inFlight, 1) > a.maxConcurrent {
+		atomic.AddInt32(&a.inFlight, -1)
+		log.Warnf("shed %s from client %s, too many requests in flight.", method, client)
+		return nil, resourceExhausted(overloadRetryDelay, "server: too many requests in flight")
+	}
+	return func() { atomic.AddInt32(&a.inFlight, -1) }, nil
+}
+
+// resourceExhausted returns an error with a hint of how long a client should wait before retrying.
+func resourceExhausted(wait time.Duration, format string, args ...interface{}) error {
+	st := status.Newf(codes.ResourceExhausted, format, args...)
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(wait)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// UnaryServerInterceptor admits unary calls to the external server.
+func (a *AdmissionController) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		release, err := a.admit(ctx, info.FullMethod, true)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor admits streams to the external server.
+// A stream is rate limited when it starts, but it doesn't hold a slot of requests in flight, since it lives long.
+func (a *AdmissionController) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, err := a.admit(ss.Context(), info.FullMethod, false); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// InternalUnaryServerInterceptor counts requests in flight on the internal server.
+// Internal requests are never refused.
+func (a *AdmissionController) InternalUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		atomic.AddInt32(&a.internalInFlight, 1)
+		defer atomic.AddInt32(&a.internalInFlight, -1)
+		return handler(ctx, req)
+	}
+}
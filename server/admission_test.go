@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+// clientContext returns a context of a request from a client at an address.
+func clientContext(address string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(address), Port: 50000}})
+}
+
+// assertExhausted asserts that err is ResourceExhausted with a retry hint of wait.
+func assertExhausted(t *testing.T, err error, wait time.Duration) {
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	details := st.Details()
+	if assert.Equal(t, 1, len(details)) {
+		info, ok := details[0].(*errdetails.RetryInfo)
+		assert.True(t, ok)
+		delay, err := ptypes.Duration(info.RetryDelay)
+		assert.NoError(t, err)
+		assert.Equal(t, wait, delay)
+	}
+}
+
+func TestTokenBucket_Take(t *testing.T) {
+	now := time.Now()
+	bucket := &tokenBucket{tokens: 2, last: now}
+	for i := 0; i < 2; i++ {
+		ok, _ := bucket.take(now, 10, 2)
+		assert.True(t, ok)
+	}
+	ok, wait := bucket.take(now, 10, 2)
+	assert.False(t, ok)
+	assert.Equal(t, 100*time.Millisecond, wait)
+
+	ok, _ = bucket.take(now.Add(100*time.Millisecond), 10, 2)
+	assert.True(t, ok)
+
+	// Tokens don't pile up beyond the burst.
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		ok, _ := bucket.take(now, 10, 2)
+		assert.True(t, ok)
+	}
+	ok, _ = bucket.take(now, 10, 2)
+	assert.False(t, ok)
+}
+
+func TestAdmissionController_RateLimit(t *testing.T) {
+	clock := &fakeClock{current: time.Now()}
+	controller := NewAdmissionController(WithClientRateLimit(1, 2))
+	controller.now = clock.now
+	for i := 0; i < 2; i++ {
+		release, err := controller.admit(clientContext("10.0.0.1"), "method", true)
+		assert.NoError(t, err)
+		release()
+	}
+	_, err := controller.admit(clientContext("10.0.0.1"), "method", true)
+	assertExhausted(t, err, time.Second)
+
+	// Each client has its own limit.
+	_, err = controller.admit(clientContext("10.0.0.2"), "method", true)
+	assert.NoError(t, err)
+
+	clock.current = clock.current.Add(time.Second)
+	_, err = controller.admit(clientContext("10.0.0.1"), "method", true)
+	assert.NoError(t, err)
+}
+
+func TestAdmissionController_ClientID(t *testing.T) {
+	controller := NewAdmissionController(WithClientRateLimit(1, 1))
+	controller.now = (&fakeClock{current: time.Now()}).now
+	_, err := controller.admit(clientContext("10.0.0.1"), "method", false)
+	assert.NoError(t, err)
+
+	// A client is identified by its address, so a header chosen by the client doesn't get it a new bucket.
+	ctx := metadata.NewIncomingContext(clientContext("10.0.0.1"), metadata.Pairs("gord-client-id", "another"))
+	_, err = controller.admit(ctx, "method", false)
+	assertExhausted(t, err, time.Second)
+}
+
+func TestAdmissionController_MaxClients(t *testing.T) {
+	clock := &fakeClock{current: time.Now()}
+	controller := NewAdmissionController(WithClientRateLimit(1, 1))
+	controller.now = clock.now
+	controller.maxClients = 2
+	for _, address := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.1", "10.0.0.3"} {
+		controller.admit(clientContext(address), "method", false)
+	}
+	// The least recently used bucket is dropped, even though it hasn't been refilled.
+	assert.Equal(t, 2, len(controller.clients))
+	assert.Equal(t, 2, controller.buckets.Len())
+	_, ok := controller.clients["10.0.0.2"]
+	assert.False(t, ok)
+	_, err := controller.admit(clientContext("10.0.0.1"), "method", false)
+	assertExhausted(t, err, time.Second)
+}
+
+func TestAdmissionController_MaxConcurrentRequests(t *testing.T) {
+	controller := NewAdmissionController(WithMaxConcurrentRequests(2))
+	release1, err := controller.admit(clientContext("10.0.0.1"), "method", true)
+	assert.NoError(t, err)
+	release2, err := controller.admit(clientContext("10.0.0.2"), "method", true)
+	assert.NoError(t, err)
+	_, err = controller.admit(clientContext("10.0.0.3"), "method", true)
+	assertExhausted(t, err, overloadRetryDelay)
+
+	// Streams don't hold slots.
+	_, err = controller.admit(clientContext("10.0.0.3"), "method", false)
+	assert.NoError(t, err)
+
+	release1()
+	release3, err := controller.admit(clientContext("10.0.0.3"), "method", true)
+	assert.NoError(t, err)
+	release2()
+	release3()
+	assert.Equal(t, int32(0), controller.inFlight)
+}
+
+func TestAdmissionController_InternalLoadLimit(t *testing.T) {
+	controller := NewAdmissionController(WithInternalLoadLimit(1))
+	external := controller.UnaryServerInterceptor()
+	internal := controller.InternalUnaryServerInterceptor()
+	externalHandler := func(_ context.Context, _ interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	callExternal := func() error {
+		_, err := external(clientContext("10.0.0.4"), nil, &grpc.UnaryServerInfo{FullMethod: "external"}, externalHandler)
+		return err
+	}
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	internalHandler := func(_ context.Context, _ interface{}) (interface{}, error) {
+		started <- struct{}{}
+		<-finish
+		return "ok", nil
+	}
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			internal(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "internal"}, internalHandler)
+			done <- struct{}{}
+		}()
+		<-started
+		if i == 0 {
+			// Internal requests up to the limit don't shed external ones.
+			assert.NoError(t, callExternal())
+		}
+	}
+	assertExhausted(t, callExternal(), overloadRetryDelay)
+
+	close(finish)
+	<-done
+	<-done
+	assert.NoError(t, callExternal())
+}
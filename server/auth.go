@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	clusterHeader   = "gord-cluster"
+	timestampHeader = "gord-timestamp"
+	nonceHeader     = "gord-nonce"
+	signatureHeader = "gord-signature"
+	// maxClockSkew bounds how old a signed request may be, which bounds how long nonces are remembered.
+	maxClockSkew = 5 * time.Minute
+	nonceSize    = 16
+)
+
+// ClusterSecret authenticates nodes of a cluster by an HMAC over a shared secret and the cluster name.
+// The HMAC covers the method and a nonce too, and a server accepts each nonce only once,
+// so headers captured from a request can't be replayed, neither against the same method nor another one.
+// It works as per-RPC credentials of a client, and as interceptors of a server.
+type ClusterSecret struct {
+	cluster string
+	secret  []byte
+	now     func() time.Time
+	nonces  *nonceCache
+}
+
+// NewClusterSecret creates credentials of a cluster.
+func NewClusterSecret(cluster string, secret []byte) *ClusterSecret {
+	return &ClusterSecret{
+		cluster: cluster,
+		secret:  secret,
+		now:     time.Now,
+		nonces:  newNonceCache(),
+	}
+}
+
+func (c *ClusterSecret) sign(method string, timestamp string, nonce string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	for _, field := range []string{c.cluster, method, timestamp, nonce} {
+		mac.Write([]byte(field))
+		mac.Write([]byte{0})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GetRequestMetadata is implemented for credentials.PerRPCCredentials interface.
+func (c *ClusterSecret) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	info, ok := credentials.RequestInfoFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no method to sign")
+	}
+	return c.headers(info.Method)
+}
+
+// headers returns headers which authenticate a request to method.
+func (c *ClusterSecret) headers(method string) (map[string]string, error) {
+	b := make([]byte, nonceSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate a nonce. err = %w", err)
+	}
+	nonce := hex.EncodeToString(b)
+	timestamp := strconv.FormatInt(c.now().Unix(), 10)
+	return map[string]string{
+		clusterHeader:   c.cluster,
+		timestampHeader: timestamp,
+		nonceHeader:     nonce,
+		signatureHeader: c.sign(method, timestamp, nonce),
+	}, nil
+}
+
+// RequireTransportSecurity is implemented for credentials.PerRPCCredentials interface.
+// The secret itself never travels, so the HMAC works over insecure connections.
+func (c *ClusterSecret) RequireTransportSecurity() bool {
+	return false
+}
+
+// verify checks the HMAC of an incoming request to method, and that its nonce hasn't been used.
+func (c *ClusterSecret) verify(ctx context.Context, method string) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return fmt.Errorf("no credentials")
+	}
+	value := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	if cluster := value(clusterHeader); cluster != c.cluster {
+		return fmt.Errorf("cluster %q doesn't match", cluster)
+	}
+	timestamp := value(timestampHeader)
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed timestamp %q", timestamp)
+	}
+	if skew := c.now().Sub(time.Unix(sec, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return fmt.Errorf("timestamp is skewed by %s", skew)
+	}
+	nonce := value(nonceHeader)
+	if len(nonce) != hex.EncodedLen(nonceSize) {
+		return fmt.Errorf("malformed nonce %q", nonce)
+	}
+	if !hmac.Equal([]byte(value(signatureHeader)), []byte(c.sign(method, timestamp, nonce))) {
+		return fmt.Errorf("invalid signature")
+	}
+	if !c.nonces.add(nonce, c.now()) {
+		return fmt.Errorf("nonce %s is replayed", nonce)
+	}
+	return nil
+}
+
+func (c *ClusterSecret) authenticate(ctx context.Context, method string) error {
+	err := c.verify(ctx, method)
+	if err == nil {
+		return nil
+	}
+	addr := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	log.Warnf("refused %s from %s. err = %v", method, addr, err)
+	return status.Errorf(codes.Unauthenticated, "server: %v", err)
+}
+
+// UnaryServerInterceptor rejects unary calls from peers out of the cluster.
+func (c *ClusterSecret) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := c.authenticate(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects streams from peers out of the cluster.
+func (c *ClusterSecret) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := c.authenticate(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// nonceCache remembers nonces of accepted requests.
+// A request signed at t is accepted until t+maxClockSkew, and t is at most maxClockSkew later than when it's accepted,
+// so a nonce is forgotten 2*maxClockSkew after it's accepted, when the timestamp check rejects its replays anyway.
+type nonceCache struct {
+	seen map[string]struct{}
+	// queue holds nonces in the order they are accepted, which is the order they expire in.
+	queue []acceptedNonce
+	lock  sync.Mutex
+}
+
+type acceptedNonce struct {
+	nonce string
+	at    time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{
+		seen: map[string]struct{}{},
+	}
+}
+
+// add remembers a nonce accepted at now. It returns false if the nonce has been accepted before.
+func (n *nonceCache) add(nonce string, now time.Time) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	expired := 0
+	for _, accepted := range n.queue {
+		if now.Sub(accepted.at) <= 2*maxClockSkew {
+			break
+		}
+		delete(n.seen, accepted.nonce)
+		expired++
+	}
+	n.queue = n.queue[expired:]
+	if _, ok := n.seen[nonce]; ok {
+		return false
+	}
+	n.seen[nonce] = struct{}{}
+	n.queue = append(n.queue, acceptedNonce{nonce: nonce, at: now})
+	return true
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+const (
+	pingMethod   = "/server.InternalService/Ping"
+	notifyMethod = "/server.InternalService/Notify"
+)
+
+func signedContext(t *testing.T, secret *ClusterSecret, method string) context.Context {
+	headers, err := secret.headers(method)
+	assert.NoError(t, err)
+	return metadata.NewIncomingContext(context.Background(), metadata.New(headers))
+}
+
+func TestClusterSecret_Verify(t *testing.T) {
+	server := NewClusterSecret("prod", []byte("secret"))
+	skewed := NewClusterSecret("prod", []byte("secret"))
+	skewed.now = func() time.Time {
+		return time.Now().Add(-maxClockSkew - time.Minute)
+	}
+	testcases := []struct {
+		name     string
+		ctx      context.Context
+		accepted bool
+	}{
+		{
+			name:     "valid",
+			ctx:      signedContext(t, NewClusterSecret("prod", []byte("secret")), pingMethod),
+			accepted: true,
+		},
+		{
+			name: "bad MAC",
+			ctx:  signedContext(t, NewClusterSecret("prod", []byte("another secret")), pingMethod),
+		},
+		{
+			name: "wrong cluster",
+			ctx:  signedContext(t, NewClusterSecret("staging", []byte("secret")), pingMethod),
+		},
+		{
+			name: "timestamp outside the skew",
+			ctx:  signedContext(t, skewed, pingMethod),
+		},
+		{
+			name: "replay on another method",
+			ctx:  signedContext(t, NewClusterSecret("prod", []byte("secret")), notifyMethod),
+		},
+		{
+			name: "no credentials",
+			ctx:  context.Background(),
+		},
+	}
+	for _, tc := range testcases {
+		err := server.verify(tc.ctx, pingMethod)
+		assert.Equal(t, tc.accepted, err == nil, "%s: err = %v", tc.name, err)
+	}
+}
+
+func TestClusterSecret_Verify_Replay(t *testing.T) {
+	server := NewClusterSecret("prod", []byte("secret"))
+	ctx := signedContext(t, NewClusterSecret("prod", []byte("secret")), pingMethod)
+	assert.NoError(t, server.verify(ctx, pingMethod))
+	assert.Error(t, server.verify(ctx, pingMethod))
+}
+
+func TestClusterSecret_UnaryServerInterceptor(t *testing.T) {
+	server := NewClusterSecret("prod", []byte("secret"))
+	interceptor := server.UnaryServerInterceptor()
+	handler := func(_ context.Context, _ interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	ctx := signedContext(t, NewClusterSecret("prod", []byte("secret")), pingMethod)
+	res, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: pingMethod}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", res)
+
+	ctx = signedContext(t, NewClusterSecret("prod", []byte("secret")), pingMethod)
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: notifyMethod}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestClusterSecret_GRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	secret := NewClusterSecret("prod", []byte("secret"))
+	s := grpc.NewServer(grpc.UnaryInterceptor(secret.UnaryServerInterceptor()))
+	RegisterExternalServiceServer(s, &UnimplementedExternalServiceServer{})
+	go s.Serve(lis)
+	defer s.Stop()
+
+	call := func(opts ...grpc.DialOption) error {
+		conn, err := grpc.Dial(lis.Addr().String(), append(opts, grpc.WithInsecure())...)
+		assert.NoError(t, err)
+		defer conn.Close()
+		_, err = NewExternalServiceClient(conn).FindHostForKey(context.Background(), &FindHostRequest{Key: "key"})
+		return err
+	}
+	// Authenticated requests reach the service, which implements nothing.
+	assert.Equal(t, codes.Unimplemented, status.Code(call(grpc.WithPerRPCCredentials(NewClusterSecret("prod", []byte("secret"))))))
+	assert.Equal(t, codes.Unauthenticated, status.Code(call()))
+}
+
+func TestNonceCache(t *testing.T) {
+	cache := newNonceCache()
+	now := time.Now()
+	assert.True(t, cache.add("nonce1", now))
+	assert.False(t, cache.add("nonce1", now.Add(maxClockSkew)))
+	assert.True(t, cache.add("nonce2", now.Add(maxClockSkew)))
+
+	// Nonces are forgotten once their replays fail the timestamp check anyway.
+	assert.True(t, cache.add("nonce3", now.Add(2*maxClockSkew+time.Second)))
+	assert.Equal(t, 2, len(cache.seen))
+	assert.True(t, cache.add("nonce1", now.Add(2*maxClockSkew+time.Second)))
+}
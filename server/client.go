@@ -6,27 +6,45 @@ import (
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/taisho6339/gord/chord"
 	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"sync"
 	"time"
 )
 
 type ApiClient struct {
-	hostNode   *chord.LocalNode
-	serverPort string
-	timeout    time.Duration
-	connPool   map[string]*grpc.ClientConn
-	poolLock   sync.Mutex
-	opts       grpc.CallOption
+	hostNode    *chord.LocalNode
+	serverPort  string
+	timeout     time.Duration
+	connPool    map[string]*grpc.ClientConn
+	poolLock    sync.Mutex
+	opts        grpc.CallOption
+	dialOptions []grpc.DialOption
 }
 
-func NewChordApiClient(hostNode *chord.LocalNode, port string, timeout time.Duration) chord.Transport {
-	return &ApiClient{
+// ApiClientOptionFunc represents options of a client for internal servers
+type ApiClientOptionFunc func(client *ApiClient)
+
+// WithClientClusterSecret makes the client sign every request with the cluster secret.
+func WithClientClusterSecret(secret *ClusterSecret) ApiClientOptionFunc {
+	return func(client *ApiClient) {
+		client.dialOptions = append(client.dialOptions, grpc.WithPerRPCCredentials(secret))
+	}
+}
+
+func NewChordApiClient(hostNode *chord.LocalNode, port string, timeout time.Duration, opts ...ApiClientOptionFunc) chord.Transport {
+	client := &ApiClient{
 		hostNode:   hostNode,
 		serverPort: port,
 		timeout:    timeout,
 		connPool:   map[string]*grpc.ClientConn{},
 	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
 }
 
 // TODO: Enable mTLS
@@ -39,7 +57,8 @@ func (c *ApiClient) getGrpcConn(address string) (InternalServiceClient, error) {
 		return NewInternalServiceClient(conn), nil
 	}
 
-	conn, err := grpc.Dial(fmt.Sprintf("%s:%s", address, c.serverPort), grpc.WithInsecure(), grpc.WithBlock())
+	dialOptions := append([]grpc.DialOption{grpc.WithInsecure(), grpc.WithBlock()}, c.dialOptions...)
+	conn, err := grpc.Dial(fmt.Sprintf("%s:%s", address, c.serverPort), dialOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -48,10 +67,11 @@ func (c *ApiClient) getGrpcConn(address string) (InternalServiceClient, error) {
 }
 
 func (c *ApiClient) createRingNodeFrom(node *Node) chord.RingNode {
-	if c.hostNode.Host == node.Host {
+	ref := toNodeRef(node)
+	if c.hostNode.Key() == ref.Key() {
 		return c.hostNode
 	}
-	return chord.NewRemoteNode(node.Host, c)
+	return chord.NewRemoteNodeFromRef(ref, c)
 }
 
 func (c *ApiClient) PingRPC(ctx context.Context, to *model.NodeRef) error {
@@ -59,7 +79,7 @@ func (c *ApiClient) PingRPC(ctx context.Context, to *model.NodeRef) error {
 	if err != nil {
 		return err
 	}
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
 	defer cancel()
 	_, err = client.Ping(ctx, &empty.Empty{})
 	if err != nil {
@@ -73,7 +93,7 @@ func (c *ApiClient) SuccessorsRPC(ctx context.Context, to *model.NodeRef) ([]cho
 	if err != nil {
 		return nil, err
 	}
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
 	defer cancel()
 	nodes, err := client.Successors(ctx, &empty.Empty{})
 	if err != nil {
@@ -91,7 +111,7 @@ func (c *ApiClient) PredecessorRPC(ctx context.Context, to *model.NodeRef) (chor
 	if err != nil {
 		return nil, err
 	}
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
 	defer cancel()
 	node, err := client.Predecessor(ctx, &empty.Empty{})
 	if err != nil {
@@ -105,7 +125,7 @@ func (c *ApiClient) FindSuccessorByTableRPC(ctx context.Context, to *model.NodeR
 	if err != nil {
 		return nil, err
 	}
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
 	defer cancel()
 	node, err := client.FindSuccessorByTable(ctx, &FindRequest{Id: id})
 	if err != nil {
@@ -119,7 +139,7 @@ func (c *ApiClient) FindSuccessorByListRPC(ctx context.Context, to *model.NodeRe
 	if err != nil {
 		return nil, err
 	}
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
 	defer cancel()
 	node, err := client.FindSuccessorByList(ctx, &FindRequest{Id: id})
 	if err != nil {
@@ -133,7 +153,7 @@ func (c *ApiClient) FindClosestPrecedingNodeRPC(ctx context.Context, to *model.N
 	if err != nil {
 		return nil, err
 	}
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
 	defer cancel()
 	node, err := client.FindClosestPrecedingNode(ctx, &FindRequest{Id: id})
 	if err != nil {
@@ -147,11 +167,96 @@ func (c *ApiClient) NotifyRPC(ctx context.Context, to *model.NodeRef, node *mode
 	if err != nil {
 		return err
 	}
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
+	defer cancel()
+	req := toNode(node)
+	req.Protocol = toProtocol(chord.LocalProtocol())
+	protocol, err := client.Notify(ctx, req)
+	if err != nil {
+		return handleError(err)
+	}
+	return c.hostNode.NegotiateProtocol(to, toChordProtocol(protocol))
+}
+
+func (c *ApiClient) HandshakeRPC(ctx context.Context, to *model.NodeRef, node *model.NodeRef) (chord.Protocol, error) {
+	client, err := c.getGrpcConn(to.Host)
+	if err != nil {
+		return chord.Protocol{}, err
+	}
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
 	defer cancel()
-	_, err = client.Notify(ctx, &Node{
-		Host: node.Host,
+	req := toNode(node)
+	req.Protocol = toProtocol(chord.LocalProtocol())
+	protocol, err := client.Handshake(ctx, req)
+	// Peers built before versioning have no Handshake RPC.
+	if status.Code(err) == codes.Unimplemented {
+		return chord.LegacyProtocol(), nil
+	}
+	if err != nil {
+		return chord.Protocol{}, handleError(err)
+	}
+	return toChordProtocol(protocol), nil
+}
+
+func (c *ApiClient) MerkleHashesRPC(ctx context.Context, to *model.NodeRef, rangeFrom model.HashID, rangeTo model.HashID, level int, indexes []int) ([][]byte, error) {
+	client, err := c.getGrpcConn(to.Host)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
+	defer cancel()
+	hashes, err := client.MerkleHashes(ctx, &MerkleRequest{
+		RangeFrom: rangeFrom,
+		RangeTo:   rangeTo,
+		Level:     int32(level),
+		Indexes:   toIndexes(indexes),
 	})
+	if err != nil {
+		return nil, handleError(err)
+	}
+	return hashes.Hashes, nil
+}
+
+func (c *ApiClient) DigestsRPC(ctx context.Context, to *model.NodeRef, rangeFrom model.HashID, rangeTo model.HashID) ([]storage.Digest, error) {
+	client, err := c.getGrpcConn(to.Host)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
+	defer cancel()
+	res, err := client.Digests(ctx, &RangeRequest{RangeFrom: rangeFrom, RangeTo: rangeTo})
+	if err != nil {
+		return nil, handleError(err)
+	}
+	digests := make([]storage.Digest, len(res.Digests))
+	for i, digest := range res.Digests {
+		digests[i] = storage.Digest{Key: digest.Key, Hash: digest.Hash}
+	}
+	return digests, nil
+}
+
+func (c *ApiClient) GetRecordsRPC(ctx context.Context, to *model.NodeRef, keys []string) ([]*storage.Record, error) {
+	client, err := c.getGrpcConn(to.Host)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
+	defer cancel()
+	records, err := client.GetRecords(ctx, &Keys{Keys: keys})
+	if err != nil {
+		return nil, handleError(err)
+	}
+	return toStorageRecords(records.Records), nil
+}
+
+func (c *ApiClient) PutRecordsRPC(ctx context.Context, to *model.NodeRef, records []*storage.Record) error {
+	client, err := c.getGrpcConn(to.Host)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(withVNode(ctx, to), c.timeout)
+	defer cancel()
+	_, err = client.PutRecords(ctx, &Records{Records: toRecords(records)})
 	if err != nil {
 		return handleError(err)
 	}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/chord"
+	"github.com/taisho6339/gord/pkg/model"
+	"google.golang.org/grpc"
+	"net"
+	"testing"
+	"time"
+)
+
+// legacyInternalServer is an internal server built before versioning, which has no Handshake RPC
+// and answers Notify with an empty message.
+type legacyInternalServer struct {
+	UnimplementedInternalServiceServer
+}
+
+func (s *legacyInternalServer) Notify(_ context.Context, _ *Node) (*Protocol, error) {
+	return &Protocol{}, nil
+}
+
+func TestApiClient_LegacyPeer(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := grpc.NewServer()
+	RegisterInternalServiceServer(s, &legacyInternalServer{})
+	go s.Serve(lis)
+	defer s.Stop()
+
+	host, port, err := net.SplitHostPort(lis.Addr().String())
+	assert.NoError(t, err)
+	node := chord.NewLocalNode("gord")
+	transport := NewChordApiClient(node, port, time.Second)
+	peer := model.NewNodeRef(host)
+
+	protocol, err := transport.HandshakeRPC(context.Background(), peer, node.NodeRef)
+	assert.NoError(t, err)
+	assert.Equal(t, chord.LegacyProtocol(), protocol)
+	assert.NoError(t, transport.NotifyRPC(context.Background(), peer, node.NodeRef))
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/taisho6339/gord/chord"
+	"time"
+)
+
+func toTimestamp(t time.Time) *timestamp.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		return nil
+	}
+	return ts
+}
+
+func fromTimestamp(ts *timestamp.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func toDebugStateProto(state chord.DebugState) *DebugState {
+	debugState := &DebugState{
+		Node:                toNode(state.Node),
+		LastStabilizedIndex: int32(state.LastStabilizedIndex),
+		IsShutdown:          state.IsShutdown,
+	}
+	if state.Predecessor != nil {
+		debugState.Predecessor = toNode(state.Predecessor)
+	}
+	for _, suc := range state.Successors {
+		debugState.Successors = append(debugState.Successors, toNode(suc))
+	}
+	for _, finger := range state.Fingers {
+		f := &DebugState_Finger{
+			Index: int32(finger.Index),
+			Id:    finger.ID,
+		}
+		if finger.Node != nil {
+			f.Node = toNode(finger.Node)
+		}
+		debugState.Fingers = append(debugState.Fingers, f)
+	}
+	for _, s := range state.Stabilizers {
+		debugState.Stabilizers = append(debugState.Stabilizers, &DebugState_Stabilizer{
+			Name:        s.Name,
+			LastRun:     toTimestamp(s.LastRun),
+			LastSuccess: toTimestamp(s.LastSuccess),
+			LastError:   s.LastError,
+			LastErrorAt: toTimestamp(s.LastErrorAt),
+		})
+	}
+	return debugState
+}
+
+func toDebugState(debugState *DebugState) chord.DebugState {
+	state := chord.DebugState{
+		LastStabilizedIndex: int(debugState.LastStabilizedIndex),
+		IsShutdown:          debugState.IsShutdown,
+	}
+	if debugState.Node != nil {
+		state.Node = toNodeRef(debugState.Node)
+	}
+	if debugState.Predecessor != nil {
+		state.Predecessor = toNodeRef(debugState.Predecessor)
+	}
+	for _, suc := range debugState.Successors {
+		state.Successors = append(state.Successors, toNodeRef(suc))
+	}
+	for _, f := range debugState.Fingers {
+		finger := chord.FingerState{
+			Index: int(f.Index),
+			ID:    f.Id,
+		}
+		if f.Node != nil {
+			finger.Node = toNodeRef(f.Node)
+		}
+		state.Fingers = append(state.Fingers, finger)
+	}
+	for _, s := range debugState.Stabilizers {
+		state.Stabilizers = append(state.Stabilizers, chord.StabilizerState{
+			Name:        s.Name,
+			LastRun:     fromTimestamp(s.LastRun),
+			LastSuccess: fromTimestamp(s.LastSuccess),
+			LastError:   s.LastError,
+			LastErrorAt: fromTimestamp(s.LastErrorAt),
+		})
+	}
+	return state
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"fmt"
 	"github.com/taisho6339/gord/chord"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -13,6 +14,10 @@ func handleError(err error) error {
 		return chord.ErrNodeUnavailable
 	case codes.NotFound:
 		return chord.ErrNotFound
+	case codes.FailedPrecondition:
+		return fmt.Errorf("%w: %s", chord.ErrIncompatibleProtocol, status.Convert(err).Message())
+	case codes.PermissionDenied:
+		return fmt.Errorf("%w: %s", chord.ErrUnverifiedIdentity, status.Convert(err).Message())
 	default:
 		return err
 	}
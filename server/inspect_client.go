@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/taisho6339/gord/chord"
+	"github.com/taisho6339/gord/pkg/model"
+	"google.golang.org/grpc"
+	"net"
+	"time"
+)
+
+// DefaultInternalPort is the port of gord's internal gRPC server.
+const DefaultInternalPort = "26040"
+
+// InspectClient is a client for operators to inspect a routing state of a node.
+type InspectClient struct {
+	conn    *grpc.ClientConn
+	client  InternalServiceClient
+	timeout time.Duration
+}
+
+// NewInspectClient creates a client connected to the internal server of a node.
+// An address without a port is connected to DefaultInternalPort.
+// If secret is not nil, requests are signed with the cluster secret.
+func NewInspectClient(address string, timeout time.Duration, secret *ClusterSecret) (*InspectClient, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, DefaultInternalPort)
+	}
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if secret != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(secret))
+	}
+	conn, err := grpc.Dial(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &InspectClient{
+		conn:    conn,
+		client:  NewInternalServiceClient(conn),
+		timeout: timeout,
+	}, nil
+}
+
+// Close closes the connection to the node.
+func (c *InspectClient) Close() error {
+	return c.conn.Close()
+}
+
+// DebugState returns a routing state of a virtual node of the node.
+func (c *InspectClient) DebugState(ctx context.Context, vnode int) (chord.DebugState, error) {
+	ctx, cancel := context.WithTimeout(withVNode(ctx, &model.NodeRef{VNode: vnode}), c.timeout)
+	defer cancel()
+	state, err := c.client.DebugState(ctx, &empty.Empty{})
+	if err != nil {
+		return chord.DebugState{}, err
+	}
+	return toDebugState(state), nil
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bytes"
+	"github.com/taisho6339/gord/pkg/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// cacheEntry represents that every ID in [from, to] belongs to node, whose ID is to.
+type cacheEntry struct {
+	from      model.HashID
+	to        model.HashID
+	node      *model.NodeRef
+	expiresAt time.Time
+}
+
+func (e *cacheEntry) contains(id model.HashID) bool {
+	return id.Equals(e.from) || id.Equals(e.to) || id.Between(e.from, e.to)
+}
+
+// lookupCacheStats represents counters of a lookup cache.
+type lookupCacheStats struct {
+	hits          uint64
+	misses        uint64
+	evictions     uint64
+	invalidations uint64
+	size          int
+}
+
+// lookupCache is a bounded cache of which node owns which range of IDs.
+// A lookup of an ID tells that every ID from it to the owner's ID belongs to the owner,
+// so entries are ranges rather than single IDs, and hot keys near each other share an entry.
+// Entries expire after ttl, and every entry is dropped when the routing version of the local node changes.
+type lookupCache struct {
+	ttl      time.Duration
+	capacity int
+	// entries are sorted by their owners' IDs. Ranges of them don't overlap.
+	entries []*cacheEntry
+	version uint64
+	stats   lookupCacheStats
+	lock    sync.Mutex
+	now     func() time.Time
+}
+
+func newLookupCache(ttl time.Duration, capacity int) *lookupCache {
+	return &lookupCache{
+		ttl:      ttl,
+		capacity: capacity,
+		now:      time.Now,
+	}
+}
+
+// sync drops every entry if the routing state has changed since the entries were stored.
+func (c *lookupCache) sync(version uint64) {
+	if c.version == version {
+		return
+	}
+	if len(c.entries) > 0 {
+		c.stats.invalidations++
+	}
+	c.entries = nil
+	c.version = version
+}
+
+// search returns an index of the first entry whose owner's ID is equal to or greater than id.
+// It wraps around to 0 past the largest ID.
+func (c *lookupCache) search(id model.HashID) int {
+	i := sort.Search(len(c.entries), func(i int) bool {
+		return bytes.Compare(c.entries[i].to, id) >= 0
+	})
+	if i == len(c.entries) {
+		return 0
+	}
+	return i
+}
+
+// get returns the owner of id, if the cache knows it.
+func (c *lookupCache) get(id model.HashID, version uint64) (*model.NodeRef, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sync(version)
+	if len(c.entries) == 0 {
+		c.stats.misses++
+		return nil, false
+	}
+	i := c.search(id)
+	entry := c.entries[i]
+	if !entry.contains(id) {
+		c.stats.misses++
+		return nil, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		c.entries = append(c.entries[:i], c.entries[i+1:]...)
+		c.stats.misses++
+		return nil, false
+	}
+	c.stats.hits++
+	return entry.node, true
+}
+
+// put stores that id belongs to node.
+func (c *lookupCache) put(id model.HashID, node *model.NodeRef, version uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// The routing state has changed during the lookup, so the result may be stale.
+	if version < c.version {
+		return
+	}
+	c.sync(version)
+	expiresAt := c.now().Add(c.ttl)
+	if len(c.entries) > 0 {
+		i := c.search(node.ID)
+		if entry := c.entries[i]; entry.to.Equals(node.ID) {
+			// Both ids belong to the node, so the farther one from the node starts the range.
+			if !entry.contains(id) {
+				entry.from = id
+			}
+			entry.node = node
+			entry.expiresAt = expiresAt
+			c.removeOverlaps(i)
+			return
+		}
+	}
+	if len(c.entries) >= c.capacity {
+		c.evict()
+	}
+	entry := &cacheEntry{from: id, to: node.ID, node: node, expiresAt: expiresAt}
+	i := sort.Search(len(c.entries), func(i int) bool {
+		return bytes.Compare(c.entries[i].to, node.ID) >= 0
+	})
+	c.entries = append(c.entries, nil)
+	copy(c.entries[i+1:], c.entries[i:])
+	c.entries[i] = entry
+	c.removeOverlaps(i)
+}
+
+// evict drops the entry which expires first.
+func (c *lookupCache) evict() {
+	oldest := 0
+	for i, entry := range c.entries {
+		if entry.expiresAt.Before(c.entries[oldest].expiresAt) {
+			oldest = i
+		}
+	}
+	c.entries = append(c.entries[:oldest], c.entries[oldest+1:]...)
+	c.stats.evictions++
+}
+
+// removeOverlaps drops entries which overlap the entry of index, since they are older than it.
+func (c *lookupCache) removeOverlaps(index int) {
+	entry := c.entries[index]
+	kept := c.entries[:0]
+	for i, e := range c.entries {
+		if i != index && (entry.contains(e.to) || e.contains(entry.to)) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	c.entries = kept
+}
+
+func (c *lookupCache) snapshot() lookupCacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	stats := c.stats
+	stats.size = len(c.entries)
+	return stats
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/pkg/model"
+	"testing"
+	"time"
+)
+
+// testID returns an id whose most significant byte is b.
+func testID(b byte) model.HashID {
+	id := make(model.HashID, model.BitSize/8)
+	id[0] = b
+	return id
+}
+
+func testNode(b byte) *model.NodeRef {
+	return &model.NodeRef{Host: testID(b).String(), ID: testID(b)}
+}
+
+// fakeClock is a clock of a cache which moves only when a test advances it.
+type fakeClock struct {
+	current time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.current
+}
+
+func newTestLookupCache(ttl time.Duration, capacity int) (*lookupCache, *fakeClock) {
+	clock := &fakeClock{current: time.Now()}
+	cache := newLookupCache(ttl, capacity)
+	cache.now = clock.now
+	return cache, clock
+}
+
+func assertOwner(t *testing.T, cache *lookupCache, id byte, owner byte) {
+	node, ok := cache.get(testID(id), 0)
+	if assert.True(t, ok, "id = %x", id) {
+		assert.Equal(t, testID(owner), node.ID, "id = %x", id)
+	}
+}
+
+func assertMiss(t *testing.T, cache *lookupCache, id byte) {
+	_, ok := cache.get(testID(id), 0)
+	assert.False(t, ok, "id = %x", id)
+}
+
+func TestLookupCache_GetPut(t *testing.T) {
+	cache, _ := newTestLookupCache(time.Minute, 10)
+	assertMiss(t, cache, 0x10)
+
+	cache.put(testID(0x10), testNode(0x20), 0)
+	assertOwner(t, cache, 0x10, 0x20)
+	assertOwner(t, cache, 0x15, 0x20)
+	assertOwner(t, cache, 0x20, 0x20)
+	assertMiss(t, cache, 0x05)
+	assertMiss(t, cache, 0x25)
+
+	// Another lookup of the same owner extends the range only when it starts farther from the owner.
+	cache.put(testID(0x18), testNode(0x20), 0)
+	assertOwner(t, cache, 0x12, 0x20)
+	cache.put(testID(0x08), testNode(0x20), 0)
+	assertOwner(t, cache, 0x08, 0x20)
+	assertMiss(t, cache, 0x05)
+
+	stats := cache.snapshot()
+	assert.Equal(t, 1, stats.size)
+	assert.Equal(t, uint64(5), stats.hits)
+	assert.Equal(t, uint64(4), stats.misses)
+}
+
+func TestLookupCache_WrappedRange(t *testing.T) {
+	cache, _ := newTestLookupCache(time.Minute, 10)
+	cache.put(testID(0x40), testNode(0x80), 0)
+	// The range wraps around past the largest ID.
+	cache.put(testID(0xf0), testNode(0x10), 0)
+
+	assertOwner(t, cache, 0xf0, 0x10)
+	assertOwner(t, cache, 0xff, 0x10)
+	assertOwner(t, cache, 0x00, 0x10)
+	assertOwner(t, cache, 0x10, 0x10)
+	assertOwner(t, cache, 0x50, 0x80)
+	assertMiss(t, cache, 0x11)
+	assertMiss(t, cache, 0xe0)
+
+	// An owner after the wrapped range extends it backward.
+	cache.put(testID(0xc0), testNode(0x10), 0)
+	assertOwner(t, cache, 0xd0, 0x10)
+	assertOwner(t, cache, 0x50, 0x80)
+	assert.Equal(t, 2, cache.snapshot().size)
+}
+
+func TestLookupCache_RemoveOverlaps(t *testing.T) {
+	cache, _ := newTestLookupCache(time.Minute, 10)
+	cache.put(testID(0x10), testNode(0x20), 0)
+	cache.put(testID(0x60), testNode(0x80), 0)
+
+	// A node joined between 0x20 and 0x40, and took over the range of 0x20.
+	cache.put(testID(0x05), testNode(0x30), 0)
+	assertOwner(t, cache, 0x15, 0x30)
+	assertOwner(t, cache, 0x70, 0x80)
+	assert.Equal(t, 2, cache.snapshot().size)
+
+	// The range of a new entry lies inside an older one, which is dropped.
+	cache.put(testID(0x70), testNode(0x75), 0)
+	assertOwner(t, cache, 0x72, 0x75)
+	assertMiss(t, cache, 0x65)
+	assert.Equal(t, 2, cache.snapshot().size)
+
+	// The range of a new entry wraps around and covers the older ones.
+	cache.put(testID(0x90), testNode(0x40), 0)
+	assertOwner(t, cache, 0x15, 0x40)
+	assertOwner(t, cache, 0x95, 0x40)
+	assert.Equal(t, 2, cache.snapshot().size)
+}
+
+func TestLookupCache_Invalidation(t *testing.T) {
+	cache, _ := newTestLookupCache(time.Minute, 10)
+	cache.put(testID(0x10), testNode(0x20), 1)
+	_, ok := cache.get(testID(0x15), 1)
+	assert.True(t, ok)
+
+	// The routing state has changed, so every entry is dropped.
+	_, ok = cache.get(testID(0x15), 2)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(1), cache.snapshot().invalidations)
+
+	// A lookup which started before the change is not stored.
+	cache.put(testID(0x10), testNode(0x20), 1)
+	_, ok = cache.get(testID(0x15), 2)
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.snapshot().size)
+
+	cache.put(testID(0x10), testNode(0x20), 2)
+	_, ok = cache.get(testID(0x15), 2)
+	assert.True(t, ok)
+}
+
+func TestLookupCache_TTL(t *testing.T) {
+	cache, clock := newTestLookupCache(time.Minute, 10)
+	cache.put(testID(0x10), testNode(0x20), 0)
+	clock.current = clock.current.Add(time.Minute - time.Second)
+	assertOwner(t, cache, 0x15, 0x20)
+
+	clock.current = clock.current.Add(time.Second)
+	assertMiss(t, cache, 0x15)
+	assert.Equal(t, 0, cache.snapshot().size)
+
+	// Storing the owner again renews the entry.
+	cache.put(testID(0x10), testNode(0x20), 0)
+	clock.current = clock.current.Add(time.Minute / 2)
+	cache.put(testID(0x10), testNode(0x20), 0)
+	clock.current = clock.current.Add(time.Minute / 2)
+	assertOwner(t, cache, 0x15, 0x20)
+}
+
+func TestLookupCache_Eviction(t *testing.T) {
+	cache, clock := newTestLookupCache(time.Minute, 2)
+	cache.put(testID(0x10), testNode(0x20), 0)
+	clock.current = clock.current.Add(time.Second)
+	cache.put(testID(0x30), testNode(0x40), 0)
+	clock.current = clock.current.Add(time.Second)
+	// The entry of 0x20 expires first, so it's evicted.
+	cache.put(testID(0x50), testNode(0x60), 0)
+
+	assertMiss(t, cache, 0x15)
+	assertOwner(t, cache, 0x35, 0x40)
+	assertOwner(t, cache, 0x55, 0x60)
+	stats := cache.snapshot()
+	assert.Equal(t, 2, stats.size)
+	assert.Equal(t, uint64(1), stats.evictions)
+
+	// Updating an entry of a cached owner doesn't evict anything.
+	cache.put(testID(0x32), testNode(0x40), 0)
+	assert.Equal(t, uint64(1), cache.snapshot().evictions)
+}
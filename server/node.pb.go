@@ -21,7 +21,15 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 type Node struct {
-	Host                 string   `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	Host     string            `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	Zone     string            `protobuf:"bytes,2,opt,name=zone,proto3" json:"zone,omitempty"`
+	Metadata map[string]string `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Vnode    int32             `protobuf:"varint,4,opt,name=vnode,proto3" json:"vnode,omitempty"`
+	// protocol is set only when a node introduces itself by Notify or Handshake.
+	Protocol *Protocol `protobuf:"bytes,5,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	// public_key and signature are set only for a node with a signed identity. Its ID is derived from public_key.
+	PublicKey            []byte   `protobuf:"bytes,6,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Signature            []byte   `protobuf:"bytes,7,opt,name=signature,proto3" json:"signature,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -59,8 +67,109 @@ func (m *Node) GetHost() string {
 	return ""
 }
 
+func (m *Node) GetZone() string {
+	if m != nil {
+		return m.Zone
+	}
+	return ""
+}
+
+func (m *Node) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *Node) GetVnode() int32 {
+	if m != nil {
+		return m.Vnode
+	}
+	return 0
+}
+
+func (m *Node) GetProtocol() *Protocol {
+	if m != nil {
+		return m.Protocol
+	}
+	return nil
+}
+
+func (m *Node) GetPublicKey() []byte {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+func (m *Node) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+// Protocol represents a protocol version and a feature set of a node.
+// version is the minor version, and nodes of different major versions refuse each other.
+type Protocol struct {
+	Version              int32    `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Features             []string `protobuf:"bytes,2,rep,name=features,proto3" json:"features,omitempty"`
+	Major                int32    `protobuf:"varint,3,opt,name=major,proto3" json:"major,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Protocol) Reset()         { *m = Protocol{} }
+func (m *Protocol) String() string { return proto.CompactTextString(m) }
+func (*Protocol) ProtoMessage()    {}
+func (*Protocol) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c843d59d2d938e7, []int{1}
+}
+
+func (m *Protocol) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Protocol.Unmarshal(m, b)
+}
+func (m *Protocol) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Protocol.Marshal(b, m, deterministic)
+}
+func (m *Protocol) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Protocol.Merge(m, src)
+}
+func (m *Protocol) XXX_Size() int {
+	return xxx_messageInfo_Protocol.Size(m)
+}
+func (m *Protocol) XXX_DiscardUnknown() {
+	xxx_messageInfo_Protocol.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Protocol proto.InternalMessageInfo
+
+func (m *Protocol) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Protocol) GetFeatures() []string {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+func (m *Protocol) GetMajor() int32 {
+	if m != nil {
+		return m.Major
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Node)(nil), "server.Node")
+	proto.RegisterMapType((map[string]string)(nil), "server.Node.MetadataEntry")
+	proto.RegisterType((*Protocol)(nil), "server.Protocol")
 }
 
 func init() {
@@ -68,12 +177,25 @@ func init() {
 }
 
 var fileDescriptor_0c843d59d2d938e7 = []byte{
-	// 107 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xca, 0xcb, 0x4f, 0x49,
-	0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2b, 0x4e, 0x2d, 0x2a, 0x4b, 0x2d, 0x52, 0x92,
-	0xe2, 0x62, 0xf1, 0xcb, 0x4f, 0x49, 0x15, 0x12, 0xe2, 0x62, 0xc9, 0xc8, 0x2f, 0x2e, 0x91, 0x60,
-	0x54, 0x60, 0xd4, 0xe0, 0x0c, 0x02, 0xb3, 0x9d, 0x94, 0xa3, 0x14, 0xd3, 0x33, 0x4b, 0x32, 0x4a,
-	0x93, 0xf4, 0x92, 0xf3, 0x73, 0xf5, 0x4b, 0x12, 0x33, 0x8b, 0x33, 0xf2, 0xcd, 0x8c, 0x8d, 0x2d,
-	0xf5, 0xd3, 0xf3, 0x8b, 0x52, 0xf4, 0x21, 0x06, 0x24, 0xb1, 0x81, 0xcd, 0x33, 0x06, 0x04, 0x00,
-	0x00, 0xff, 0xff, 0x2d, 0x4e, 0xd9, 0xe3, 0x5d, 0x00, 0x00, 0x00,
+	// 308 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x50, 0xcf, 0x4b, 0xc3, 0x30,
+	0x18, 0xa5, 0xed, 0xba, 0xb5, 0xdf, 0x14, 0x46, 0xf0, 0x10, 0x86, 0x42, 0x9d, 0x97, 0x1c, 0xa4,
+	0x83, 0x0d, 0x86, 0x3f, 0x6e, 0x82, 0x27, 0x51, 0x24, 0x07, 0x0f, 0x5e, 0x24, 0x5b, 0xe3, 0x56,
+	0xdd, 0xfa, 0x8d, 0x24, 0x1d, 0xd4, 0xbf, 0xc7, 0x3f, 0x54, 0x92, 0xac, 0x05, 0x6f, 0xef, 0xbd,
+	0x7c, 0x79, 0xdf, 0x7b, 0x1f, 0x40, 0x85, 0x85, 0xcc, 0xf7, 0x0a, 0x0d, 0x92, 0xbe, 0x96, 0xea,
+	0x20, 0xd5, 0xe4, 0x37, 0x84, 0xde, 0x0b, 0x16, 0x92, 0x10, 0xe8, 0x6d, 0x50, 0x1b, 0x1a, 0x64,
+	0x01, 0x4b, 0xb9, 0xc3, 0x56, 0xfb, 0xc1, 0x4a, 0xd2, 0xd0, 0x6b, 0x16, 0x93, 0x05, 0x24, 0x3b,
+	0x69, 0x44, 0x21, 0x8c, 0xa0, 0x51, 0x16, 0xb1, 0xe1, 0x6c, 0x9c, 0x7b, 0xaf, 0xdc, 0xfa, 0xe4,
+	0xcf, 0xc7, 0xc7, 0xc7, 0xca, 0xa8, 0x86, 0x77, 0xb3, 0xe4, 0x0c, 0xe2, 0x83, 0xdd, 0x4f, 0x7b,
+	0x59, 0xc0, 0x62, 0xee, 0x09, 0xb9, 0x86, 0xc4, 0xe5, 0x59, 0xe1, 0x96, 0xc6, 0x59, 0xc0, 0x86,
+	0xb3, 0x51, 0xeb, 0xf6, 0x7a, 0xd4, 0x79, 0x37, 0x41, 0x2e, 0x00, 0xf6, 0xf5, 0x72, 0x5b, 0xae,
+	0x3e, 0xbe, 0x65, 0x43, 0xfb, 0x59, 0xc0, 0x4e, 0x78, 0xea, 0x95, 0x27, 0xd9, 0x90, 0x73, 0x48,
+	0x75, 0xb9, 0xae, 0x84, 0xa9, 0x95, 0xa4, 0x03, 0xff, 0xda, 0x09, 0xe3, 0x7b, 0x38, 0xfd, 0x97,
+	0x8d, 0x8c, 0x20, 0xb2, 0x36, 0xbe, 0xb0, 0x85, 0x2e, 0xa3, 0xd8, 0xd6, 0x6d, 0x61, 0x4f, 0xee,
+	0xc2, 0x9b, 0x60, 0xf2, 0x06, 0x49, 0x9b, 0x87, 0x50, 0x18, 0x1c, 0xa4, 0xd2, 0x25, 0x56, 0xee,
+	0x6f, 0xcc, 0x5b, 0x4a, 0xc6, 0x90, 0x7c, 0x4a, 0xb7, 0x4d, 0xd3, 0x30, 0x8b, 0x58, 0xca, 0x3b,
+	0x6e, 0xbd, 0x77, 0xe2, 0x0b, 0x15, 0x8d, 0x7c, 0x7f, 0x47, 0x1e, 0xae, 0xde, 0x2f, 0xd7, 0xa5,
+	0xd9, 0xd4, 0xcb, 0x7c, 0x85, 0xbb, 0xa9, 0x11, 0xa5, 0xde, 0xe0, 0x62, 0x3e, 0xbf, 0x9d, 0xae,
+	0x51, 0x15, 0x53, 0x7f, 0x89, 0x65, 0xdf, 0x1d, 0x60, 0xfe, 0x37, 0x00, 0xb7, 0x48, 0x3b, 0x58,
+	0xc0, 0x01, 0x00, 0x00,
 }
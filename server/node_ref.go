@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"github.com/taisho6339/gord/chord"
+	"github.com/taisho6339/gord/pkg/model"
+	"google.golang.org/grpc/metadata"
+	"strconv"
+)
+
+// vnodeHeader is a gRPC metadata key to specify a virtual node which handles a request.
+const vnodeHeader = "gord-vnode"
+
+func toNode(ref *model.NodeRef) *Node {
+	return &Node{
+		Host:      ref.Host,
+		Zone:      ref.Zone,
+		Metadata:  ref.Metadata,
+		Vnode:     int32(ref.VNode),
+		PublicKey: ref.PublicKey,
+		Signature: ref.Signature,
+	}
+}
+
+func toNodeRef(node *Node) *model.NodeRef {
+	ref := model.NewVirtualNodeRef(node.Host, int(node.Vnode))
+	ref.Zone = node.Zone
+	ref.Metadata = node.Metadata
+	// The ID is derived from the key, so that a peer can't claim any ID it likes.
+	if len(node.PublicKey) > 0 {
+		ref.SetIdentity(node.PublicKey, node.Signature)
+	}
+	return ref
+}
+
+func toProtocol(protocol chord.Protocol) *Protocol {
+	features := make([]string, len(protocol.Features))
+	for i, feature := range protocol.Features {
+		features[i] = string(feature)
+	}
+	return &Protocol{
+		Major:    int32(protocol.Major),
+		Version:  int32(protocol.Version),
+		Features: features,
+	}
+}
+
+// toChordProtocol converts a protocol received from a peer.
+// Peers built before versioning send no protocol, which is version 0.0 without features.
+// toChordProtocol converts a protocol a peer has sent.
+// Peers built before versioning send none, or an empty message in place of a Notify response, which have no major version.
+func toChordProtocol(protocol *Protocol) chord.Protocol {
+	if protocol.GetMajor() == 0 {
+		return chord.LegacyProtocol()
+	}
+	features := make([]chord.Feature, len(protocol.GetFeatures()))
+	for i, feature := range protocol.GetFeatures() {
+		features[i] = chord.Feature(feature)
+	}
+	return chord.Protocol{
+		Major:    int(protocol.GetMajor()),
+		Version:  int(protocol.GetVersion()),
+		Features: features,
+	}
+}
+
+// withVNode attaches a virtual node of a destination to an outgoing context.
+func withVNode(ctx context.Context, to *model.NodeRef) context.Context {
+	if to.VNode == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, vnodeHeader, strconv.Itoa(to.VNode))
+}
+
+// vnodeFrom returns a virtual node which an incoming request is sent to.
+func vnodeFrom(ctx context.Context) int {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0
+	}
+	values := md.Get(vnodeHeader)
+	if len(values) == 0 {
+		return 0
+	}
+	vnode, err := strconv.Atoi(values[0])
+	if err != nil {
+		return -1
+	}
+	return vnode
+}
@@ -8,6 +8,7 @@ import (
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
 	empty "github.com/golang/protobuf/ptypes/empty"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -103,9 +104,509 @@ func (m *FindRequest) GetId() []byte {
 	return nil
 }
 
+// DebugState represents a routing state of a node, for operators to inspect.
+type DebugState struct {
+	Node        *Node                `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Predecessor *Node                `protobuf:"bytes,2,opt,name=predecessor,proto3" json:"predecessor,omitempty"`
+	Successors  []*Node              `protobuf:"bytes,3,rep,name=successors,proto3" json:"successors,omitempty"`
+	Fingers     []*DebugState_Finger `protobuf:"bytes,4,rep,name=fingers,proto3" json:"fingers,omitempty"`
+	// last_stabilized_index is the last finger the finger table stabilizer updated, or -1 before the first update.
+	LastStabilizedIndex  int32                    `protobuf:"varint,5,opt,name=last_stabilized_index,json=lastStabilizedIndex,proto3" json:"last_stabilized_index,omitempty"`
+	IsShutdown           bool                     `protobuf:"varint,6,opt,name=is_shutdown,json=isShutdown,proto3" json:"is_shutdown,omitempty"`
+	Stabilizers          []*DebugState_Stabilizer `protobuf:"bytes,7,rep,name=stabilizers,proto3" json:"stabilizers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *DebugState) Reset()         { *m = DebugState{} }
+func (m *DebugState) String() string { return proto.CompactTextString(m) }
+func (*DebugState) ProtoMessage()    {}
+func (*DebugState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d2a91b51c7bdc125, []int{2}
+}
+
+func (m *DebugState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DebugState.Unmarshal(m, b)
+}
+func (m *DebugState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DebugState.Marshal(b, m, deterministic)
+}
+func (m *DebugState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DebugState.Merge(m, src)
+}
+func (m *DebugState) XXX_Size() int {
+	return xxx_messageInfo_DebugState.Size(m)
+}
+func (m *DebugState) XXX_DiscardUnknown() {
+	xxx_messageInfo_DebugState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DebugState proto.InternalMessageInfo
+
+func (m *DebugState) GetNode() *Node {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+func (m *DebugState) GetPredecessor() *Node {
+	if m != nil {
+		return m.Predecessor
+	}
+	return nil
+}
+
+func (m *DebugState) GetSuccessors() []*Node {
+	if m != nil {
+		return m.Successors
+	}
+	return nil
+}
+
+func (m *DebugState) GetFingers() []*DebugState_Finger {
+	if m != nil {
+		return m.Fingers
+	}
+	return nil
+}
+
+func (m *DebugState) GetLastStabilizedIndex() int32 {
+	if m != nil {
+		return m.LastStabilizedIndex
+	}
+	return 0
+}
+
+func (m *DebugState) GetIsShutdown() bool {
+	if m != nil {
+		return m.IsShutdown
+	}
+	return false
+}
+
+func (m *DebugState) GetStabilizers() []*DebugState_Stabilizer {
+	if m != nil {
+		return m.Stabilizers
+	}
+	return nil
+}
+
+type DebugState_Finger struct {
+	Index int32  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Id    []byte `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// node is unset until the finger is stabilized.
+	Node                 *Node    `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DebugState_Finger) Reset()         { *m = DebugState_Finger{} }
+func (m *DebugState_Finger) String() string { return proto.CompactTextString(m) }
+func (*DebugState_Finger) ProtoMessage()    {}
+func (*DebugState_Finger) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d2a91b51c7bdc125, []int{2, 0}
+}
+
+func (m *DebugState_Finger) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DebugState_Finger.Unmarshal(m, b)
+}
+func (m *DebugState_Finger) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DebugState_Finger.Marshal(b, m, deterministic)
+}
+func (m *DebugState_Finger) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DebugState_Finger.Merge(m, src)
+}
+func (m *DebugState_Finger) XXX_Size() int {
+	return xxx_messageInfo_DebugState_Finger.Size(m)
+}
+func (m *DebugState_Finger) XXX_DiscardUnknown() {
+	xxx_messageInfo_DebugState_Finger.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DebugState_Finger proto.InternalMessageInfo
+
+func (m *DebugState_Finger) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *DebugState_Finger) GetId() []byte {
+	if m != nil {
+		return m.Id
+	}
+	return nil
+}
+
+func (m *DebugState_Finger) GetNode() *Node {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+type DebugState_Stabilizer struct {
+	Name                 string               `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	LastRun              *timestamp.Timestamp `protobuf:"bytes,2,opt,name=last_run,json=lastRun,proto3" json:"last_run,omitempty"`
+	LastSuccess          *timestamp.Timestamp `protobuf:"bytes,3,opt,name=last_success,json=lastSuccess,proto3" json:"last_success,omitempty"`
+	LastError            string               `protobuf:"bytes,4,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	LastErrorAt          *timestamp.Timestamp `protobuf:"bytes,5,opt,name=last_error_at,json=lastErrorAt,proto3" json:"last_error_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *DebugState_Stabilizer) Reset()         { *m = DebugState_Stabilizer{} }
+func (m *DebugState_Stabilizer) String() string { return proto.CompactTextString(m) }
+func (*DebugState_Stabilizer) ProtoMessage()    {}
+func (*DebugState_Stabilizer) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d2a91b51c7bdc125, []int{2, 1}
+}
+
+func (m *DebugState_Stabilizer) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DebugState_Stabilizer.Unmarshal(m, b)
+}
+func (m *DebugState_Stabilizer) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DebugState_Stabilizer.Marshal(b, m, deterministic)
+}
+func (m *DebugState_Stabilizer) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DebugState_Stabilizer.Merge(m, src)
+}
+func (m *DebugState_Stabilizer) XXX_Size() int {
+	return xxx_messageInfo_DebugState_Stabilizer.Size(m)
+}
+func (m *DebugState_Stabilizer) XXX_DiscardUnknown() {
+	xxx_messageInfo_DebugState_Stabilizer.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DebugState_Stabilizer proto.InternalMessageInfo
+
+func (m *DebugState_Stabilizer) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DebugState_Stabilizer) GetLastRun() *timestamp.Timestamp {
+	if m != nil {
+		return m.LastRun
+	}
+	return nil
+}
+
+func (m *DebugState_Stabilizer) GetLastSuccess() *timestamp.Timestamp {
+	if m != nil {
+		return m.LastSuccess
+	}
+	return nil
+}
+
+func (m *DebugState_Stabilizer) GetLastError() string {
+	if m != nil {
+		return m.LastError
+	}
+	return ""
+}
+
+func (m *DebugState_Stabilizer) GetLastErrorAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.LastErrorAt
+	}
+	return nil
+}
+
+// MerkleRequest asks for hashes of nodes of indexes at a level of the Merkle tree of records in (range_from, range_to].
+type MerkleRequest struct {
+	RangeFrom            []byte   `protobuf:"bytes,1,opt,name=range_from,json=rangeFrom,proto3" json:"range_from,omitempty"`
+	RangeTo              []byte   `protobuf:"bytes,2,opt,name=range_to,json=rangeTo,proto3" json:"range_to,omitempty"`
+	Level                int32    `protobuf:"varint,3,opt,name=level,proto3" json:"level,omitempty"`
+	Indexes              []int32  `protobuf:"varint,4,rep,packed,name=indexes,proto3" json:"indexes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MerkleRequest) Reset()         { *m = MerkleRequest{} }
+func (m *MerkleRequest) String() string { return proto.CompactTextString(m) }
+func (*MerkleRequest) ProtoMessage()    {}
+func (*MerkleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d2a91b51c7bdc125, []int{3}
+}
+
+func (m *MerkleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MerkleRequest.Unmarshal(m, b)
+}
+func (m *MerkleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MerkleRequest.Marshal(b, m, deterministic)
+}
+func (m *MerkleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MerkleRequest.Merge(m, src)
+}
+func (m *MerkleRequest) XXX_Size() int {
+	return xxx_messageInfo_MerkleRequest.Size(m)
+}
+func (m *MerkleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MerkleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MerkleRequest proto.InternalMessageInfo
+
+func (m *MerkleRequest) GetRangeFrom() []byte {
+	if m != nil {
+		return m.RangeFrom
+	}
+	return nil
+}
+
+func (m *MerkleRequest) GetRangeTo() []byte {
+	if m != nil {
+		return m.RangeTo
+	}
+	return nil
+}
+
+func (m *MerkleRequest) GetLevel() int32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *MerkleRequest) GetIndexes() []int32 {
+	if m != nil {
+		return m.Indexes
+	}
+	return nil
+}
+
+type Hashes struct {
+	Hashes               [][]byte `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Hashes) Reset()         { *m = Hashes{} }
+func (m *Hashes) String() string { return proto.CompactTextString(m) }
+func (*Hashes) ProtoMessage()    {}
+func (*Hashes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d2a91b51c7bdc125, []int{4}
+}
+
+func (m *Hashes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Hashes.Unmarshal(m, b)
+}
+func (m *Hashes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Hashes.Marshal(b, m, deterministic)
+}
+func (m *Hashes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Hashes.Merge(m, src)
+}
+func (m *Hashes) XXX_Size() int {
+	return xxx_messageInfo_Hashes.Size(m)
+}
+func (m *Hashes) XXX_DiscardUnknown() {
+	xxx_messageInfo_Hashes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Hashes proto.InternalMessageInfo
+
+func (m *Hashes) GetHashes() [][]byte {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+type RangeRequest struct {
+	RangeFrom            []byte   `protobuf:"bytes,1,opt,name=range_from,json=rangeFrom,proto3" json:"range_from,omitempty"`
+	RangeTo              []byte   `protobuf:"bytes,2,opt,name=range_to,json=rangeTo,proto3" json:"range_to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RangeRequest) Reset()         { *m = RangeRequest{} }
+func (m *RangeRequest) String() string { return proto.CompactTextString(m) }
+func (*RangeRequest) ProtoMessage()    {}
+func (*RangeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d2a91b51c7bdc125, []int{5}
+}
+
+func (m *RangeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RangeRequest.Unmarshal(m, b)
+}
+func (m *RangeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RangeRequest.Marshal(b, m, deterministic)
+}
+func (m *RangeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RangeRequest.Merge(m, src)
+}
+func (m *RangeRequest) XXX_Size() int {
+	return xxx_messageInfo_RangeRequest.Size(m)
+}
+func (m *RangeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RangeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RangeRequest proto.InternalMessageInfo
+
+func (m *RangeRequest) GetRangeFrom() []byte {
+	if m != nil {
+		return m.RangeFrom
+	}
+	return nil
+}
+
+func (m *RangeRequest) GetRangeTo() []byte {
+	if m != nil {
+		return m.RangeTo
+	}
+	return nil
+}
+
+type Digests struct {
+	Digests              []*Digests_Digest `protobuf:"bytes,1,rep,name=digests,proto3" json:"digests,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Digests) Reset()         { *m = Digests{} }
+func (m *Digests) String() string { return proto.CompactTextString(m) }
+func (*Digests) ProtoMessage()    {}
+func (*Digests) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d2a91b51c7bdc125, []int{6}
+}
+
+func (m *Digests) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Digests.Unmarshal(m, b)
+}
+func (m *Digests) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Digests.Marshal(b, m, deterministic)
+}
+func (m *Digests) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Digests.Merge(m, src)
+}
+func (m *Digests) XXX_Size() int {
+	return xxx_messageInfo_Digests.Size(m)
+}
+func (m *Digests) XXX_DiscardUnknown() {
+	xxx_messageInfo_Digests.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Digests proto.InternalMessageInfo
+
+func (m *Digests) GetDigests() []*Digests_Digest {
+	if m != nil {
+		return m.Digests
+	}
+	return nil
+}
+
+type Digests_Digest struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Hash                 []byte   `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Digests_Digest) Reset()         { *m = Digests_Digest{} }
+func (m *Digests_Digest) String() string { return proto.CompactTextString(m) }
+func (*Digests_Digest) ProtoMessage()    {}
+func (*Digests_Digest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d2a91b51c7bdc125, []int{6, 0}
+}
+
+func (m *Digests_Digest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Digests_Digest.Unmarshal(m, b)
+}
+func (m *Digests_Digest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Digests_Digest.Marshal(b, m, deterministic)
+}
+func (m *Digests_Digest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Digests_Digest.Merge(m, src)
+}
+func (m *Digests_Digest) XXX_Size() int {
+	return xxx_messageInfo_Digests_Digest.Size(m)
+}
+func (m *Digests_Digest) XXX_DiscardUnknown() {
+	xxx_messageInfo_Digests_Digest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Digests_Digest proto.InternalMessageInfo
+
+func (m *Digests_Digest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Digests_Digest) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+type Keys struct {
+	Keys                 []string `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Keys) Reset()         { *m = Keys{} }
+func (m *Keys) String() string { return proto.CompactTextString(m) }
+func (*Keys) ProtoMessage()    {}
+func (*Keys) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d2a91b51c7bdc125, []int{7}
+}
+
+func (m *Keys) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Keys.Unmarshal(m, b)
+}
+func (m *Keys) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Keys.Marshal(b, m, deterministic)
+}
+func (m *Keys) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Keys.Merge(m, src)
+}
+func (m *Keys) XXX_Size() int {
+	return xxx_messageInfo_Keys.Size(m)
+}
+func (m *Keys) XXX_DiscardUnknown() {
+	xxx_messageInfo_Keys.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Keys proto.InternalMessageInfo
+
+func (m *Keys) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Nodes)(nil), "server.Nodes")
 	proto.RegisterType((*FindRequest)(nil), "server.FindRequest")
+	proto.RegisterType((*DebugState)(nil), "server.DebugState")
+	proto.RegisterType((*DebugState_Finger)(nil), "server.DebugState.Finger")
+	proto.RegisterType((*DebugState_Stabilizer)(nil), "server.DebugState.Stabilizer")
+	proto.RegisterType((*MerkleRequest)(nil), "server.MerkleRequest")
+	proto.RegisterType((*Hashes)(nil), "server.Hashes")
+	proto.RegisterType((*RangeRequest)(nil), "server.RangeRequest")
+	proto.RegisterType((*Digests)(nil), "server.Digests")
+	proto.RegisterType((*Digests_Digest)(nil), "server.Digests.Digest")
+	proto.RegisterType((*Keys)(nil), "server.Keys")
 }
 
 func init() {
@@ -113,27 +614,60 @@ func init() {
 }
 
 var fileDescriptor_d2a91b51c7bdc125 = []byte{
-	// 319 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x90, 0x5f, 0x4b, 0x02, 0x41,
-	0x14, 0xc5, 0xfd, 0x93, 0x3e, 0x5c, 0xb5, 0x60, 0x8c, 0x10, 0x23, 0xb0, 0xed, 0x45, 0x08, 0x66,
-	0x43, 0x29, 0xfa, 0xf3, 0x10, 0x18, 0x05, 0x41, 0x88, 0x68, 0x4f, 0xbd, 0xed, 0xee, 0x5c, 0xd7,
-	0x81, 0x75, 0xaf, 0xcd, 0x9d, 0x15, 0xfc, 0x40, 0x7d, 0xcf, 0x58, 0x37, 0x43, 0x8b, 0x05, 0x5f,
-	0xcf, 0xb9, 0xbf, 0x33, 0xe7, 0x0c, 0x34, 0x16, 0x46, 0x2f, 0x3d, 0x8b, 0x72, 0x61, 0xc8, 0x92,
-	0xa8, 0x32, 0x9a, 0x25, 0x9a, 0xf6, 0x69, 0x48, 0x14, 0x46, 0xe8, 0xae, 0x55, 0x3f, 0x99, 0xba,
-	0x38, 0x5f, 0xd8, 0x55, 0x76, 0xd4, 0x86, 0x98, 0xd4, 0x0f, 0xe0, 0x5c, 0x42, 0x65, 0x48, 0x0a,
-	0x59, 0x38, 0x50, 0x49, 0x65, 0x6e, 0x15, 0x3b, 0xe5, 0x6e, 0xad, 0x57, 0x97, 0x59, 0x92, 0x4c,
-	0xdd, 0x71, 0x66, 0x39, 0x67, 0x50, 0x7b, 0xd1, 0xb1, 0x1a, 0xe3, 0x67, 0x82, 0x6c, 0xc5, 0x21,
-	0x94, 0xb4, 0x6a, 0x15, 0x3b, 0xc5, 0x6e, 0x7d, 0x5c, 0xd2, 0xaa, 0xf7, 0x55, 0x86, 0xa3, 0xd7,
-	0xd8, 0xa2, 0x89, 0xbd, 0x68, 0x82, 0x66, 0xa9, 0x03, 0x14, 0xb7, 0x70, 0x30, 0xd2, 0x71, 0x28,
-	0x4e, 0x64, 0xd6, 0x48, 0x6e, 0x1a, 0xc9, 0xe7, 0xb4, 0x51, 0x3b, 0x47, 0x77, 0x0a, 0xe2, 0x1a,
-	0x60, 0x92, 0x04, 0x01, 0x32, 0x93, 0xe1, 0x5c, 0xbe, 0xb1, 0xdd, 0x93, 0xd7, 0x58, 0x6d, 0x64,
-	0x50, 0x61, 0x06, 0xe6, 0x72, 0x3b, 0xfb, 0x9c, 0x82, 0x78, 0x80, 0xe3, 0x74, 0xda, 0xef, 0x8b,
-	0x83, 0xd5, 0xbb, 0xe7, 0x47, 0x28, 0x9a, 0x9b, 0xbb, 0xad, 0xe1, 0xff, 0xe0, 0x7b, 0x68, 0xfe,
-	0x81, 0xdf, 0x34, 0xdb, 0xfd, 0xd8, 0x47, 0x68, 0xa5, 0xf6, 0x53, 0x44, 0x8c, 0x6c, 0x47, 0x06,
-	0x03, 0x54, 0x3a, 0x0e, 0x53, 0x77, 0xbf, 0x80, 0x2b, 0xa8, 0x0e, 0xc9, 0xea, 0xe9, 0x4a, 0xec,
-	0x38, 0xf9, 0x3f, 0x3b, 0xb8, 0xf8, 0x38, 0x0f, 0xb5, 0x9d, 0x25, 0xbe, 0x0c, 0x68, 0xee, 0x5a,
-	0x4f, 0xf3, 0x8c, 0x6e, 0xfa, 0xfd, 0x3b, 0x37, 0x24, 0xa3, 0xdc, 0x2c, 0xc3, 0xaf, 0xae, 0xb1,
-	0xfe, 0x77, 0x00, 0x00, 0x00, 0xff, 0xff, 0xb3, 0xa7, 0xee, 0x52, 0x61, 0x02, 0x00, 0x00,
+	// 845 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0xdb, 0x6e, 0xdb, 0x46,
+	0x10, 0x95, 0x2c, 0x91, 0xb2, 0x46, 0x72, 0x12, 0xac, 0x1d, 0x83, 0x61, 0x61, 0x44, 0x65, 0x5f,
+	0x8c, 0x5e, 0xa8, 0xc2, 0x46, 0x7a, 0x49, 0xd1, 0x06, 0x4d, 0x13, 0xd7, 0x41, 0xdb, 0x40, 0x58,
+	0xf9, 0xa9, 0x2f, 0x02, 0x25, 0x8e, 0xa8, 0x85, 0x28, 0xae, 0xba, 0xbb, 0x54, 0xab, 0xfe, 0x4d,
+	0xbf, 0xac, 0x5f, 0xd1, 0xf7, 0x62, 0x77, 0x49, 0xdd, 0x6c, 0xa1, 0x02, 0xfa, 0xa4, 0x9d, 0x39,
+	0x67, 0x66, 0x87, 0x33, 0x67, 0x56, 0x70, 0x32, 0x17, 0x6c, 0x11, 0x29, 0x0c, 0xe7, 0x82, 0x2b,
+	0x4e, 0x5c, 0x89, 0x62, 0x81, 0xc2, 0xff, 0x20, 0xe1, 0x3c, 0x49, 0xb1, 0x6b, 0xbc, 0xc3, 0x7c,
+	0xdc, 0xc5, 0xd9, 0x5c, 0x2d, 0x2d, 0xc9, 0x7f, 0xbe, 0x0b, 0x2a, 0x36, 0x43, 0xa9, 0xa2, 0xd9,
+	0xbc, 0x20, 0x40, 0xc6, 0xe3, 0x22, 0xa3, 0xdf, 0x16, 0x38, 0xe2, 0x22, 0xb6, 0x56, 0xf0, 0x09,
+	0x38, 0xef, 0x79, 0x8c, 0x92, 0x04, 0xe0, 0x68, 0x92, 0xf4, 0xaa, 0x9d, 0xda, 0x65, 0xeb, 0xaa,
+	0x1d, 0xda, 0x8b, 0x43, 0x8d, 0x52, 0x0b, 0x05, 0x17, 0xd0, 0xba, 0x61, 0x59, 0x4c, 0xf1, 0xb7,
+	0x1c, 0xa5, 0x22, 0x8f, 0xe0, 0x88, 0xc5, 0x5e, 0xb5, 0x53, 0xbd, 0x6c, 0xd3, 0x23, 0x16, 0x07,
+	0x7f, 0x39, 0x00, 0x6f, 0x70, 0x98, 0x27, 0x7d, 0x15, 0x29, 0x24, 0x1d, 0xa8, 0xeb, 0x30, 0x43,
+	0xd8, 0x4d, 0x68, 0x10, 0x12, 0x42, 0x6b, 0x2e, 0x30, 0xc6, 0x11, 0x4a, 0xc9, 0x85, 0x77, 0xf4,
+	0x00, 0x71, 0x93, 0x40, 0x3e, 0x05, 0x90, 0xf9, 0xc8, 0x1a, 0xd2, 0xab, 0x3d, 0x50, 0xe8, 0x06,
+	0x4e, 0xae, 0xa1, 0x31, 0x66, 0x59, 0x82, 0x42, 0x7a, 0x75, 0x43, 0x7d, 0x56, 0x52, 0xd7, 0x45,
+	0x86, 0x37, 0x86, 0x41, 0x4b, 0x26, 0xb9, 0x82, 0xa7, 0x69, 0x24, 0xd5, 0x40, 0xaa, 0x68, 0xc8,
+	0x52, 0xf6, 0x27, 0xc6, 0x03, 0x96, 0xc5, 0xf8, 0x87, 0xe7, 0x74, 0xaa, 0x97, 0x0e, 0x3d, 0xd5,
+	0x60, 0x7f, 0x85, 0xbd, 0xd3, 0x10, 0x79, 0x0e, 0x2d, 0x26, 0x07, 0x72, 0x92, 0xab, 0x98, 0xff,
+	0x9e, 0x79, 0x6e, 0xa7, 0x7a, 0x79, 0x4c, 0x81, 0xc9, 0x7e, 0xe1, 0x21, 0xaf, 0xa0, 0xb5, 0xca,
+	0x27, 0xa4, 0xd7, 0x30, 0xd5, 0x5c, 0x3c, 0x50, 0xcd, 0x2a, 0xb3, 0xa0, 0x9b, 0x11, 0x7e, 0x0f,
+	0x5c, 0x5b, 0x28, 0x39, 0x03, 0xc7, 0xd6, 0x53, 0x35, 0xf5, 0x58, 0xa3, 0x98, 0xc4, 0x51, 0x39,
+	0x89, 0x55, 0xeb, 0x6b, 0xfb, 0x5a, 0xef, 0xff, 0x53, 0x05, 0x58, 0xdf, 0x46, 0x08, 0xd4, 0xb3,
+	0x68, 0x66, 0x67, 0xd5, 0xa4, 0xe6, 0x4c, 0x5e, 0xc0, 0xb1, 0x69, 0x85, 0xc8, 0xb3, 0x62, 0x34,
+	0x7e, 0x68, 0x85, 0x16, 0x96, 0x42, 0x0b, 0xef, 0x4a, 0xa1, 0xd1, 0x86, 0xe6, 0xd2, 0x3c, 0x23,
+	0xdf, 0x42, 0xdb, 0x76, 0xd0, 0x4e, 0xc2, 0xab, 0xfd, 0x67, 0x68, 0xcb, 0x34, 0xd5, 0xd2, 0xc9,
+	0x05, 0x80, 0x09, 0x47, 0x21, 0xb8, 0xf0, 0xea, 0xa6, 0x9e, 0xa6, 0xf6, 0xbc, 0xd5, 0x0e, 0xf2,
+	0x1d, 0x9c, 0xac, 0xe1, 0x41, 0xa4, 0x3c, 0xe7, 0xb0, 0xf4, 0x26, 0xfa, 0x7b, 0x15, 0x2c, 0xe1,
+	0xe4, 0x17, 0x14, 0xd3, 0x14, 0x4b, 0x11, 0x5f, 0x00, 0x88, 0x28, 0x4b, 0x70, 0x30, 0x16, 0x7c,
+	0x56, 0x88, 0xb9, 0x69, 0x3c, 0x37, 0x82, 0xcf, 0xc8, 0x33, 0x38, 0xb6, 0xb0, 0xe2, 0x45, 0x7f,
+	0x1b, 0xc6, 0xbe, 0xe3, 0x7a, 0x14, 0x29, 0x2e, 0x30, 0x35, 0x5f, 0xe8, 0x50, 0x6b, 0x10, 0x0f,
+	0x1a, 0x66, 0x26, 0x68, 0x55, 0xe7, 0xd0, 0xd2, 0x0c, 0x3a, 0xe0, 0xde, 0x46, 0x72, 0x82, 0x92,
+	0x9c, 0x83, 0x3b, 0x31, 0x27, 0xb3, 0x6c, 0x6d, 0x5a, 0x58, 0xc1, 0x2d, 0xb4, 0xa9, 0x4e, 0xfe,
+	0xbf, 0x6b, 0x0b, 0xa6, 0xd0, 0x78, 0xc3, 0x12, 0x94, 0x4a, 0x92, 0xcf, 0xa1, 0x11, 0xdb, 0x63,
+	0xb1, 0xda, 0xe7, 0x2b, 0xe1, 0x59, 0x77, 0xf1, 0x4b, 0x4b, 0x9a, 0x1f, 0x82, 0x6b, 0x5d, 0xe4,
+	0x09, 0xd4, 0xa6, 0xb8, 0x2c, 0x54, 0xa1, 0x8f, 0x5a, 0x28, 0xba, 0xd8, 0xe2, 0x3e, 0x73, 0x0e,
+	0x7c, 0xa8, 0xff, 0x84, 0x4b, 0xa9, 0xb1, 0x29, 0x2e, 0xed, 0x35, 0x4d, 0x6a, 0xce, 0x57, 0x7f,
+	0x3b, 0xf0, 0xf8, 0x5d, 0xa6, 0x50, 0x64, 0x51, 0xda, 0x47, 0xb1, 0x60, 0x23, 0x24, 0x5f, 0x41,
+	0xbd, 0xc7, 0xb2, 0x84, 0x9c, 0xdf, 0x1b, 0xda, 0x5b, 0xfd, 0xa8, 0xf9, 0x7b, 0xfc, 0x41, 0x85,
+	0xbc, 0x00, 0xe8, 0xaf, 0x17, 0x7c, 0x5f, 0xfc, 0xc9, 0xa6, 0xde, 0xa5, 0x09, 0x6b, 0xf5, 0x36,
+	0x9e, 0x91, 0x7d, 0x71, 0x5b, 0x7b, 0x12, 0x54, 0xc8, 0x37, 0x70, 0xa6, 0x9f, 0xbb, 0xd5, 0x8d,
+	0xaf, 0x97, 0x77, 0xd1, 0x30, 0x45, 0x72, 0x5a, 0xf2, 0x36, 0x1e, 0xc3, 0x7b, 0xc1, 0x2f, 0xe1,
+	0x74, 0x27, 0xf8, 0x67, 0x26, 0xd5, 0x61, 0xb1, 0xaf, 0xc0, 0xd3, 0xf0, 0x0f, 0x29, 0x97, 0x28,
+	0x55, 0x4f, 0xe0, 0x08, 0x63, 0x96, 0x25, 0x1a, 0x3d, 0x2c, 0xc1, 0xc7, 0xe0, 0xbe, 0xe7, 0x8a,
+	0x8d, 0x97, 0x64, 0x0b, 0xf1, 0x9f, 0x94, 0x56, 0x4f, 0x7f, 0xf9, 0x88, 0xa7, 0x41, 0x85, 0x7c,
+	0x06, 0xcd, 0xdb, 0x28, 0x8b, 0xe5, 0x24, 0x9a, 0xe2, 0x01, 0xf4, 0x97, 0x5b, 0x6f, 0xfc, 0xbe,
+	0x56, 0x92, 0xfb, 0x8f, 0x5b, 0x50, 0x21, 0x5f, 0x42, 0xdb, 0x2e, 0x5f, 0xb1, 0x07, 0x4f, 0x4b,
+	0xd6, 0xd6, 0x4a, 0xfa, 0x8f, 0x4a, 0xb7, 0xa5, 0x05, 0x15, 0x72, 0xb5, 0x96, 0xf3, 0x59, 0x09,
+	0x6e, 0x6e, 0x8a, 0xff, 0x78, 0x47, 0xd3, 0xe6, 0xbb, 0xe0, 0x47, 0x54, 0xd4, 0xfc, 0xd9, 0xc9,
+	0xf5, 0x87, 0x69, 0xa5, 0xae, 0xe9, 0x05, 0x6c, 0x6a, 0x83, 0x5e, 0xbe, 0xa2, 0xef, 0x12, 0xf6,
+	0x6b, 0xf2, 0xf5, 0x47, 0xbf, 0x7e, 0x98, 0x30, 0x35, 0xc9, 0x87, 0xe1, 0x88, 0xcf, 0xba, 0x2a,
+	0x62, 0x72, 0xc2, 0xbf, 0xb8, 0xbe, 0xfe, 0xba, 0x9b, 0x70, 0x11, 0x77, 0x6d, 0x9a, 0xa1, 0x6b,
+	0xc2, 0xae, 0xff, 0x1d, 0x00, 0xea, 0x70, 0x19, 0x2d, 0xde, 0x07, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -154,7 +688,13 @@ type InternalServiceClient interface {
 	FindSuccessorByTable(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*Node, error)
 	FindSuccessorByList(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*Node, error)
 	FindClosestPrecedingNode(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*Node, error)
-	Notify(ctx context.Context, in *Node, opts ...grpc.CallOption) (*empty.Empty, error)
+	Notify(ctx context.Context, in *Node, opts ...grpc.CallOption) (*Protocol, error)
+	Handshake(ctx context.Context, in *Node, opts ...grpc.CallOption) (*Protocol, error)
+	DebugState(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*DebugState, error)
+	MerkleHashes(ctx context.Context, in *MerkleRequest, opts ...grpc.CallOption) (*Hashes, error)
+	Digests(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*Digests, error)
+	GetRecords(ctx context.Context, in *Keys, opts ...grpc.CallOption) (*Records, error)
+	PutRecords(ctx context.Context, in *Records, opts ...grpc.CallOption) (*empty.Empty, error)
 }
 
 type internalServiceClient struct {
@@ -219,8 +759,8 @@ func (c *internalServiceClient) FindClosestPrecedingNode(ctx context.Context, in
 	return out, nil
 }
 
-func (c *internalServiceClient) Notify(ctx context.Context, in *Node, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
+func (c *internalServiceClient) Notify(ctx context.Context, in *Node, opts ...grpc.CallOption) (*Protocol, error) {
+	out := new(Protocol)
 	err := c.cc.Invoke(ctx, "/server.InternalService/Notify", in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -228,6 +768,60 @@ func (c *internalServiceClient) Notify(ctx context.Context, in *Node, opts ...gr
 	return out, nil
 }
 
+func (c *internalServiceClient) Handshake(ctx context.Context, in *Node, opts ...grpc.CallOption) (*Protocol, error) {
+	out := new(Protocol)
+	err := c.cc.Invoke(ctx, "/server.InternalService/Handshake", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *internalServiceClient) DebugState(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*DebugState, error) {
+	out := new(DebugState)
+	err := c.cc.Invoke(ctx, "/server.InternalService/DebugState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *internalServiceClient) MerkleHashes(ctx context.Context, in *MerkleRequest, opts ...grpc.CallOption) (*Hashes, error) {
+	out := new(Hashes)
+	err := c.cc.Invoke(ctx, "/server.InternalService/MerkleHashes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *internalServiceClient) Digests(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*Digests, error) {
+	out := new(Digests)
+	err := c.cc.Invoke(ctx, "/server.InternalService/Digests", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *internalServiceClient) GetRecords(ctx context.Context, in *Keys, opts ...grpc.CallOption) (*Records, error) {
+	out := new(Records)
+	err := c.cc.Invoke(ctx, "/server.InternalService/GetRecords", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *internalServiceClient) PutRecords(ctx context.Context, in *Records, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/server.InternalService/PutRecords", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // InternalServiceServer is the server API for InternalService service.
 type InternalServiceServer interface {
 	Ping(context.Context, *empty.Empty) (*empty.Empty, error)
@@ -236,7 +830,13 @@ type InternalServiceServer interface {
 	FindSuccessorByTable(context.Context, *FindRequest) (*Node, error)
 	FindSuccessorByList(context.Context, *FindRequest) (*Node, error)
 	FindClosestPrecedingNode(context.Context, *FindRequest) (*Node, error)
-	Notify(context.Context, *Node) (*empty.Empty, error)
+	Notify(context.Context, *Node) (*Protocol, error)
+	Handshake(context.Context, *Node) (*Protocol, error)
+	DebugState(context.Context, *empty.Empty) (*DebugState, error)
+	MerkleHashes(context.Context, *MerkleRequest) (*Hashes, error)
+	Digests(context.Context, *RangeRequest) (*Digests, error)
+	GetRecords(context.Context, *Keys) (*Records, error)
+	PutRecords(context.Context, *Records) (*empty.Empty, error)
 }
 
 // UnimplementedInternalServiceServer can be embedded to have forward compatible implementations.
@@ -261,9 +861,27 @@ func (*UnimplementedInternalServiceServer) FindSuccessorByList(ctx context.Conte
 func (*UnimplementedInternalServiceServer) FindClosestPrecedingNode(ctx context.Context, req *FindRequest) (*Node, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FindClosestPrecedingNode not implemented")
 }
-func (*UnimplementedInternalServiceServer) Notify(ctx context.Context, req *Node) (*empty.Empty, error) {
+func (*UnimplementedInternalServiceServer) Notify(ctx context.Context, req *Node) (*Protocol, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Notify not implemented")
 }
+func (*UnimplementedInternalServiceServer) Handshake(ctx context.Context, req *Node) (*Protocol, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Handshake not implemented")
+}
+func (*UnimplementedInternalServiceServer) DebugState(ctx context.Context, req *empty.Empty) (*DebugState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DebugState not implemented")
+}
+func (*UnimplementedInternalServiceServer) MerkleHashes(ctx context.Context, req *MerkleRequest) (*Hashes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MerkleHashes not implemented")
+}
+func (*UnimplementedInternalServiceServer) Digests(ctx context.Context, req *RangeRequest) (*Digests, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Digests not implemented")
+}
+func (*UnimplementedInternalServiceServer) GetRecords(ctx context.Context, req *Keys) (*Records, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecords not implemented")
+}
+func (*UnimplementedInternalServiceServer) PutRecords(ctx context.Context, req *Records) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutRecords not implemented")
+}
 
 func RegisterInternalServiceServer(s *grpc.Server, srv InternalServiceServer) {
 	s.RegisterService(&_InternalService_serviceDesc, srv)
@@ -395,6 +1013,114 @@ func _InternalService_Notify_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _InternalService_Handshake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Node)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalServiceServer).Handshake(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.InternalService/Handshake",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalServiceServer).Handshake(ctx, req.(*Node))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InternalService_DebugState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalServiceServer).DebugState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.InternalService/DebugState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalServiceServer).DebugState(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InternalService_MerkleHashes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MerkleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalServiceServer).MerkleHashes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.InternalService/MerkleHashes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalServiceServer).MerkleHashes(ctx, req.(*MerkleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InternalService_Digests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalServiceServer).Digests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.InternalService/Digests",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalServiceServer).Digests(ctx, req.(*RangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InternalService_GetRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Keys)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalServiceServer).GetRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.InternalService/GetRecords",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalServiceServer).GetRecords(ctx, req.(*Keys))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InternalService_PutRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Records)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalServiceServer).PutRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.InternalService/PutRecords",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalServiceServer).PutRecords(ctx, req.(*Records))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _InternalService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "server.InternalService",
 	HandlerType: (*InternalServiceServer)(nil),
@@ -427,6 +1153,30 @@ var _InternalService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Notify",
 			Handler:    _InternalService_Notify_Handler,
 		},
+		{
+			MethodName: "Handshake",
+			Handler:    _InternalService_Handshake_Handler,
+		},
+		{
+			MethodName: "DebugState",
+			Handler:    _InternalService_DebugState_Handler,
+		},
+		{
+			MethodName: "MerkleHashes",
+			Handler:    _InternalService_MerkleHashes_Handler,
+		},
+		{
+			MethodName: "Digests",
+			Handler:    _InternalService_Digests_Handler,
+		},
+		{
+			MethodName: "GetRecords",
+			Handler:    _InternalService_GetRecords_Handler,
+		},
+		{
+			MethodName: "PutRecords",
+			Handler:    _InternalService_PutRecords_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "private.proto",
@@ -26,6 +26,9 @@ type chordOption struct {
 	host            string
 	timeoutConnNode time.Duration
 	processOpts     []chord.ProcessOptionFunc
+	faultInjector   *chord.FaultInjector
+	clusterSecret   *ClusterSecret
+	admission       *AdmissionController
 }
 
 // InternalServerOptionFunc represents server options for internal
@@ -56,6 +59,28 @@ func WithTimeoutConnNode(duration time.Duration) InternalServerOptionFunc {
 	}
 }
 
+// WithFaultInjector serves AdminService to change fault rules of injector at runtime.
+func WithFaultInjector(injector *chord.FaultInjector) InternalServerOptionFunc {
+	return func(option *chordOption) {
+		option.faultInjector = injector
+	}
+}
+
+// WithClusterSecret makes the server reject requests without an HMAC of the cluster secret with Unauthenticated.
+func WithClusterSecret(secret *ClusterSecret) InternalServerOptionFunc {
+	return func(option *chordOption) {
+		option.clusterSecret = secret
+	}
+}
+
+// WithStabilizerPriority reports requests in flight to the admission controller of the external server,
+// which sheds external requests while the internal server is busy.
+func WithStabilizerPriority(admission *AdmissionController) InternalServerOptionFunc {
+	return func(option *chordOption) {
+		option.admission = admission
+	}
+}
+
 // NewChordServer creates a chord server
 func NewChordServer(process *chord.Process, port string, opts ...InternalServerOptionFunc) *InternalServer {
 	opt := newDefaultServerOption()
@@ -71,9 +96,23 @@ func NewChordServer(process *chord.Process, port string, opts ...InternalServerO
 }
 
 func (is *InternalServer) newGrpcServer() *grpc.Server {
-	s := grpc.NewServer()
+	var (
+		unary  []grpc.UnaryServerInterceptor
+		stream []grpc.StreamServerInterceptor
+	)
+	if is.opt.clusterSecret != nil {
+		unary = append(unary, is.opt.clusterSecret.UnaryServerInterceptor())
+		stream = append(stream, is.opt.clusterSecret.StreamServerInterceptor())
+	}
+	if is.opt.admission != nil {
+		unary = append(unary, is.opt.admission.InternalUnaryServerInterceptor())
+	}
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
 	reflection.Register(s)
 	RegisterInternalServiceServer(s, is)
+	if is.opt.faultInjector != nil {
+		RegisterAdminServiceServer(s, NewAdminServer(is.opt.faultInjector))
+	}
 	return s
 }
 
@@ -102,18 +141,32 @@ func (is *InternalServer) Shutdown() {
 	is.shutdownCh <- struct{}{}
 }
 
-func (is *InternalServer) Ping(_ context.Context, _ *empty.Empty) (*empty.Empty, error) {
-	if is.process.IsShutdown {
+// target returns the process of a virtual node which handles a request.
+func (is *InternalServer) target(ctx context.Context) (*chord.Process, error) {
+	vnode := vnodeFrom(ctx)
+	process := is.process.VirtualNode(vnode)
+	if process == nil {
+		return nil, status.Errorf(codes.Unavailable, "server: virtual node %d is not running", vnode)
+	}
+	if process.IsShutdown {
 		return nil, status.Errorf(codes.Unavailable, "server has started shutdown")
 	}
+	return process, nil
+}
+
+func (is *InternalServer) Ping(ctx context.Context, _ *empty.Empty) (*empty.Empty, error) {
+	if _, err := is.target(ctx); err != nil {
+		return nil, err
+	}
 	return &empty.Empty{}, nil
 }
 
 func (is *InternalServer) Successors(ctx context.Context, req *empty.Empty) (*Nodes, error) {
-	if is.process.IsShutdown {
-		return nil, status.Errorf(codes.Unavailable, "server has started shutdown")
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
 	}
-	successors, err := is.process.GetSuccessors(ctx)
+	successors, err := process.GetSuccessors(ctx)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "server: internal error occured. successor is not set.")
 	}
@@ -122,9 +175,7 @@ func (is *InternalServer) Successors(ctx context.Context, req *empty.Empty) (*No
 		if suc == nil {
 			continue
 		}
-		nodes = append(nodes, &Node{
-			Host: suc.Reference().Host,
-		})
+		nodes = append(nodes, toNode(suc.Reference()))
 	}
 	return &Nodes{
 		Nodes: nodes,
@@ -132,70 +183,166 @@ func (is *InternalServer) Successors(ctx context.Context, req *empty.Empty) (*No
 }
 
 func (is *InternalServer) Predecessor(ctx context.Context, _ *empty.Empty) (*Node, error) {
-	if is.process.IsShutdown {
-		return nil, status.Errorf(codes.Unavailable, "server has started shutdown")
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
 	}
-	pred, err := is.process.GetPredecessor(ctx)
+	pred, err := process.GetPredecessor(ctx)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "server: internal error occured. predecessor is not set.")
 	}
 	if pred != nil {
-		return &Node{
-			Host: pred.Reference().Host,
-		}, nil
+		return toNode(pred.Reference()), nil
 	}
 	return nil, status.Errorf(codes.NotFound, "server: predecessor is not set.")
 }
 
 func (is *InternalServer) FindSuccessorByTable(ctx context.Context, req *FindRequest) (*Node, error) {
-	if is.process.IsShutdown {
-		return nil, status.Errorf(codes.Unavailable, "server has started shutdown")
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
 	}
-	successor, err := is.process.FindSuccessorByTable(ctx, req.Id)
+	successor, err := process.FindSuccessorByTable(ctx, req.Id)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "server: find successor failed. reason = %#v", err)
 	}
-	return &Node{
-		Host: successor.Reference().Host,
-	}, nil
+	return toNode(successor.Reference()), nil
 }
 
 func (is *InternalServer) FindSuccessorByList(ctx context.Context, req *FindRequest) (*Node, error) {
-	if is.process.IsShutdown {
-		return nil, status.Errorf(codes.Unavailable, "server has started shutdown")
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
 	}
-	successor, err := is.process.FindSuccessorByList(ctx, req.Id)
+	successor, err := process.FindSuccessorByList(ctx, req.Id)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "server: find successor fallback failed. reason = %#v", err)
 	}
-	return &Node{
-		Host: successor.Reference().Host,
-	}, nil
+	return toNode(successor.Reference()), nil
 }
 
 func (is *InternalServer) FindClosestPrecedingNode(ctx context.Context, req *FindRequest) (*Node, error) {
-	if is.process.IsShutdown {
-		return nil, status.Errorf(codes.Unavailable, "server has started shutdown")
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
 	}
-	node, err := is.process.FindClosestPrecedingNode(ctx, req.Id)
+	node, err := process.FindClosestPrecedingNode(ctx, req.Id)
 	if err == chord.ErrStabilizeNotCompleted {
 		return nil, status.Error(codes.NotFound, "Stabilize not completed.")
 	}
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "server: find closest preceding node failed. reason = %#v", err)
 	}
-	return &Node{
-		Host: node.Reference().Host,
-	}, nil
+	return toNode(node.Reference()), nil
 }
 
-func (is *InternalServer) Notify(ctx context.Context, req *Node) (*empty.Empty, error) {
-	if is.process.IsShutdown {
-		return nil, status.Errorf(codes.Unavailable, "server has started shutdown")
+func (is *InternalServer) Notify(ctx context.Context, req *Node) (*Protocol, error) {
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
+	}
+	peer := toNodeRef(req)
+	// The identity is verified first, so that an unverified peer can't record its protocol.
+	if err := process.VerifyPeer(peer); err != nil {
+		log.Warnf("refused notify from Host[%s]. err = %v", peer.Key(), err)
+		return nil, status.Errorf(codes.PermissionDenied, "server: %v", err)
+	}
+	if err := process.NegotiateProtocol(peer, toChordProtocol(req.Protocol)); err != nil {
+		log.Warnf("refused notify from Host[%s]. err = %v", peer.Key(), err)
+		return nil, status.Errorf(codes.FailedPrecondition, "server: %v", err)
 	}
-	err := is.process.Notify(ctx, chord.NewRemoteNode(req.Host, is.process.Transport))
+	err = process.Notify(ctx, chord.NewRemoteNodeFromRef(peer, process.Transport))
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "server: notify failed. reason = %#v", err)
 	}
+	return toProtocol(chord.LocalProtocol()), nil
+}
+
+func (is *InternalServer) Handshake(ctx context.Context, req *Node) (*Protocol, error) {
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
+	}
+	peer := toNodeRef(req)
+	if err := process.VerifyPeer(peer); err != nil {
+		log.Warnf("refused handshake from Host[%s]. err = %v", peer.Key(), err)
+		return nil, status.Errorf(codes.PermissionDenied, "server: %v", err)
+	}
+	if err := process.NegotiateProtocol(peer, toChordProtocol(req.Protocol)); err != nil {
+		log.Warnf("refused handshake from Host[%s]. err = %v", peer.Key(), err)
+		return nil, status.Errorf(codes.FailedPrecondition, "server: %v", err)
+	}
+	return toProtocol(chord.LocalProtocol()), nil
+}
+
+// DebugState returns a routing state of a node.
+// The process may be shut down, so this doesn't refuse requests after shutdown.
+func (is *InternalServer) DebugState(ctx context.Context, _ *empty.Empty) (*DebugState, error) {
+	vnode := vnodeFrom(ctx)
+	process := is.process.VirtualNode(vnode)
+	if process == nil {
+		return nil, status.Errorf(codes.Unavailable, "server: virtual node %d is not running", vnode)
+	}
+	return toDebugStateProto(process.DebugState()), nil
+}
+
+// MerkleHashes returns hashes of nodes of a Merkle tree of records, for a replica peer to find records it disagrees on.
+func (is *InternalServer) MerkleHashes(ctx context.Context, req *MerkleRequest) (*Hashes, error) {
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := process.MerkleHashes(ctx, req.RangeFrom, req.RangeTo, int(req.Level), fromIndexes(req.Indexes))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "server: merkle hashes failed. reason = %#v", err)
+	}
+	return &Hashes{
+		Hashes: hashes,
+	}, nil
+}
+
+// Digests returns digests of records in a range.
+func (is *InternalServer) Digests(ctx context.Context, req *RangeRequest) (*Digests, error) {
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
+	}
+	digests, err := process.Digests(ctx, req.RangeFrom, req.RangeTo)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "server: digests failed. reason = %#v", err)
+	}
+	res := &Digests{
+		Digests: make([]*Digests_Digest, len(digests)),
+	}
+	for i, digest := range digests {
+		res.Digests[i] = &Digests_Digest{Key: digest.Key, Hash: digest.Hash}
+	}
+	return res, nil
+}
+
+// GetRecords returns records of keys. Keys which aren't stored are skipped.
+func (is *InternalServer) GetRecords(ctx context.Context, req *Keys) (*Records, error) {
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records, err := process.GetRecords(ctx, req.Keys)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "server: get records failed. reason = %#v", err)
+	}
+	return &Records{
+		Records: toRecords(records),
+	}, nil
+}
+
+// PutRecords stores records sent by a replica peer.
+func (is *InternalServer) PutRecords(ctx context.Context, req *Records) (*empty.Empty, error) {
+	process, err := is.target(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := process.PutRecords(ctx, toStorageRecords(req.Records)); err != nil {
+		return nil, status.Errorf(codes.Internal, "server: put records failed. reason = %#v", err)
+	}
 	return &empty.Empty{}, nil
 }
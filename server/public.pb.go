@@ -7,6 +7,8 @@ import (
 	context "context"
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
+	duration "github.com/golang/protobuf/ptypes/duration"
+	empty "github.com/golang/protobuf/ptypes/empty"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -24,6 +26,67 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// Consistency represents how many of n replicas must answer a request before it returns.
+type Consistency int32
+
+const (
+	// QUORUM waits for a majority of replicas. It is the default.
+	Consistency_QUORUM Consistency = 0
+	Consistency_ONE    Consistency = 1
+	Consistency_ALL    Consistency = 2
+)
+
+var Consistency_name = map[int32]string{
+	0: "QUORUM",
+	1: "ONE",
+	2: "ALL",
+}
+
+var Consistency_value = map[string]int32{
+	"QUORUM": 0,
+	"ONE":    1,
+	"ALL":    2,
+}
+
+func (x Consistency) String() string {
+	return proto.EnumName(Consistency_name, int32(x))
+}
+
+func (Consistency) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{0}
+}
+
+type RingEvent_Type int32
+
+const (
+	RingEvent_PREDECESSOR_CHANGED RingEvent_Type = 0
+	RingEvent_SUCCESSOR_ADDED     RingEvent_Type = 1
+	RingEvent_SUCCESSOR_REMOVED   RingEvent_Type = 2
+	RingEvent_NODE_DEAD           RingEvent_Type = 3
+)
+
+var RingEvent_Type_name = map[int32]string{
+	0: "PREDECESSOR_CHANGED",
+	1: "SUCCESSOR_ADDED",
+	2: "SUCCESSOR_REMOVED",
+	3: "NODE_DEAD",
+}
+
+var RingEvent_Type_value = map[string]int32{
+	"PREDECESSOR_CHANGED": 0,
+	"SUCCESSOR_ADDED":     1,
+	"SUCCESSOR_REMOVED":   2,
+	"NODE_DEAD":           3,
+}
+
+func (x RingEvent_Type) String() string {
+	return proto.EnumName(RingEvent_Type_name, int32(x))
+}
+
+func (RingEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{6, 0}
+}
+
 type FindHostRequest struct {
 	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -63,8 +126,587 @@ func (m *FindHostRequest) GetKey() string {
 	return ""
 }
 
+type FindReplicasRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// n is how many distinct hosts to return, including the owner. It defaults to 1.
+	N                    int32    `protobuf:"varint,2,opt,name=n,proto3" json:"n,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FindReplicasRequest) Reset()         { *m = FindReplicasRequest{} }
+func (m *FindReplicasRequest) String() string { return proto.CompactTextString(m) }
+func (*FindReplicasRequest) ProtoMessage()    {}
+func (*FindReplicasRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{1}
+}
+
+func (m *FindReplicasRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FindReplicasRequest.Unmarshal(m, b)
+}
+func (m *FindReplicasRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FindReplicasRequest.Marshal(b, m, deterministic)
+}
+func (m *FindReplicasRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FindReplicasRequest.Merge(m, src)
+}
+func (m *FindReplicasRequest) XXX_Size() int {
+	return xxx_messageInfo_FindReplicasRequest.Size(m)
+}
+func (m *FindReplicasRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FindReplicasRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FindReplicasRequest proto.InternalMessageInfo
+
+func (m *FindReplicasRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *FindReplicasRequest) GetN() int32 {
+	if m != nil {
+		return m.N
+	}
+	return 0
+}
+
+// Replicas represents a preference list of a key, which is its owner followed by live successors on distinct hosts in ring order.
+type Replicas struct {
+	Nodes                []*Node  `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Replicas) Reset()         { *m = Replicas{} }
+func (m *Replicas) String() string { return proto.CompactTextString(m) }
+func (*Replicas) ProtoMessage()    {}
+func (*Replicas) Descriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{2}
+}
+
+func (m *Replicas) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Replicas.Unmarshal(m, b)
+}
+func (m *Replicas) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Replicas.Marshal(b, m, deterministic)
+}
+func (m *Replicas) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Replicas.Merge(m, src)
+}
+func (m *Replicas) XXX_Size() int {
+	return xxx_messageInfo_Replicas.Size(m)
+}
+func (m *Replicas) XXX_DiscardUnknown() {
+	xxx_messageInfo_Replicas.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Replicas proto.InternalMessageInfo
+
+func (m *Replicas) GetNodes() []*Node {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+// GetRequest reads a record from the preference list of a key.
+// n and r are set by the server if they are 0, and r overrides the consistency.
+type GetRequest struct {
+	Key                  string      `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Consistency          Consistency `protobuf:"varint,2,opt,name=consistency,proto3,enum=server.Consistency" json:"consistency,omitempty"`
+	N                    int32       `protobuf:"varint,3,opt,name=n,proto3" json:"n,omitempty"`
+	R                    int32       `protobuf:"varint,4,opt,name=r,proto3" json:"r,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *GetRequest) Reset()         { *m = GetRequest{} }
+func (m *GetRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRequest) ProtoMessage()    {}
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{3}
+}
+
+func (m *GetRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRequest.Unmarshal(m, b)
+}
+func (m *GetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRequest.Marshal(b, m, deterministic)
+}
+func (m *GetRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRequest.Merge(m, src)
+}
+func (m *GetRequest) XXX_Size() int {
+	return xxx_messageInfo_GetRequest.Size(m)
+}
+func (m *GetRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRequest proto.InternalMessageInfo
+
+func (m *GetRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *GetRequest) GetConsistency() Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return Consistency_QUORUM
+}
+
+func (m *GetRequest) GetN() int32 {
+	if m != nil {
+		return m.N
+	}
+	return 0
+}
+
+func (m *GetRequest) GetR() int32 {
+	if m != nil {
+		return m.R
+	}
+	return 0
+}
+
+// PutRequest writes a record to the preference list of its key.
+// The version of the record is the context the value is based on, which is a version descending from the version
+// and the siblings of a record read before. Leave it empty to write without reading.
+// n and w are set by the server if they are 0, and w overrides the consistency.
+// With ttl, the value expires after it from now, overriding expires_at of the record.
+type PutRequest struct {
+	Record               *Record            `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`
+	Consistency          Consistency        `protobuf:"varint,2,opt,name=consistency,proto3,enum=server.Consistency" json:"consistency,omitempty"`
+	N                    int32              `protobuf:"varint,3,opt,name=n,proto3" json:"n,omitempty"`
+	W                    int32              `protobuf:"varint,4,opt,name=w,proto3" json:"w,omitempty"`
+	Ttl                  *duration.Duration `protobuf:"bytes,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *PutRequest) Reset()         { *m = PutRequest{} }
+func (m *PutRequest) String() string { return proto.CompactTextString(m) }
+func (*PutRequest) ProtoMessage()    {}
+func (*PutRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{4}
+}
+
+func (m *PutRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PutRequest.Unmarshal(m, b)
+}
+func (m *PutRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PutRequest.Marshal(b, m, deterministic)
+}
+func (m *PutRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PutRequest.Merge(m, src)
+}
+func (m *PutRequest) XXX_Size() int {
+	return xxx_messageInfo_PutRequest.Size(m)
+}
+func (m *PutRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PutRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PutRequest proto.InternalMessageInfo
+
+func (m *PutRequest) GetRecord() *Record {
+	if m != nil {
+		return m.Record
+	}
+	return nil
+}
+
+func (m *PutRequest) GetConsistency() Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return Consistency_QUORUM
+}
+
+func (m *PutRequest) GetN() int32 {
+	if m != nil {
+		return m.N
+	}
+	return 0
+}
+
+func (m *PutRequest) GetW() int32 {
+	if m != nil {
+		return m.W
+	}
+	return 0
+}
+
+func (m *PutRequest) GetTtl() *duration.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+// PutResponse represents the version a value is written with.
+type PutResponse struct {
+	Version              *Version `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PutResponse) Reset()         { *m = PutResponse{} }
+func (m *PutResponse) String() string { return proto.CompactTextString(m) }
+func (*PutResponse) ProtoMessage()    {}
+func (*PutResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{5}
+}
+
+func (m *PutResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PutResponse.Unmarshal(m, b)
+}
+func (m *PutResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PutResponse.Marshal(b, m, deterministic)
+}
+func (m *PutResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PutResponse.Merge(m, src)
+}
+func (m *PutResponse) XXX_Size() int {
+	return xxx_messageInfo_PutResponse.Size(m)
+}
+func (m *PutResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PutResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PutResponse proto.InternalMessageInfo
+
+func (m *PutResponse) GetVersion() *Version {
+	if m != nil {
+		return m.Version
+	}
+	return nil
+}
+
+// RingEvent represents that the ownership of keys in (range_from, range_to] has shifted.
+type RingEvent struct {
+	Type                 RingEvent_Type `protobuf:"varint,1,opt,name=type,proto3,enum=server.RingEvent_Type" json:"type,omitempty"`
+	Node                 *Node          `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	RangeFrom            []byte         `protobuf:"bytes,3,opt,name=range_from,json=rangeFrom,proto3" json:"range_from,omitempty"`
+	RangeTo              []byte         `protobuf:"bytes,4,opt,name=range_to,json=rangeTo,proto3" json:"range_to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *RingEvent) Reset()         { *m = RingEvent{} }
+func (m *RingEvent) String() string { return proto.CompactTextString(m) }
+func (*RingEvent) ProtoMessage()    {}
+func (*RingEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{6}
+}
+
+func (m *RingEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RingEvent.Unmarshal(m, b)
+}
+func (m *RingEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RingEvent.Marshal(b, m, deterministic)
+}
+func (m *RingEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RingEvent.Merge(m, src)
+}
+func (m *RingEvent) XXX_Size() int {
+	return xxx_messageInfo_RingEvent.Size(m)
+}
+func (m *RingEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_RingEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RingEvent proto.InternalMessageInfo
+
+func (m *RingEvent) GetType() RingEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return RingEvent_PREDECESSOR_CHANGED
+}
+
+func (m *RingEvent) GetNode() *Node {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+func (m *RingEvent) GetRangeFrom() []byte {
+	if m != nil {
+		return m.RangeFrom
+	}
+	return nil
+}
+
+func (m *RingEvent) GetRangeTo() []byte {
+	if m != nil {
+		return m.RangeTo
+	}
+	return nil
+}
+
+// RingSnapshot represents every node of a ring in ring order, starting from the node answering.
+type RingSnapshot struct {
+	Nodes                []*Node  `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RingSnapshot) Reset()         { *m = RingSnapshot{} }
+func (m *RingSnapshot) String() string { return proto.CompactTextString(m) }
+func (*RingSnapshot) ProtoMessage()    {}
+func (*RingSnapshot) Descriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{7}
+}
+
+func (m *RingSnapshot) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RingSnapshot.Unmarshal(m, b)
+}
+func (m *RingSnapshot) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RingSnapshot.Marshal(b, m, deterministic)
+}
+func (m *RingSnapshot) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RingSnapshot.Merge(m, src)
+}
+func (m *RingSnapshot) XXX_Size() int {
+	return xxx_messageInfo_RingSnapshot.Size(m)
+}
+func (m *RingSnapshot) XXX_DiscardUnknown() {
+	xxx_messageInfo_RingSnapshot.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RingSnapshot proto.InternalMessageInfo
+
+func (m *RingSnapshot) GetNodes() []*Node {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+// Ownership represents the key space a host owns with its virtual nodes.
+type Ownership struct {
+	// share is a fraction of the key space the host owns in total.
+	Share                float64            `protobuf:"fixed64,1,opt,name=share,proto3" json:"share,omitempty"`
+	Ranges               []*Ownership_Range `protobuf:"bytes,2,rep,name=ranges,proto3" json:"ranges,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *Ownership) Reset()         { *m = Ownership{} }
+func (m *Ownership) String() string { return proto.CompactTextString(m) }
+func (*Ownership) ProtoMessage()    {}
+func (*Ownership) Descriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{8}
+}
+
+func (m *Ownership) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Ownership.Unmarshal(m, b)
+}
+func (m *Ownership) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Ownership.Marshal(b, m, deterministic)
+}
+func (m *Ownership) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Ownership.Merge(m, src)
+}
+func (m *Ownership) XXX_Size() int {
+	return xxx_messageInfo_Ownership.Size(m)
+}
+func (m *Ownership) XXX_DiscardUnknown() {
+	xxx_messageInfo_Ownership.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Ownership proto.InternalMessageInfo
+
+func (m *Ownership) GetShare() float64 {
+	if m != nil {
+		return m.Share
+	}
+	return 0
+}
+
+func (m *Ownership) GetRanges() []*Ownership_Range {
+	if m != nil {
+		return m.Ranges
+	}
+	return nil
+}
+
+type Ownership_Range struct {
+	Node                 *Node    `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	RangeFrom            []byte   `protobuf:"bytes,2,opt,name=range_from,json=rangeFrom,proto3" json:"range_from,omitempty"`
+	RangeTo              []byte   `protobuf:"bytes,3,opt,name=range_to,json=rangeTo,proto3" json:"range_to,omitempty"`
+	Share                float64  `protobuf:"fixed64,4,opt,name=share,proto3" json:"share,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Ownership_Range) Reset()         { *m = Ownership_Range{} }
+func (m *Ownership_Range) String() string { return proto.CompactTextString(m) }
+func (*Ownership_Range) ProtoMessage()    {}
+func (*Ownership_Range) Descriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{8, 0}
+}
+
+func (m *Ownership_Range) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Ownership_Range.Unmarshal(m, b)
+}
+func (m *Ownership_Range) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Ownership_Range.Marshal(b, m, deterministic)
+}
+func (m *Ownership_Range) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Ownership_Range.Merge(m, src)
+}
+func (m *Ownership_Range) XXX_Size() int {
+	return xxx_messageInfo_Ownership_Range.Size(m)
+}
+func (m *Ownership_Range) XXX_DiscardUnknown() {
+	xxx_messageInfo_Ownership_Range.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Ownership_Range proto.InternalMessageInfo
+
+func (m *Ownership_Range) GetNode() *Node {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+func (m *Ownership_Range) GetRangeFrom() []byte {
+	if m != nil {
+		return m.RangeFrom
+	}
+	return nil
+}
+
+func (m *Ownership_Range) GetRangeTo() []byte {
+	if m != nil {
+		return m.RangeTo
+	}
+	return nil
+}
+
+func (m *Ownership_Range) GetShare() float64 {
+	if m != nil {
+		return m.Share
+	}
+	return 0
+}
+
+// LookupCacheStats represents counters of the cache of FindHostForKey results.
+type LookupCacheStats struct {
+	Enabled bool   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Hits    uint64 `protobuf:"varint,2,opt,name=hits,proto3" json:"hits,omitempty"`
+	Misses  uint64 `protobuf:"varint,3,opt,name=misses,proto3" json:"misses,omitempty"`
+	// evictions counts entries dropped because the cache is full.
+	Evictions uint64 `protobuf:"varint,4,opt,name=evictions,proto3" json:"evictions,omitempty"`
+	// invalidations counts times every entry is dropped because the routing state has changed.
+	Invalidations        uint64   `protobuf:"varint,5,opt,name=invalidations,proto3" json:"invalidations,omitempty"`
+	Size                 int32    `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LookupCacheStats) Reset()         { *m = LookupCacheStats{} }
+func (m *LookupCacheStats) String() string { return proto.CompactTextString(m) }
+func (*LookupCacheStats) ProtoMessage()    {}
+func (*LookupCacheStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_413a91106d7bcce8, []int{9}
+}
+
+func (m *LookupCacheStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LookupCacheStats.Unmarshal(m, b)
+}
+func (m *LookupCacheStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LookupCacheStats.Marshal(b, m, deterministic)
+}
+func (m *LookupCacheStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LookupCacheStats.Merge(m, src)
+}
+func (m *LookupCacheStats) XXX_Size() int {
+	return xxx_messageInfo_LookupCacheStats.Size(m)
+}
+func (m *LookupCacheStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_LookupCacheStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LookupCacheStats proto.InternalMessageInfo
+
+func (m *LookupCacheStats) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+func (m *LookupCacheStats) GetHits() uint64 {
+	if m != nil {
+		return m.Hits
+	}
+	return 0
+}
+
+func (m *LookupCacheStats) GetMisses() uint64 {
+	if m != nil {
+		return m.Misses
+	}
+	return 0
+}
+
+func (m *LookupCacheStats) GetEvictions() uint64 {
+	if m != nil {
+		return m.Evictions
+	}
+	return 0
+}
+
+func (m *LookupCacheStats) GetInvalidations() uint64 {
+	if m != nil {
+		return m.Invalidations
+	}
+	return 0
+}
+
+func (m *LookupCacheStats) GetSize() int32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
 func init() {
+	proto.RegisterEnum("server.Consistency", Consistency_name, Consistency_value)
+	proto.RegisterEnum("server.RingEvent_Type", RingEvent_Type_name, RingEvent_Type_value)
 	proto.RegisterType((*FindHostRequest)(nil), "server.FindHostRequest")
+	proto.RegisterType((*FindReplicasRequest)(nil), "server.FindReplicasRequest")
+	proto.RegisterType((*Replicas)(nil), "server.Replicas")
+	proto.RegisterType((*GetRequest)(nil), "server.GetRequest")
+	proto.RegisterType((*PutRequest)(nil), "server.PutRequest")
+	proto.RegisterType((*PutResponse)(nil), "server.PutResponse")
+	proto.RegisterType((*RingEvent)(nil), "server.RingEvent")
+	proto.RegisterType((*RingSnapshot)(nil), "server.RingSnapshot")
+	proto.RegisterType((*Ownership)(nil), "server.Ownership")
+	proto.RegisterType((*Ownership_Range)(nil), "server.Ownership.Range")
+	proto.RegisterType((*LookupCacheStats)(nil), "server.LookupCacheStats")
 }
 
 func init() {
@@ -72,18 +714,62 @@ func init() {
 }
 
 var fileDescriptor_413a91106d7bcce8 = []byte{
-	// 176 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x29, 0x28, 0x4d, 0xca,
-	0xc9, 0x4c, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2b, 0x4e, 0x2d, 0x2a, 0x4b, 0x2d,
-	0x92, 0xe2, 0xca, 0xcb, 0x4f, 0x49, 0x85, 0x88, 0x29, 0x29, 0x73, 0xf1, 0xbb, 0x65, 0xe6, 0xa5,
-	0x78, 0xe4, 0x17, 0x97, 0x04, 0xa5, 0x16, 0x96, 0xa6, 0x16, 0x97, 0x08, 0x09, 0x70, 0x31, 0x67,
-	0xa7, 0x56, 0x4a, 0x30, 0x2a, 0x30, 0x6a, 0x70, 0x06, 0x81, 0x98, 0x46, 0x3e, 0x5c, 0xfc, 0xae,
-	0x15, 0x25, 0xa9, 0x45, 0x79, 0x89, 0x39, 0xc1, 0xa9, 0x45, 0x65, 0x99, 0xc9, 0xa9, 0x42, 0x96,
-	0x5c, 0x7c, 0x30, 0x7d, 0x6e, 0xf9, 0x45, 0xde, 0xa9, 0x95, 0x42, 0xe2, 0x7a, 0x10, 0xe3, 0xf5,
-	0xd0, 0xcc, 0x93, 0xe2, 0x81, 0x49, 0xf8, 0xe5, 0xa7, 0xa4, 0x2a, 0x31, 0x38, 0x29, 0x47, 0x29,
-	0xa6, 0x67, 0x96, 0x64, 0x94, 0x26, 0xe9, 0x25, 0xe7, 0xe7, 0xea, 0x97, 0x24, 0x66, 0x16, 0x67,
-	0xe4, 0x9b, 0x19, 0x1b, 0x5b, 0xea, 0xa7, 0xe7, 0x17, 0xa5, 0xe8, 0x43, 0xd4, 0x26, 0xb1, 0x81,
-	0x9d, 0x67, 0x0c, 0x08, 0x00, 0x00, 0xff, 0xff, 0xa4, 0x3b, 0x6d, 0x53, 0xc2, 0x00, 0x00, 0x00,
+	// 875 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0xcd, 0x6e, 0xdb, 0x46,
+	0x10, 0x26, 0xf5, 0xe7, 0x68, 0xa4, 0x58, 0xcc, 0x28, 0x75, 0x18, 0xa5, 0x2d, 0xdc, 0x4d, 0x51,
+	0xb8, 0x09, 0x40, 0x05, 0x32, 0x52, 0x34, 0xed, 0x49, 0x15, 0x69, 0xa5, 0xa8, 0x63, 0xb9, 0xab,
+	0x38, 0x05, 0x72, 0x31, 0x28, 0x6a, 0x23, 0x11, 0x91, 0xb8, 0xcc, 0xee, 0x4a, 0xae, 0xfa, 0x54,
+	0x3d, 0xf4, 0x35, 0xfa, 0x2c, 0x3d, 0xf7, 0x56, 0x70, 0x49, 0x4a, 0xb2, 0x0c, 0xb7, 0x39, 0xf4,
+	0xb6, 0x33, 0xf3, 0xcd, 0xcc, 0xb7, 0x3b, 0xb3, 0x1f, 0xd4, 0xe3, 0xc5, 0x68, 0x16, 0x06, 0x4e,
+	0x2c, 0xb8, 0xe2, 0x58, 0x91, 0x4c, 0x2c, 0x99, 0x68, 0x7d, 0x3e, 0xe1, 0x7c, 0x32, 0x63, 0x6d,
+	0xed, 0x1d, 0x2d, 0xde, 0xb5, 0xc7, 0x0b, 0xe1, 0xab, 0x90, 0x47, 0x29, 0xae, 0xf5, 0x68, 0x37,
+	0xce, 0xe6, 0xb1, 0x5a, 0x65, 0x41, 0x88, 0xf8, 0x98, 0x65, 0xe7, 0xba, 0x60, 0x01, 0x17, 0xe3,
+	0xd4, 0x22, 0x8f, 0xa1, 0x71, 0x12, 0x46, 0xe3, 0x97, 0x5c, 0x2a, 0xca, 0x3e, 0x2c, 0x98, 0x54,
+	0x68, 0x41, 0xf1, 0x3d, 0x5b, 0xd9, 0xe6, 0xa1, 0x79, 0x54, 0xa5, 0xc9, 0x91, 0x3c, 0x87, 0x66,
+	0x02, 0xa2, 0x2c, 0x9e, 0x85, 0x81, 0x2f, 0x6f, 0x05, 0x62, 0x1d, 0xcc, 0xc8, 0x2e, 0x1c, 0x9a,
+	0x47, 0x65, 0x6a, 0x46, 0xc4, 0x81, 0x3b, 0x79, 0x0a, 0x12, 0x28, 0x27, 0x1c, 0xa4, 0x6d, 0x1e,
+	0x16, 0x8f, 0x6a, 0x9d, 0xba, 0x93, 0x5e, 0xcb, 0x39, 0xe3, 0x63, 0x46, 0xd3, 0x10, 0xf9, 0x00,
+	0xd0, 0x67, 0xb7, 0xd3, 0xc0, 0xe7, 0x50, 0x0b, 0x78, 0x24, 0x43, 0xa9, 0x58, 0x14, 0xac, 0x74,
+	0x9f, 0xfd, 0x4e, 0x33, 0xaf, 0xd4, 0xdb, 0x84, 0xe8, 0x36, 0x2e, 0x25, 0x55, 0xcc, 0x48, 0x25,
+	0x96, 0xb0, 0x4b, 0xa9, 0x25, 0xc8, 0x1f, 0x26, 0xc0, 0xf9, 0x62, 0xdd, 0xf3, 0x2b, 0xa8, 0xa4,
+	0xaf, 0xa3, 0xdb, 0xd6, 0x3a, 0xfb, 0x79, 0x71, 0xaa, 0xbd, 0x34, 0x8b, 0xfe, 0x6f, 0x4c, 0xae,
+	0x72, 0x26, 0x57, 0xf8, 0x14, 0x8a, 0x4a, 0xcd, 0xec, 0xb2, 0xee, 0xfb, 0xd0, 0x49, 0xa7, 0xe9,
+	0xe4, 0xd3, 0x74, 0xdc, 0x6c, 0xda, 0x34, 0x41, 0x91, 0x6f, 0xa1, 0xa6, 0x59, 0xcb, 0x98, 0x47,
+	0x92, 0xe1, 0xd7, 0xb0, 0xb7, 0x64, 0x42, 0x86, 0x3c, 0xca, 0x78, 0x37, 0x72, 0x2a, 0x6f, 0x52,
+	0x37, 0xcd, 0xe3, 0xe4, 0x2f, 0x13, 0xaa, 0x34, 0x8c, 0x26, 0xde, 0x92, 0x45, 0x0a, 0x9f, 0x40,
+	0x49, 0xad, 0x62, 0xa6, 0xb3, 0xf6, 0x3b, 0x07, 0xeb, 0xdb, 0xe6, 0x00, 0xe7, 0xf5, 0x2a, 0x66,
+	0x54, 0x63, 0xf0, 0x10, 0x4a, 0xc9, 0x98, 0xf4, 0x65, 0x77, 0x07, 0xa8, 0x23, 0xf8, 0x19, 0x80,
+	0xf0, 0xa3, 0x09, 0xbb, 0x7c, 0x27, 0xf8, 0x5c, 0xdf, 0xb3, 0x4e, 0xab, 0xda, 0x73, 0x22, 0xf8,
+	0x1c, 0x1f, 0xc2, 0x9d, 0x34, 0xac, 0xb8, 0xbe, 0x76, 0x9d, 0xee, 0x69, 0xfb, 0x35, 0x27, 0x6f,
+	0xa1, 0x94, 0x74, 0xc2, 0x07, 0xd0, 0x3c, 0xa7, 0x9e, 0xeb, 0xf5, 0xbc, 0xe1, 0x70, 0x40, 0x2f,
+	0x7b, 0x2f, 0xbb, 0x67, 0x7d, 0xcf, 0xb5, 0x0c, 0x6c, 0x42, 0x63, 0x78, 0xd1, 0xcb, 0xdc, 0x5d,
+	0xd7, 0xf5, 0x5c, 0xcb, 0xc4, 0x4f, 0xe0, 0xde, 0xc6, 0x49, 0xbd, 0x57, 0x83, 0x37, 0x9e, 0x6b,
+	0x15, 0xf0, 0x2e, 0x54, 0xcf, 0x06, 0xae, 0x77, 0xe9, 0x7a, 0x5d, 0xd7, 0x2a, 0x92, 0x0e, 0xd4,
+	0x93, 0xfb, 0x0c, 0x23, 0x3f, 0x96, 0x53, 0xae, 0x3e, 0x6a, 0x13, 0xff, 0x34, 0xa1, 0x3a, 0xb8,
+	0x8a, 0x98, 0x90, 0xd3, 0x30, 0xc6, 0xfb, 0x50, 0x96, 0x53, 0x5f, 0xa4, 0xcf, 0x64, 0xd2, 0xd4,
+	0xc0, 0x36, 0x54, 0x34, 0x7d, 0x69, 0x17, 0x74, 0xa1, 0x07, 0x79, 0xa1, 0x75, 0xa2, 0x43, 0x93,
+	0x38, 0xcd, 0x60, 0xad, 0x15, 0x94, 0xb5, 0x63, 0xfd, 0x92, 0xe6, 0x47, 0xbe, 0x64, 0xe1, 0xdf,
+	0x5e, 0xb2, 0x78, 0xed, 0x25, 0x37, 0x5c, 0x4b, 0x5b, 0x5c, 0xc9, 0xef, 0x26, 0x58, 0xa7, 0x9c,
+	0xbf, 0x5f, 0xc4, 0x3d, 0x3f, 0x98, 0xb2, 0xa1, 0xf2, 0x95, 0x44, 0x1b, 0xf6, 0x58, 0xe4, 0x8f,
+	0x66, 0x2c, 0xdd, 0xf6, 0x3b, 0x34, 0x37, 0x11, 0xa1, 0x34, 0x0d, 0x95, 0xd4, 0x8d, 0x4b, 0x54,
+	0x9f, 0xf1, 0x00, 0x2a, 0xf3, 0x50, 0x4a, 0x26, 0x75, 0xc7, 0x12, 0xcd, 0x2c, 0xfc, 0x14, 0xaa,
+	0x6c, 0x19, 0x06, 0xc9, 0x6e, 0x4a, 0xdd, 0xb4, 0x44, 0x37, 0x0e, 0xfc, 0x12, 0xee, 0x86, 0xd1,
+	0xd2, 0x9f, 0x85, 0x63, 0x3f, 0x45, 0x94, 0x35, 0xe2, 0xba, 0x33, 0xe9, 0x27, 0xc3, 0xdf, 0x98,
+	0x5d, 0xd1, 0x9f, 0x41, 0x9f, 0x9f, 0x3c, 0x85, 0xda, 0xd6, 0x3f, 0x42, 0x80, 0xca, 0xcf, 0x17,
+	0x03, 0x7a, 0xf1, 0xca, 0x32, 0x70, 0x0f, 0x8a, 0x83, 0x33, 0xcf, 0x32, 0x93, 0x43, 0xf7, 0xf4,
+	0xd4, 0x2a, 0x74, 0xfe, 0x2e, 0x42, 0xc3, 0xfb, 0x55, 0x31, 0x11, 0xf9, 0xb3, 0x21, 0x13, 0xcb,
+	0x30, 0x60, 0xf8, 0x02, 0xf6, 0x73, 0x65, 0x3b, 0xe1, 0xe2, 0x27, 0xb6, 0xc2, 0xf5, 0x84, 0x76,
+	0x14, 0xaf, 0x75, 0x6d, 0x04, 0xc4, 0x40, 0x0f, 0x70, 0x5b, 0xef, 0xb2, 0xf4, 0x47, 0xdb, 0xe9,
+	0x3b, 0x5a, 0xd8, 0xb2, 0x36, 0x4a, 0x91, 0x06, 0x88, 0x81, 0xdf, 0x41, 0xf5, 0x17, 0x5f, 0x05,
+	0xd3, 0x64, 0xfd, 0xf0, 0xe0, 0xc6, 0x97, 0xf6, 0x12, 0x81, 0x6e, 0xdd, 0xbb, 0xf1, 0xe9, 0x88,
+	0xf1, 0xcc, 0xc4, 0x2e, 0x34, 0x12, 0x2d, 0xdc, 0x5e, 0xdc, 0xdb, 0x2a, 0xdc, 0xdf, 0xae, 0x90,
+	0xa3, 0x89, 0x81, 0xdf, 0x43, 0xbd, 0xcf, 0xd4, 0x66, 0x8d, 0xff, 0x93, 0xc1, 0x1a, 0x4a, 0x0c,
+	0xfc, 0x11, 0x9a, 0x7d, 0xa6, 0x6e, 0xec, 0xcc, 0x6d, 0x35, 0xec, 0xbc, 0xc6, 0x6e, 0x06, 0x31,
+	0x12, 0x65, 0xeb, 0x33, 0x85, 0x98, 0x43, 0x36, 0x1a, 0xdf, 0xda, 0xd1, 0x57, 0x62, 0xe0, 0x33,
+	0x28, 0x9e, 0x2f, 0xb6, 0xc0, 0x1b, 0x71, 0x6e, 0x35, 0xaf, 0xf9, 0x52, 0xe9, 0x23, 0xc6, 0x0f,
+	0x8f, 0xdf, 0x7e, 0x31, 0x09, 0xd5, 0x74, 0x31, 0x72, 0x02, 0x3e, 0x6f, 0x2b, 0x3f, 0x94, 0x53,
+	0xfe, 0xcd, 0xf1, 0xf1, 0x8b, 0xf6, 0x84, 0x8b, 0x71, 0x3b, 0x4d, 0x19, 0x55, 0x34, 0xdf, 0xe3,
+	0x7f, 0x06, 0x00, 0xfd, 0x8d, 0x1e, 0x5b, 0x5c, 0x07, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -99,6 +785,13 @@ const _ = grpc.SupportPackageIsVersion6
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type ExternalServiceClient interface {
 	FindHostForKey(ctx context.Context, in *FindHostRequest, opts ...grpc.CallOption) (*Node, error)
+	FindReplicasForKey(ctx context.Context, in *FindReplicasRequest, opts ...grpc.CallOption) (*Replicas, error)
+	WatchRing(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (ExternalService_WatchRingClient, error)
+	GetRingSnapshot(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*RingSnapshot, error)
+	GetOwnership(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Ownership, error)
+	GetLookupCacheStats(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*LookupCacheStats, error)
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Record, error)
+	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error)
 }
 
 type externalServiceClient struct {
@@ -118,9 +811,102 @@ func (c *externalServiceClient) FindHostForKey(ctx context.Context, in *FindHost
 	return out, nil
 }
 
+func (c *externalServiceClient) FindReplicasForKey(ctx context.Context, in *FindReplicasRequest, opts ...grpc.CallOption) (*Replicas, error) {
+	out := new(Replicas)
+	err := c.cc.Invoke(ctx, "/server.ExternalService/FindReplicasForKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *externalServiceClient) WatchRing(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (ExternalService_WatchRingClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ExternalService_serviceDesc.Streams[0], "/server.ExternalService/WatchRing", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &externalServiceWatchRingClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ExternalService_WatchRingClient interface {
+	Recv() (*RingEvent, error)
+	grpc.ClientStream
+}
+
+type externalServiceWatchRingClient struct {
+	grpc.ClientStream
+}
+
+func (x *externalServiceWatchRingClient) Recv() (*RingEvent, error) {
+	m := new(RingEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *externalServiceClient) GetRingSnapshot(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*RingSnapshot, error) {
+	out := new(RingSnapshot)
+	err := c.cc.Invoke(ctx, "/server.ExternalService/GetRingSnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *externalServiceClient) GetOwnership(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Ownership, error) {
+	out := new(Ownership)
+	err := c.cc.Invoke(ctx, "/server.ExternalService/GetOwnership", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *externalServiceClient) GetLookupCacheStats(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*LookupCacheStats, error) {
+	out := new(LookupCacheStats)
+	err := c.cc.Invoke(ctx, "/server.ExternalService/GetLookupCacheStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *externalServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Record, error) {
+	out := new(Record)
+	err := c.cc.Invoke(ctx, "/server.ExternalService/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *externalServiceClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error) {
+	out := new(PutResponse)
+	err := c.cc.Invoke(ctx, "/server.ExternalService/Put", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ExternalServiceServer is the server API for ExternalService service.
 type ExternalServiceServer interface {
 	FindHostForKey(context.Context, *FindHostRequest) (*Node, error)
+	FindReplicasForKey(context.Context, *FindReplicasRequest) (*Replicas, error)
+	WatchRing(*empty.Empty, ExternalService_WatchRingServer) error
+	GetRingSnapshot(context.Context, *empty.Empty) (*RingSnapshot, error)
+	GetOwnership(context.Context, *empty.Empty) (*Ownership, error)
+	GetLookupCacheStats(context.Context, *empty.Empty) (*LookupCacheStats, error)
+	Get(context.Context, *GetRequest) (*Record, error)
+	Put(context.Context, *PutRequest) (*PutResponse, error)
 }
 
 // UnimplementedExternalServiceServer can be embedded to have forward compatible implementations.
@@ -130,6 +916,27 @@ type UnimplementedExternalServiceServer struct {
 func (*UnimplementedExternalServiceServer) FindHostForKey(ctx context.Context, req *FindHostRequest) (*Node, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FindHostForKey not implemented")
 }
+func (*UnimplementedExternalServiceServer) FindReplicasForKey(ctx context.Context, req *FindReplicasRequest) (*Replicas, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindReplicasForKey not implemented")
+}
+func (*UnimplementedExternalServiceServer) WatchRing(req *empty.Empty, srv ExternalService_WatchRingServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRing not implemented")
+}
+func (*UnimplementedExternalServiceServer) GetRingSnapshot(ctx context.Context, req *empty.Empty) (*RingSnapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRingSnapshot not implemented")
+}
+func (*UnimplementedExternalServiceServer) GetOwnership(ctx context.Context, req *empty.Empty) (*Ownership, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOwnership not implemented")
+}
+func (*UnimplementedExternalServiceServer) GetLookupCacheStats(ctx context.Context, req *empty.Empty) (*LookupCacheStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLookupCacheStats not implemented")
+}
+func (*UnimplementedExternalServiceServer) Get(ctx context.Context, req *GetRequest) (*Record, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (*UnimplementedExternalServiceServer) Put(ctx context.Context, req *PutRequest) (*PutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Put not implemented")
+}
 
 func RegisterExternalServiceServer(s *grpc.Server, srv ExternalServiceServer) {
 	s.RegisterService(&_ExternalService_serviceDesc, srv)
@@ -153,6 +960,135 @@ func _ExternalService_FindHostForKey_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ExternalService_FindReplicasForKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindReplicasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExternalServiceServer).FindReplicasForKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.ExternalService/FindReplicasForKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExternalServiceServer).FindReplicasForKey(ctx, req.(*FindReplicasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExternalService_WatchRing_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(empty.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExternalServiceServer).WatchRing(m, &externalServiceWatchRingServer{stream})
+}
+
+type ExternalService_WatchRingServer interface {
+	Send(*RingEvent) error
+	grpc.ServerStream
+}
+
+type externalServiceWatchRingServer struct {
+	grpc.ServerStream
+}
+
+func (x *externalServiceWatchRingServer) Send(m *RingEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ExternalService_GetRingSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExternalServiceServer).GetRingSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.ExternalService/GetRingSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExternalServiceServer).GetRingSnapshot(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExternalService_GetOwnership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExternalServiceServer).GetOwnership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.ExternalService/GetOwnership",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExternalServiceServer).GetOwnership(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExternalService_GetLookupCacheStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExternalServiceServer).GetLookupCacheStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.ExternalService/GetLookupCacheStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExternalServiceServer).GetLookupCacheStats(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExternalService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExternalServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.ExternalService/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExternalServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExternalService_Put_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExternalServiceServer).Put(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/server.ExternalService/Put",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExternalServiceServer).Put(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _ExternalService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "server.ExternalService",
 	HandlerType: (*ExternalServiceServer)(nil),
@@ -161,7 +1097,37 @@ var _ExternalService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "FindHostForKey",
 			Handler:    _ExternalService_FindHostForKey_Handler,
 		},
+		{
+			MethodName: "FindReplicasForKey",
+			Handler:    _ExternalService_FindReplicasForKey_Handler,
+		},
+		{
+			MethodName: "GetRingSnapshot",
+			Handler:    _ExternalService_GetRingSnapshot_Handler,
+		},
+		{
+			MethodName: "GetOwnership",
+			Handler:    _ExternalService_GetOwnership_Handler,
+		},
+		{
+			MethodName: "GetLookupCacheStats",
+			Handler:    _ExternalService_GetLookupCacheStats_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _ExternalService_Get_Handler,
+		},
+		{
+			MethodName: "Put",
+			Handler:    _ExternalService_Put_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchRing",
+			Handler:       _ExternalService_WatchRing_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "public.proto",
 }
@@ -2,13 +2,28 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
 	log "github.com/sirupsen/logrus"
 	"github.com/taisho6339/gord/chord"
 	"github.com/taisho6339/gord/pkg/model"
+	"github.com/taisho6339/gord/storage"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 	"net"
+	"time"
+)
+
+const (
+	// ExpectedOwnerHeader carries the node which a client expects to own the key of a Get or a Put.
+	ExpectedOwnerHeader = "gord-expected-owner"
+	// NotOwnerHeader carries the actual owner of a key, if it isn't the node which the client expects.
+	NotOwnerHeader = "gord-not-owner"
 )
 
 // ExternalServer represents gRPC server to expose for gord users
@@ -16,19 +31,50 @@ type ExternalServer struct {
 	port       string
 	process    *chord.Process
 	shutdownCh chan struct{}
+	admission  *AdmissionController
+	cache      *lookupCache
+}
+
+// ExternalServerOptionFunc represents server options for external
+type ExternalServerOptionFunc func(server *ExternalServer)
+
+// WithAdmission makes the server refuse requests over the limits of the controller with ResourceExhausted.
+func WithAdmission(admission *AdmissionController) ExternalServerOptionFunc {
+	return func(server *ExternalServer) {
+		server.admission = admission
+	}
+}
+
+// WithLookupCache caches results of FindHostForKey for ttl, keeping up to capacity ranges of IDs.
+// The cache is dropped whenever successors, predecessor or fingers of the node change.
+func WithLookupCache(ttl time.Duration, capacity int) ExternalServerOptionFunc {
+	return func(server *ExternalServer) {
+		server.cache = newLookupCache(ttl, capacity)
+	}
 }
 
 // NewExternalServer creates an gRPC server to expose
-func NewExternalServer(process *chord.Process, port string) *ExternalServer {
-	return &ExternalServer{
+func NewExternalServer(process *chord.Process, port string, opts ...ExternalServerOptionFunc) *ExternalServer {
+	server := &ExternalServer{
 		port:       port,
 		process:    process,
 		shutdownCh: make(chan struct{}, 1),
 	}
+	for _, opt := range opts {
+		opt(server)
+	}
+	return server
 }
 
 func (g *ExternalServer) newGrpcServer() *grpc.Server {
-	s := grpc.NewServer()
+	var opts []grpc.ServerOption
+	if g.admission != nil {
+		opts = append(opts,
+			grpc.UnaryInterceptor(g.admission.UnaryServerInterceptor()),
+			grpc.StreamInterceptor(g.admission.StreamServerInterceptor()),
+		)
+	}
+	s := grpc.NewServer(opts...)
 	reflection.Register(s)
 	RegisterExternalServiceServer(s, g)
 	return s
@@ -59,13 +105,249 @@ func (g *ExternalServer) Shutdown() {
 // FindHostForKey search for a given key's node.
 // It is implemented for PublicService.
 func (g *ExternalServer) FindHostForKey(ctx context.Context, req *FindHostRequest) (*Node, error) {
-	id := model.NewHashID(req.Key)
-	s, err := g.process.FindSuccessorByTable(ctx, id)
+	node, err := g.findHost(ctx, model.NewHashID(req.Key))
 	if err != nil {
 		log.Errorf("FindHostForKey failed. reason: %#v", err)
 		return nil, err
 	}
-	return &Node{
-		Host: s.Reference().Host,
+	return toNode(node), nil
+}
+
+func (g *ExternalServer) findHost(ctx context.Context, id model.HashID) (*model.NodeRef, error) {
+	var version uint64
+	if g.cache != nil {
+		// Read the version before the lookup, so that a change during the lookup invalidates its result.
+		version = g.process.RoutingVersion()
+		if node, ok := g.cache.get(id, version); ok {
+			return node, nil
+		}
+	}
+	s, err := g.process.FindSuccessorByTable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if g.cache != nil {
+		g.cache.put(id, s.Reference(), version)
+	}
+	return s.Reference(), nil
+}
+
+// reportNotOwner returns a quorum option which tells a client whether the node it expects to own a key,
+// resolved with its snapshot of the ring, doesn't own the key, so that the client refreshes the stale snapshot.
+// It compares the expected node with the owner the request finds for its preference list, without another lookup.
+func reportNotOwner(ctx context.Context) chord.QuorumOptionFunc {
+	md, _ := metadata.FromIncomingContext(ctx)
+	expected := md.Get(ExpectedOwnerHeader)
+	return chord.WithOwnerResolved(func(owner *model.NodeRef) {
+		if len(expected) == 0 || owner.Key() == expected[0] {
+			return
+		}
+		if err := grpc.SetHeader(ctx, metadata.Pairs(NotOwnerHeader, owner.Key())); err != nil {
+			log.Warnf("failed to report the owner of a key. err = %#v", err)
+		}
+	})
+}
+
+// FindReplicasForKey returns a given key's owner followed by live successors on distinct hosts, up to n hosts in total.
+// It is implemented for PublicService.
+func (g *ExternalServer) FindReplicasForKey(ctx context.Context, req *FindReplicasRequest) (*Replicas, error) {
+	n := int(req.N)
+	if n < 1 {
+		n = 1
+	}
+	replicas, err := g.process.FindReplicas(ctx, model.NewHashID(req.Key), n)
+	if err != nil {
+		log.Errorf("FindReplicasForKey failed. reason: %#v", err)
+		return nil, err
+	}
+	nodes := make([]*Node, len(replicas))
+	for i, replica := range replicas {
+		nodes[i] = toNode(replica.Reference())
+	}
+	return &Replicas{
+		Nodes: nodes,
 	}, nil
 }
+
+// Get reads a record of a key from replicas in the preference list of the key, repairing stale ones.
+// If the client tells the owner it expects, the response header reports whether it's wrong.
+// It is implemented for PublicService.
+func (g *ExternalServer) Get(ctx context.Context, req *GetRequest) (*Record, error) {
+	opts := toQuorumOptions(req.Consistency, req.N)
+	if req.R > 0 {
+		opts = append(opts, chord.WithReadQuorum(int(req.R)))
+	}
+	opts = append(opts, reportNotOwner(ctx))
+	record, err := g.process.Get(ctx, req.Key, opts...)
+	if err != nil {
+		log.Errorf("Get failed. reason: %#v", err)
+		return nil, toQuorumError(err)
+	}
+	return toRecord(record), nil
+}
+
+// Put writes a record to replicas in the preference list of its key, and returns the version it is written with.
+// A value with a TTL expires at an absolute time computed on this node.
+// If the client tells the owner it expects, the response header reports whether it's wrong.
+// It is implemented for PublicService.
+func (g *ExternalServer) Put(ctx context.Context, req *PutRequest) (*PutResponse, error) {
+	if req.Record == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "server: record is not set.")
+	}
+	opts := toQuorumOptions(req.Consistency, req.N)
+	if req.W > 0 {
+		opts = append(opts, chord.WithWriteQuorum(int(req.W)))
+	}
+	opts = append(opts, reportNotOwner(ctx))
+	record := toStorageRecord(req.Record)
+	if req.Ttl != nil {
+		ttl, err := ptypes.Duration(req.Ttl)
+		if err != nil || ttl <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "server: invalid ttl %v", req.Ttl)
+		}
+		// The expiry is absolute, so that it means the same on every replica.
+		record.ExpiresAt = storage.ExpiresAt(time.Now().Add(ttl))
+	}
+	version, err := g.process.Put(ctx, record, opts...)
+	if err != nil {
+		log.Errorf("Put failed. reason: %#v", err)
+		return nil, toQuorumError(err)
+	}
+	return &PutResponse{
+		Version: toVersion(version),
+	}, nil
+}
+
+func toQuorumOptions(consistency Consistency, n int32) []chord.QuorumOptionFunc {
+	var opts []chord.QuorumOptionFunc
+	switch consistency {
+	case Consistency_ONE:
+		opts = append(opts, chord.WithConsistency(chord.ConsistencyOne))
+	case Consistency_ALL:
+		opts = append(opts, chord.WithConsistency(chord.ConsistencyAll))
+	default:
+		opts = append(opts, chord.WithConsistency(chord.ConsistencyQuorum))
+	}
+	if n > 0 {
+		opts = append(opts, chord.WithReplicas(int(n)))
+	}
+	return opts
+}
+
+func toQuorumError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Errorf(codes.NotFound, "server: %v", err)
+	case errors.Is(err, chord.ErrInvalidQuorum), errors.Is(err, chord.ErrTimestampAhead):
+		return status.Errorf(codes.InvalidArgument, "server: %v", err)
+	case errors.Is(err, chord.ErrQuorumNotReached):
+		return status.Errorf(codes.Unavailable, "server: %v", err)
+	default:
+		return status.Errorf(codes.Internal, "server: %v", err)
+	}
+}
+
+// GetLookupCacheStats returns hit and miss counts of the cache of FindHostForKey.
+// It is implemented for PublicService.
+func (g *ExternalServer) GetLookupCacheStats(_ context.Context, _ *empty.Empty) (*LookupCacheStats, error) {
+	if g.cache == nil {
+		return &LookupCacheStats{}, nil
+	}
+	stats := g.cache.snapshot()
+	return &LookupCacheStats{
+		Enabled:       true,
+		Hits:          stats.hits,
+		Misses:        stats.misses,
+		Evictions:     stats.evictions,
+		Invalidations: stats.invalidations,
+		Size:          int32(stats.size),
+	}, nil
+}
+
+// GetRingSnapshot returns every node of the ring, walking along successor lists from this node.
+// It is implemented for PublicService.
+func (g *ExternalServer) GetRingSnapshot(ctx context.Context, _ *empty.Empty) (*RingSnapshot, error) {
+	ring, err := g.process.Ring(ctx)
+	if err != nil {
+		log.Errorf("GetRingSnapshot failed. reason: %#v", err)
+		return nil, status.Errorf(codes.Unavailable, "server: ring is unavailable.")
+	}
+	nodes := make([]*Node, len(ring))
+	for i, node := range ring {
+		nodes[i] = toNode(node.Reference())
+	}
+	return &RingSnapshot{
+		Nodes: nodes,
+	}, nil
+}
+
+// GetOwnership returns the key space which every virtual node on this host owns.
+// It is implemented for PublicService.
+func (g *ExternalServer) GetOwnership(ctx context.Context, _ *empty.Empty) (*Ownership, error) {
+	ownership := &Ownership{}
+	for _, vp := range g.process.VirtualNodes() {
+		pred, err := vp.GetPredecessor(ctx)
+		if err != nil || pred == nil {
+			log.Errorf("GetOwnership failed. reason: %#v", err)
+			return nil, status.Errorf(codes.Unavailable, "server: predecessor of virtual node %d is unavailable.", vp.VNode)
+		}
+		share, err := vp.LocalNode.OwnershipShare(ctx)
+		if err != nil {
+			log.Errorf("GetOwnership failed. reason: %#v", err)
+			return nil, status.Errorf(codes.Unavailable, "server: ownership of virtual node %d is unavailable.", vp.VNode)
+		}
+		ownership.Share += share
+		ownership.Ranges = append(ownership.Ranges, &Ownership_Range{
+			Node:      toNode(vp.NodeRef),
+			RangeFrom: pred.Reference().ID,
+			RangeTo:   vp.ID,
+			Share:     share,
+		})
+	}
+	return ownership, nil
+}
+
+// WatchRing streams ring membership changes observed by this node.
+// It is implemented for PublicService.
+func (g *ExternalServer) WatchRing(_ *empty.Empty, stream ExternalService_WatchRingServer) error {
+	ctx := stream.Context()
+	events := make(chan chord.RingEvent)
+	unwatch := g.process.Watch(func(event chord.RingEvent) {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	})
+	defer unwatch()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			if err := stream.Send(toRingEventProto(event)); err != nil {
+				log.Errorf("WatchRing failed. reason: %#v", err)
+				return err
+			}
+		}
+	}
+}
+
+func toRingEventProto(event chord.RingEvent) *RingEvent {
+	var eventType RingEvent_Type
+	switch event.Type {
+	case chord.PredecessorChanged:
+		eventType = RingEvent_PREDECESSOR_CHANGED
+	case chord.SuccessorAdded:
+		eventType = RingEvent_SUCCESSOR_ADDED
+	case chord.SuccessorRemoved:
+		eventType = RingEvent_SUCCESSOR_REMOVED
+	case chord.NodeDead:
+		eventType = RingEvent_NODE_DEAD
+	}
+	return &RingEvent{
+		Type:      eventType,
+		Node:      toNode(event.Node),
+		RangeFrom: event.From,
+		RangeTo:   event.To,
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"github.com/taisho6339/gord/storage"
+)
+
+func toRecord(record *storage.Record) *Record {
+	converted := &Record{
+		Key:       record.Key,
+		Value:     record.Value,
+		Version:   toVersion(record.Version),
+		ExpiresAt: record.ExpiresAt,
+	}
+	for _, sibling := range record.Siblings {
+		converted.Siblings = append(converted.Siblings, &Sibling{
+			Value:     sibling.Value,
+			Version:   toVersion(sibling.Version),
+			ExpiresAt: sibling.ExpiresAt,
+		})
+	}
+	return converted
+}
+
+func toStorageRecord(record *Record) *storage.Record {
+	converted := &storage.Record{
+		Key:       record.Key,
+		Value:     record.Value,
+		Version:   toStorageVersion(record.Version),
+		ExpiresAt: record.ExpiresAt,
+	}
+	for _, sibling := range record.Siblings {
+		converted.Siblings = append(converted.Siblings, &storage.Sibling{
+			Value:     sibling.Value,
+			Version:   toStorageVersion(sibling.Version),
+			ExpiresAt: sibling.ExpiresAt,
+		})
+	}
+	return converted
+}
+
+func toRecords(records []*storage.Record) []*Record {
+	converted := make([]*Record, len(records))
+	for i, record := range records {
+		converted[i] = toRecord(record)
+	}
+	return converted
+}
+
+func toStorageRecords(records []*Record) []*storage.Record {
+	converted := make([]*storage.Record, len(records))
+	for i, record := range records {
+		converted[i] = toStorageRecord(record)
+	}
+	return converted
+}
+
+func toVersion(version *storage.Version) *Version {
+	if version == nil {
+		return nil
+	}
+	return &Version{
+		Clock:     version.Clock,
+		Timestamp: version.Timestamp,
+	}
+}
+
+func toStorageVersion(version *Version) *storage.Version {
+	if version == nil {
+		return nil
+	}
+	return &storage.Version{
+		Clock:     version.Clock,
+		Timestamp: version.Timestamp,
+	}
+}
+
+func toIndexes(indexes []int) []int32 {
+	converted := make([]int32, len(indexes))
+	for i, index := range indexes {
+		converted[i] = int32(index)
+	}
+	return converted
+}
+
+func fromIndexes(indexes []int32) []int {
+	converted := make([]int, len(indexes))
+	for i, index := range indexes {
+		converted[i] = int(index)
+	}
+	return converted
+}
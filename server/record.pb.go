@@ -0,0 +1,275 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: record.proto
+
+package server
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// Version represents the causal history of a value.
+type Version struct {
+	// clock maps each node having coordinated writes of the key to the timestamp of its latest write.
+	Clock map[string]uint64 `protobuf:"bytes,1,rep,name=clock,proto3" json:"clock,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// timestamp is the hybrid logical clock timestamp of the write.
+	Timestamp            uint64   `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Version) Reset()         { *m = Version{} }
+func (m *Version) String() string { return proto.CompactTextString(m) }
+func (*Version) ProtoMessage()    {}
+func (*Version) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bf94fd919e302a1d, []int{0}
+}
+
+func (m *Version) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Version.Unmarshal(m, b)
+}
+func (m *Version) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Version.Marshal(b, m, deterministic)
+}
+func (m *Version) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Version.Merge(m, src)
+}
+func (m *Version) XXX_Size() int {
+	return xxx_messageInfo_Version.Size(m)
+}
+func (m *Version) XXX_DiscardUnknown() {
+	xxx_messageInfo_Version.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Version proto.InternalMessageInfo
+
+func (m *Version) GetClock() map[string]uint64 {
+	if m != nil {
+		return m.Clock
+	}
+	return nil
+}
+
+func (m *Version) GetTimestamp() uint64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+// Sibling represents a value written concurrently with the value of a record.
+type Sibling struct {
+	Value                []byte   `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Version              *Version `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	ExpiresAt            int64    `protobuf:"varint,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Sibling) Reset()         { *m = Sibling{} }
+func (m *Sibling) String() string { return proto.CompactTextString(m) }
+func (*Sibling) ProtoMessage()    {}
+func (*Sibling) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bf94fd919e302a1d, []int{1}
+}
+
+func (m *Sibling) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Sibling.Unmarshal(m, b)
+}
+func (m *Sibling) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Sibling.Marshal(b, m, deterministic)
+}
+func (m *Sibling) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Sibling.Merge(m, src)
+}
+func (m *Sibling) XXX_Size() int {
+	return xxx_messageInfo_Sibling.Size(m)
+}
+func (m *Sibling) XXX_DiscardUnknown() {
+	xxx_messageInfo_Sibling.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Sibling proto.InternalMessageInfo
+
+func (m *Sibling) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Sibling) GetVersion() *Version {
+	if m != nil {
+		return m.Version
+	}
+	return nil
+}
+
+func (m *Sibling) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+type Record struct {
+	Key     string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value   []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Version *Version `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	// expires_at is when the value expires in unix milliseconds. 0 means it never expires.
+	ExpiresAt int64 `protobuf:"varint,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// siblings are kept only if nodes keep concurrent values. Write a value with a context descending from every sibling to resolve them.
+	Siblings             []*Sibling `protobuf:"bytes,4,rep,name=siblings,proto3" json:"siblings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *Record) Reset()         { *m = Record{} }
+func (m *Record) String() string { return proto.CompactTextString(m) }
+func (*Record) ProtoMessage()    {}
+func (*Record) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bf94fd919e302a1d, []int{2}
+}
+
+func (m *Record) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Record.Unmarshal(m, b)
+}
+func (m *Record) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Record.Marshal(b, m, deterministic)
+}
+func (m *Record) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Record.Merge(m, src)
+}
+func (m *Record) XXX_Size() int {
+	return xxx_messageInfo_Record.Size(m)
+}
+func (m *Record) XXX_DiscardUnknown() {
+	xxx_messageInfo_Record.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Record proto.InternalMessageInfo
+
+func (m *Record) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Record) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Record) GetVersion() *Version {
+	if m != nil {
+		return m.Version
+	}
+	return nil
+}
+
+func (m *Record) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *Record) GetSiblings() []*Sibling {
+	if m != nil {
+		return m.Siblings
+	}
+	return nil
+}
+
+type Records struct {
+	Records              []*Record `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Records) Reset()         { *m = Records{} }
+func (m *Records) String() string { return proto.CompactTextString(m) }
+func (*Records) ProtoMessage()    {}
+func (*Records) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bf94fd919e302a1d, []int{3}
+}
+
+func (m *Records) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Records.Unmarshal(m, b)
+}
+func (m *Records) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Records.Marshal(b, m, deterministic)
+}
+func (m *Records) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Records.Merge(m, src)
+}
+func (m *Records) XXX_Size() int {
+	return xxx_messageInfo_Records.Size(m)
+}
+func (m *Records) XXX_DiscardUnknown() {
+	xxx_messageInfo_Records.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Records proto.InternalMessageInfo
+
+func (m *Records) GetRecords() []*Record {
+	if m != nil {
+		return m.Records
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Version)(nil), "server.Version")
+	proto.RegisterMapType((map[string]uint64)(nil), "server.Version.ClockEntry")
+	proto.RegisterType((*Sibling)(nil), "server.Sibling")
+	proto.RegisterType((*Record)(nil), "server.Record")
+	proto.RegisterType((*Records)(nil), "server.Records")
+}
+
+func init() {
+	proto.RegisterFile("record.proto", fileDescriptor_bf94fd919e302a1d)
+}
+
+var fileDescriptor_bf94fd919e302a1d = []byte{
+	// 306 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x92, 0xd1, 0x4a, 0xc3, 0x30,
+	0x14, 0x86, 0xc9, 0xb2, 0xad, 0xee, 0x6c, 0xa8, 0x04, 0x2f, 0xca, 0x50, 0xa8, 0xf5, 0xa6, 0x22,
+	0x74, 0xb2, 0x82, 0x4c, 0xef, 0x54, 0x7c, 0x81, 0x08, 0x5e, 0x78, 0x23, 0x5d, 0x17, 0xba, 0xb0,
+	0xb6, 0x29, 0x49, 0x56, 0xdc, 0x73, 0xf8, 0x10, 0xbe, 0xa6, 0xb4, 0x49, 0xad, 0x0c, 0x41, 0xbc,
+	0x6b, 0xcf, 0xff, 0x7f, 0x39, 0xff, 0xc9, 0x09, 0x4c, 0x24, 0x4b, 0x84, 0x5c, 0x85, 0xa5, 0x14,
+	0x5a, 0x90, 0xa1, 0x62, 0xb2, 0x62, 0xd2, 0xff, 0x40, 0xe0, 0xbc, 0x30, 0xa9, 0xb8, 0x28, 0xc8,
+	0x35, 0x0c, 0x92, 0x4c, 0x24, 0x1b, 0x17, 0x79, 0x38, 0x18, 0xcf, 0xa7, 0xa1, 0xf1, 0x84, 0x56,
+	0x0f, 0x1f, 0x6b, 0xf1, 0xa9, 0xd0, 0x72, 0x47, 0x8d, 0x91, 0x9c, 0xc2, 0x48, 0xf3, 0x9c, 0x29,
+	0x1d, 0xe7, 0xa5, 0xdb, 0xf3, 0x50, 0xd0, 0xa7, 0x5d, 0x61, 0xba, 0x00, 0xe8, 0x10, 0x72, 0x0c,
+	0x78, 0xc3, 0x76, 0x2e, 0xf2, 0x50, 0x30, 0xa2, 0xf5, 0x27, 0x39, 0x81, 0x41, 0x15, 0x67, 0x5b,
+	0x66, 0x49, 0xf3, 0x73, 0xd7, 0x5b, 0x20, 0x9f, 0x83, 0xf3, 0xcc, 0x97, 0x19, 0x2f, 0xd2, 0xce,
+	0x54, 0x83, 0x13, 0x6b, 0x22, 0x97, 0xe0, 0x54, 0x26, 0x55, 0x03, 0x8f, 0xe7, 0x47, 0x7b, 0x61,
+	0x69, 0xab, 0x93, 0x33, 0x00, 0xf6, 0x5e, 0x72, 0xc9, 0xd4, 0x5b, 0xac, 0x5d, 0xec, 0xa1, 0x00,
+	0xd3, 0x91, 0xad, 0xdc, 0x6b, 0xff, 0x13, 0xc1, 0x90, 0x36, 0x37, 0xf3, 0x57, 0xc2, 0xdf, 0x9a,
+	0xe3, 0x7f, 0x35, 0x1f, 0xec, 0x35, 0x27, 0x57, 0x70, 0xa0, 0xcc, 0x9c, 0xca, 0xed, 0x7b, 0xf8,
+	0xe7, 0x51, 0x76, 0x7e, 0xfa, 0x6d, 0xf0, 0x23, 0x70, 0x4c, 0x50, 0x45, 0x02, 0x70, 0xcc, 0x36,
+	0x95, 0xdd, 0xd5, 0x61, 0x8b, 0x19, 0x07, 0x6d, 0xe5, 0x87, 0x8b, 0xd7, 0xf3, 0x94, 0xeb, 0xf5,
+	0x76, 0x19, 0x26, 0x22, 0x9f, 0xe9, 0x98, 0xab, 0xb5, 0xb8, 0x89, 0xa2, 0xdb, 0x59, 0x2a, 0xe4,
+	0x6a, 0x66, 0xa0, 0xe5, 0xb0, 0x79, 0x13, 0xd1, 0xd7, 0x00, 0xf8, 0xe0, 0x1a, 0x40, 0x23, 0x02,
+	0x00, 0x00,
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+const (
+	// recordFormatVersioned is the format of records with versions and siblings.
+	recordFormatVersioned byte = iota + 1
+	// recordFormatExpiring is the format which adds expiry to each value. Records are encoded in it.
+	recordFormatExpiring
+)
+
+// encodeRecord encodes the values of a record, each of which has its version and expiry.
+// Clocks are encoded in order of nodes, so the same record is always encoded to the same bytes.
+func encodeRecord(record *Record) []byte {
+	buf := []byte{recordFormatExpiring}
+	buf = appendValue(buf, record.Value, record.Version, record.ExpiresAt)
+	buf = appendUint32(buf, uint32(len(record.Siblings)))
+	for _, sibling := range record.Siblings {
+		buf = appendValue(buf, sibling.Value, sibling.Version, sibling.ExpiresAt)
+	}
+	return buf
+}
+
+func appendValue(buf []byte, value []byte, version *Version, expiresAt int64) []byte {
+	buf = appendBytes(buf, value)
+	buf = appendVersion(buf, version)
+	return appendUint64(buf, uint64(expiresAt))
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return append(buf, b...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return append(buf, b...)
+}
+
+func appendBytes(buf []byte, b []byte) []byte {
+	buf = appendUint32(buf, uint32(len(b)))
+	return append(buf, b...)
+}
+
+func appendVersion(buf []byte, version *Version) []byte {
+	if version == nil {
+		return append(buf, 0)
+	}
+	buf = append(buf, 1)
+	buf = appendUint64(buf, version.Timestamp)
+	nodes := make([]string, 0, len(version.Clock))
+	for node := range version.Clock {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	buf = appendUint32(buf, uint32(len(nodes)))
+	for _, node := range nodes {
+		buf = appendBytes(buf, []byte(node))
+		buf = appendUint64(buf, version.Clock[node])
+	}
+	return buf
+}
+
+// decoder reads fields of an encoded record. Once a field overruns the buffer, every read fails with ErrCorrupted.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil || n < 0 || n > len(d.buf) {
+		d.err = ErrCorrupted
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) bytes() []byte {
+	return d.next(int(d.uint32()))
+}
+
+func (d *decoder) version() *Version {
+	if present := d.next(1); present == nil || present[0] == 0 {
+		return nil
+	}
+	version := &Version{Timestamp: d.uint64(), Clock: map[string]uint64{}}
+	for n := d.uint32(); n > 0 && d.err == nil; n-- {
+		node := string(d.bytes())
+		version.Clock[node] = d.uint64()
+	}
+	return version
+}
+
+func (d *decoder) value(format byte) *Sibling {
+	value := &Sibling{Value: d.bytes(), Version: d.version()}
+	if format >= recordFormatExpiring {
+		value.ExpiresAt = int64(d.uint64())
+	}
+	return value
+}
+
+// decodeRecord decodes a record of key encoded by encodeRecord, or in an older format.
+func decodeRecord(key string, buf []byte) (*Record, error) {
+	d := &decoder{buf: buf}
+	format := d.next(1)
+	if format == nil || format[0] < recordFormatVersioned || format[0] > recordFormatExpiring {
+		return nil, ErrCorrupted
+	}
+	values := []*Sibling{d.value(format[0])}
+	for n := d.uint32(); n > 0 && d.err == nil; n-- {
+		values = append(values, d.value(format[0]))
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.buf) > 0 {
+		return nil, ErrCorrupted
+	}
+	return newRecord(key, values), nil
+}
@@ -0,0 +1,513 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/taisho6339/gord/pkg/model"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// opPut is an entry of a record encoded with its version and siblings.
+	opPut byte = iota + 1
+	opDelete
+)
+
+const (
+	// headerSize is the size of crc, op, key length and value length, which precede a key and a value in a log entry.
+	headerSize = 4 + 1 + 4 + 4
+	// maxEntrySize bounds an entry, so that a broken length in a header doesn't make a huge allocation.
+	maxEntrySize = 64 << 20
+)
+
+// location represents where the latest entry of a key lies in the log.
+type location struct {
+	offset int64
+	size   int64
+	// expiresAt is when every value of the record has expired, which the reaper finds expired records by.
+	expiresAt int64
+	// pruneAt is when the first value of the record expires, after which the reaper rewrites it without the value.
+	pruneAt int64
+	// digest is the digest of the record, which Merkle trees are updated with.
+	digest []byte
+}
+
+// logFile is the file of a log. It is *os.File except in tests, which inject failed writes.
+type logFile interface {
+	io.Writer
+	io.ReaderAt
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+}
+
+// DiskEngine keeps records in an append-only log file, and an index of the log in memory.
+// Every change is appended to the log, and the log is compacted when most of it is overwritten or deleted entries.
+// A broken tail of the log, left by a crash during a write, is truncated on open.
+type DiskEngine struct {
+	path string
+	file logFile
+	size int64
+	// failed is set when a failed write couldn't be undone, after which the engine refuses writes until it reopens the log.
+	failed    bool
+	live      int64
+	locations map[string]location
+	index     *keyIndex
+	trees     merkleTrees
+	closed    bool
+	lock      sync.RWMutex
+	now       func() time.Time
+
+	syncWrites        bool
+	compactionRatio   float64
+	minCompactionSize int64
+}
+
+// DiskOptionFunc represents options of a disk engine
+type DiskOptionFunc func(engine *DiskEngine)
+
+// WithSyncWrites makes a disk engine flush every write to the disk before returning.
+// It is durable against power loss, but much slower.
+func WithSyncWrites() DiskOptionFunc {
+	return func(engine *DiskEngine) {
+		engine.syncWrites = true
+	}
+}
+
+// WithCompaction makes a disk engine compact its log once it is larger than minSize
+// and more than ratio of it is overwritten or deleted entries.
+func WithCompaction(ratio float64, minSize int64) DiskOptionFunc {
+	return func(engine *DiskEngine) {
+		engine.compactionRatio = ratio
+		engine.minCompactionSize = minSize
+	}
+}
+
+// NewDiskEngine opens a log file at path, creating it if it doesn't exist.
+func NewDiskEngine(path string, opts ...DiskOptionFunc) (*DiskEngine, error) {
+	engine := &DiskEngine{
+		path:              path,
+		locations:         map[string]location{},
+		index:             &keyIndex{},
+		compactionRatio:   0.5,
+		minCompactionSize: 4 << 20,
+		now:               time.Now,
+	}
+	for _, opt := range opts {
+		opt(engine)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := engine.open(); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+func encodeEntry(op byte, key string, value []byte) []byte {
+	buf := make([]byte, headerSize+len(key)+len(value))
+	buf[4] = op
+	binary.BigEndian.PutUint32(buf[5:9], uint32(len(key)))
+	binary.BigEndian.PutUint32(buf[9:13], uint32(len(value)))
+	copy(buf[headerSize:], key)
+	copy(buf[headerSize+len(key):], value)
+	binary.BigEndian.PutUint32(buf[0:4], crc32.ChecksumIEEE(buf[4:]))
+	return buf
+}
+
+// decodeEntry reads an entry from r. It returns io.EOF only if r ends exactly before an entry.
+func decodeEntry(r io.Reader) (byte, *Record, int64, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, 0, err
+	}
+	keyLen := binary.BigEndian.Uint32(header[5:9])
+	valueLen := binary.BigEndian.Uint32(header[9:13])
+	if uint64(keyLen)+uint64(valueLen) > maxEntrySize-headerSize {
+		return 0, nil, 0, ErrCorrupted
+	}
+	body := make([]byte, int(keyLen)+int(valueLen))
+	if _, err := io.ReadFull(r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, 0, err
+	}
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(body)
+	if crc.Sum32() != binary.BigEndian.Uint32(header[0:4]) {
+		return 0, nil, 0, ErrCorrupted
+	}
+	op := header[4]
+	record := &Record{Key: string(body[:keyLen])}
+	switch op {
+	case opDelete:
+		// A delete has no value.
+	case opPut:
+		decoded, err := decodeRecord(record.Key, body[keyLen:])
+		if err != nil {
+			return 0, nil, 0, err
+		}
+		record = decoded
+	default:
+		return 0, nil, 0, ErrCorrupted
+	}
+	return op, record, int64(len(header) + len(body)), nil
+}
+
+// open opens the log and builds the index from it.
+func (d *DiskEngine) open() error {
+	file, err := os.OpenFile(d.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	d.file = file
+	d.failed = false
+	d.size, d.live = 0, 0
+	d.locations = map[string]location{}
+	d.index = &keyIndex{}
+	r := bufio.NewReader(file)
+	for {
+		op, record, n, err := decodeEntry(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err == io.ErrUnexpectedEOF || err == ErrCorrupted {
+			log.Warnf("truncated a broken tail of %s at %d. err = %v", d.path, d.size, err)
+			return file.Truncate(d.size)
+		}
+		if err != nil {
+			file.Close()
+			return err
+		}
+		d.apply(op, record, location{offset: d.size, size: n})
+		d.size += n
+	}
+}
+
+// apply updates the index with an entry of a record appended at loc.
+func (d *DiskEngine) apply(op byte, record *Record, loc location) {
+	key := record.Key
+	if old, ok := d.locations[key]; ok {
+		d.live -= old.size
+	} else if op != opDelete {
+		d.index.insert(key)
+	}
+	if op == opDelete {
+		delete(d.locations, key)
+		d.index.remove(key)
+		return
+	}
+	loc.expiresAt = record.expiresAt()
+	loc.pruneAt = record.pruneAt()
+	loc.digest = record.Digest()
+	d.locations[key] = loc
+	d.live += loc.size
+}
+
+func (d *DiskEngine) append(op byte, record *Record) error {
+	if d.failed {
+		return ErrFailed
+	}
+	var value []byte
+	if op == opPut {
+		value = encodeRecord(record)
+	}
+	if headerSize+len(record.Key)+len(value) > maxEntrySize {
+		return ErrTooLarge
+	}
+	entry := encodeEntry(op, record.Key, value)
+	if _, err := d.file.Write(entry); err != nil {
+		return d.undoAppend(err)
+	}
+	if d.syncWrites {
+		if err := d.file.Sync(); err != nil {
+			return d.undoAppend(err)
+		}
+	}
+	old := d.locations[record.Key].digest
+	d.apply(op, record, location{offset: d.size, size: int64(len(entry))})
+	d.size += int64(len(entry))
+	d.trees.update(record.Key, old, d.locations[record.Key].digest)
+	return nil
+}
+
+// undoAppend truncates what a failed write has left after the last entry, so that the next entry lies at d.size.
+// If the log can't be truncated, the engine refuses writes, since their offsets would be wrong.
+func (d *DiskEngine) undoAppend(err error) error {
+	if truncErr := d.file.Truncate(d.size); truncErr != nil {
+		log.Errorf("failed to truncate %s at %d after a failed write. refusing writes. err = %v", d.path, d.size, truncErr)
+		d.failed = true
+	}
+	return err
+}
+
+func (d *DiskEngine) read(loc location) (*Record, error) {
+	buf := make([]byte, loc.size)
+	if _, err := d.file.ReadAt(buf, loc.offset); err != nil {
+		return nil, err
+	}
+	_, record, _, err := decodeEntry(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at %d. err = %w", d.path, loc.offset, err)
+	}
+	return record, nil
+}
+
+// Get is implemented for Engine interface.
+func (d *DiskEngine) Get(key string) (*Record, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	if d.closed {
+		return nil, ErrClosed
+	}
+	now := d.now()
+	loc, ok := d.locations[key]
+	if !ok || expired(loc.expiresAt, now) {
+		return nil, ErrNotFound
+	}
+	record, err := d.read(loc)
+	if err != nil {
+		return nil, err
+	}
+	if record = record.unexpired(now); record == nil {
+		return nil, ErrNotFound
+	}
+	return record, nil
+}
+
+// Put is implemented for Engine interface.
+func (d *DiskEngine) Put(record *Record) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	if err := d.append(opPut, record); err != nil {
+		return err
+	}
+	d.maybeCompact()
+	return nil
+}
+
+// Update is implemented for Engine interface.
+func (d *DiskEngine) Update(key string, fn func(current *Record) *Record) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	var current *Record
+	if loc, ok := d.locations[key]; ok && !expired(loc.expiresAt, d.now()) {
+		record, err := d.read(loc)
+		if err != nil {
+			return err
+		}
+		current = record.unexpired(d.now())
+	}
+	updated := fn(current)
+	if updated == nil {
+		return nil
+	}
+	if err := d.append(opPut, updated); err != nil {
+		return err
+	}
+	d.maybeCompact()
+	return nil
+}
+
+// DeleteExpired is implemented for Engine interface.
+func (d *DiskEngine) DeleteExpired() (int, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.closed {
+		return 0, ErrClosed
+	}
+	now := d.now()
+	var keys, pruned []string
+	for key, loc := range d.locations {
+		switch {
+		case expired(loc.expiresAt, now):
+			keys = append(keys, key)
+		case expired(loc.pruneAt, now):
+			pruned = append(pruned, key)
+		}
+	}
+	for _, key := range pruned {
+		record, err := d.read(d.locations[key])
+		if err != nil {
+			return 0, err
+		}
+		if err := d.append(opPut, record.unexpired(now)); err != nil {
+			return 0, err
+		}
+	}
+	for _, key := range keys {
+		if err := d.append(opDelete, &Record{Key: key}); err != nil {
+			return 0, err
+		}
+	}
+	d.maybeCompact()
+	return len(keys), nil
+}
+
+// Delete is implemented for Engine interface.
+func (d *DiskEngine) Delete(key string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	if _, ok := d.locations[key]; !ok {
+		return nil
+	}
+	if err := d.append(opDelete, &Record{Key: key}); err != nil {
+		return err
+	}
+	d.maybeCompact()
+	return nil
+}
+
+// Range is implemented for Engine interface.
+func (d *DiskEngine) Range(from model.HashID, to model.HashID, fn func(record *Record) bool) error {
+	d.lock.RLock()
+	if d.closed {
+		d.lock.RUnlock()
+		return ErrClosed
+	}
+	now := d.now()
+	keys := d.index.keys(from, to)
+	records := make([]*Record, 0, len(keys))
+	for _, key := range keys {
+		loc := d.locations[key]
+		if expired(loc.expiresAt, now) {
+			continue
+		}
+		record, err := d.read(loc)
+		if err != nil {
+			d.lock.RUnlock()
+			return err
+		}
+		if record = record.unexpired(now); record != nil {
+			records = append(records, record)
+		}
+	}
+	d.lock.RUnlock()
+	for _, record := range records {
+		if !fn(record) {
+			break
+		}
+	}
+	return nil
+}
+
+// MerkleTree is implemented for Engine interface.
+func (d *DiskEngine) MerkleTree(from model.HashID, to model.HashID, depth int) (*MerkleTree, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.closed {
+		return nil, ErrClosed
+	}
+	return d.trees.get(from, to, depth, d.now(), func(fn func(id model.HashID, digest []byte)) {
+		for _, key := range d.index.keys(from, to) {
+			fn(model.NewHashID(key), d.locations[key].digest)
+		}
+	}), nil
+}
+
+func (d *DiskEngine) maybeCompact() {
+	garbage := d.size - d.live
+	if d.compactionRatio <= 0 || d.size < d.minCompactionSize || float64(garbage) <= d.compactionRatio*float64(d.size) {
+		return
+	}
+	if err := d.compact(); err != nil {
+		log.Errorf("failed to compact %s. err = %v", d.path, err)
+	}
+}
+
+// Compact rewrites the log with only the latest entries of stored keys. Expired records are dropped.
+func (d *DiskEngine) Compact() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	return d.compact()
+}
+
+// compact writes live entries to a new file, and replaces the log with it.
+// The old log stays intact until the rename, so a crash during compaction loses nothing.
+func (d *DiskEngine) compact() error {
+	tmpPath := d.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	now := d.now()
+	// dropped are digests of expired records, which are removed from Merkle trees once the new log is open.
+	dropped := map[string][]byte{}
+	for _, entry := range d.index.entries {
+		if loc := d.locations[entry.key]; expired(loc.expiresAt, now) {
+			dropped[entry.key] = loc.digest
+			continue
+		}
+		record, err := d.read(d.locations[entry.key])
+		if err == nil {
+			_, err = w.Write(encodeEntry(opPut, record.Key, encodeRecord(record)))
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, d.path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	d.file.Close()
+	if err := d.open(); err != nil {
+		return err
+	}
+	for key, digest := range dropped {
+		d.trees.update(key, digest, nil)
+	}
+	return nil
+}
+
+// Close is implemented for Engine interface.
+func (d *DiskEngine) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	d.trees.clear()
+	return d.file.Close()
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"github.com/taisho6339/gord/pkg/model"
+	"time"
+)
+
+// Record represents a value stored under a key.
+type Record struct {
+	Key   string
+	Value []byte
+	// Version is the version of Value. It is nil if the value was written without a version.
+	Version *Version
+	// ExpiresAt is when Value expires in unix milliseconds. 0 means it never expires.
+	// It is absolute, so that it means the same on every replica the record is copied to.
+	ExpiresAt int64
+	// Siblings are values written concurrently with Value, which are kept under the KeepSiblings policy.
+	Siblings []*Sibling
+}
+
+// ID returns the position of a record on the ring, which decides the node owning it.
+func (r *Record) ID() model.HashID {
+	return model.NewHashID(r.Key)
+}
+
+// ExpiresAt returns ExpiresAt of a value expiring at t.
+func ExpiresAt(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func expired(expiresAt int64, now time.Time) bool {
+	return expiresAt > 0 && expiresAt <= ExpiresAt(now)
+}
+
+// unexpired returns a record without values expired at now, or nil if every value has expired.
+func (r *Record) unexpired(now time.Time) *Record {
+	values := r.values()
+	kept := make([]*Sibling, 0, len(values))
+	for _, value := range values {
+		if !expired(value.ExpiresAt, now) {
+			kept = append(kept, value)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return nil
+	case len(values):
+		return r
+	default:
+		return newRecord(r.Key, kept)
+	}
+}
+
+// pruneAt returns when the first value of a record expires, or 0 if none of them expires.
+func (r *Record) pruneAt() int64 {
+	var earliest int64
+	for _, value := range r.values() {
+		if value.ExpiresAt > 0 && (earliest == 0 || value.ExpiresAt < earliest) {
+			earliest = value.ExpiresAt
+		}
+	}
+	return earliest
+}
+
+// expiresAt returns when every value of a record has expired, or 0 if any of them never expires.
+func (r *Record) expiresAt() int64 {
+	var latest int64
+	for _, value := range r.values() {
+		if value.ExpiresAt == 0 {
+			return 0
+		}
+		if value.ExpiresAt > latest {
+			latest = value.ExpiresAt
+		}
+	}
+	return latest
+}
+
+// Engine stores records which a node holds.
+// Records are identified by their keys, and ordered by the IDs of their keys, since nodes own ranges of IDs.
+// Values are expired lazily. Reads and updates don't see expired values, and DeleteExpired removes them.
+// Engines must be safe for concurrent use.
+type Engine interface {
+	// Get returns the record of key. It returns ErrNotFound if the key isn't stored.
+	Get(key string) (*Record, error)
+	// Put stores a record, replacing the record of the same key.
+	Put(record *Record) error
+	// Update replaces the record of key with the one fn returns, atomically.
+	// fn is given the current record, or nil if the key isn't stored. If fn returns nil, nothing is changed.
+	Update(key string, fn func(current *Record) *Record) error
+	// DeleteExpired removes expired values, and records whose every value has expired.
+	// It returns how many records it has removed.
+	DeleteExpired() (int, error)
+	// Delete removes the record of key. Deleting a key which isn't stored is not an error.
+	Delete(key string) error
+	// Range calls fn for records whose IDs are in (from, to] in ring order, until fn returns false.
+	// If from equals to, it iterates the whole ring. fn may modify the engine.
+	Range(from model.HashID, to model.HashID, fn func(record *Record) bool) error
+	// MerkleTree returns a tree of records whose IDs are in (from, to], whose leaves are at depth.
+	// The tree is kept up to date on writes once it has been asked for, so the range is scanned only the first time.
+	// Expired values are in the tree until DeleteExpired removes them.
+	MerkleTree(from model.HashID, to model.HashID, depth int) (*MerkleTree, error)
+	// Close releases resources of the engine. Operations after Close fail with ErrClosed.
+	Close() error
+}
@@ -0,0 +1,365 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/pkg/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func newTestEngines(t *testing.T) (map[string]Engine, func()) {
+	dir, err := ioutil.TempDir("", "gord")
+	assert.Nil(t, err)
+	disk, err := NewDiskEngine(filepath.Join(dir, "data.log"))
+	assert.Nil(t, err)
+	engines := map[string]Engine{
+		"memory": NewMemoryEngine(),
+		"disk":   disk,
+	}
+	return engines, func() {
+		for _, engine := range engines {
+			engine.Close()
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+// sortedIDs returns IDs of keys in ring order.
+func sortedIDs(keys []string) []model.HashID {
+	ids := make([]model.HashID, len(keys))
+	for i, key := range keys {
+		ids[i] = model.NewHashID(key)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].LessThan(ids[j])
+	})
+	return ids
+}
+
+func collect(t *testing.T, engine Engine, from model.HashID, to model.HashID) []model.HashID {
+	var ids []model.HashID
+	assert.Nil(t, engine.Range(from, to, func(record *Record) bool {
+		ids = append(ids, record.ID())
+		return true
+	}))
+	return ids
+}
+
+func TestEngine_GetPutDelete(t *testing.T) {
+	engines, cleanup := newTestEngines(t)
+	defer cleanup()
+	for name, engine := range engines {
+		_, err := engine.Get("key")
+		assert.Equal(t, ErrNotFound, err, name)
+
+		assert.Nil(t, engine.Put(&Record{Key: "key", Value: []byte("value1")}), name)
+		assert.Nil(t, engine.Put(&Record{Key: "key", Value: []byte("value2")}), name)
+		record, err := engine.Get("key")
+		assert.Nil(t, err, name)
+		assert.Equal(t, []byte("value2"), record.Value, name)
+
+		assert.Nil(t, engine.Delete("key"), name)
+		assert.Nil(t, engine.Delete("key"), name)
+		_, err = engine.Get("key")
+		assert.Equal(t, ErrNotFound, err, name)
+
+		assert.Nil(t, engine.Close(), name)
+		_, err = engine.Get("key")
+		assert.Equal(t, ErrClosed, err, name)
+	}
+}
+
+func TestEngine_Update(t *testing.T) {
+	engines, cleanup := newTestEngines(t)
+	defer cleanup()
+	for name, engine := range engines {
+		appendValue := func(current *Record) *Record {
+			if current == nil {
+				return &Record{Key: "key", Value: []byte("a")}
+			}
+			return &Record{Key: "key", Value: append(current.Value, 'a')}
+		}
+		assert.Nil(t, engine.Update("key", appendValue), name)
+		assert.Nil(t, engine.Update("key", appendValue), name)
+		assert.Nil(t, engine.Update("key", func(current *Record) *Record {
+			return nil
+		}), name)
+		record, err := engine.Get("key")
+		assert.Nil(t, err, name)
+		assert.Equal(t, []byte("aa"), record.Value, name)
+		assert.Len(t, collect(t, engine, model.NewHashID("key"), model.NewHashID("key")), 1, name)
+	}
+}
+
+func TestEngine_Expiry(t *testing.T) {
+	engines, cleanup := newTestEngines(t)
+	defer cleanup()
+	now := time.Unix(1000, 0)
+	clock := func() time.Time {
+		return now
+	}
+	for name, engine := range engines {
+		switch e := engine.(type) {
+		case *MemoryEngine:
+			e.now = clock
+		case *DiskEngine:
+			e.now = clock
+		}
+		later := ExpiresAt(now.Add(time.Minute))
+		assert.Nil(t, engine.Put(&Record{Key: "session", Value: []byte("value"), ExpiresAt: later}), name)
+		assert.Nil(t, engine.Put(&Record{Key: "permanent", Value: []byte("value")}), name)
+		// Only the sibling expires.
+		assert.Nil(t, engine.Put(&Record{
+			Key:       "siblings",
+			Value:     []byte("value"),
+			ExpiresAt: ExpiresAt(now.Add(time.Hour)),
+			Siblings:  []*Sibling{{Value: []byte("sibling"), ExpiresAt: later}},
+		}), name)
+		assert.Len(t, collect(t, engine, model.NewHashID("a"), model.NewHashID("a")), 3, name)
+
+		now = now.Add(time.Minute)
+		_, err := engine.Get("session")
+		assert.Equal(t, ErrNotFound, err, name)
+		record, err := engine.Get("siblings")
+		assert.Nil(t, err, name)
+		assert.Len(t, record.Siblings, 0, name)
+		assert.Len(t, collect(t, engine, model.NewHashID("a"), model.NewHashID("a")), 2, name)
+		assert.Nil(t, engine.Update("session", func(current *Record) *Record {
+			assert.Nil(t, current, name)
+			return nil
+		}), name)
+
+		deleted, err := engine.DeleteExpired()
+		assert.Nil(t, err, name)
+		assert.Equal(t, 1, deleted, name)
+		deleted, err = engine.DeleteExpired()
+		assert.Nil(t, err, name)
+		assert.Equal(t, 0, deleted, name)
+		now = time.Unix(1000, 0)
+		_, err = engine.Get("session")
+		assert.Equal(t, ErrNotFound, err, name)
+	}
+}
+
+func TestEngine_Range(t *testing.T) {
+	engines, cleanup := newTestEngines(t)
+	defer cleanup()
+	keys := make([]string, 10)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	ids := sortedIDs(keys)
+	for name, engine := range engines {
+		for _, key := range keys {
+			assert.Nil(t, engine.Put(&Record{Key: key}), name)
+		}
+		// (ids[2], ids[5]]
+		assert.Equal(t, ids[3:6], collect(t, engine, ids[2], ids[5]), name)
+		// The range wraps around the ring.
+		assert.Equal(t, append(append([]model.HashID{}, ids[8:]...), ids[:2]...), collect(t, engine, ids[7], ids[1]), name)
+		// The whole ring, starting after from.
+		assert.Equal(t, append(append([]model.HashID{}, ids[5:]...), ids[:5]...), collect(t, engine, ids[4], ids[4]), name)
+
+		// fn may modify the engine, and iteration stops once fn returns false.
+		count := 0
+		assert.Nil(t, engine.Range(ids[0], ids[0], func(record *Record) bool {
+			assert.Nil(t, engine.Delete(record.Key))
+			count++
+			return count < 3
+		}), name)
+		assert.Len(t, collect(t, engine, ids[0], ids[0]), 7, name)
+	}
+}
+
+func TestDiskEngine_Get_ExpiresDuringRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gord")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	engine, err := NewDiskEngine(filepath.Join(dir, "data.log"))
+	assert.Nil(t, err)
+	defer engine.Close()
+
+	now := time.Unix(1000, 0)
+	assert.Nil(t, engine.Put(&Record{Key: "session", Value: []byte("value"), ExpiresAt: ExpiresAt(now.Add(time.Second))}))
+	// The clock passes the expiry on every reading, so a record is checked against a single reading.
+	engine.now = func() time.Time {
+		current := now
+		now = now.Add(time.Second)
+		return current
+	}
+	record, err := engine.Get("session")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), record.Value)
+	_, err = engine.Get("session")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestDiskEngine_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gord")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "data.log")
+
+	engine, err := NewDiskEngine(path)
+	assert.Nil(t, err)
+	assert.Nil(t, engine.Put(&Record{Key: "key1", Value: []byte("value1")}))
+	assert.Nil(t, engine.Put(&Record{Key: "key2", Value: []byte("value2")}))
+	assert.Nil(t, engine.Delete("key1"))
+	assert.Nil(t, engine.Close())
+
+	// A crash during a write leaves a broken entry at the tail.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	assert.Nil(t, err)
+	entry := encodeEntry(opPut, "key3", encodeRecord(&Record{Key: "key3", Value: []byte("value3")}))
+	_, err = file.Write(entry[:len(entry)-1])
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	engine, err = NewDiskEngine(path)
+	assert.Nil(t, err)
+	defer engine.Close()
+	_, err = engine.Get("key1")
+	assert.Equal(t, ErrNotFound, err)
+	_, err = engine.Get("key3")
+	assert.Equal(t, ErrNotFound, err)
+	record, err := engine.Get("key2")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value2"), record.Value)
+
+	// Writes after the truncated tail are readable.
+	assert.Nil(t, engine.Put(&Record{Key: "key3", Value: []byte("value3")}))
+	record, err = engine.Get("key3")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value3"), record.Value)
+}
+
+// failingFile writes half of the next entry and fails, as a full disk does.
+type failingFile struct {
+	*os.File
+	failWrite    bool
+	failTruncate bool
+}
+
+func (f *failingFile) Write(b []byte) (int, error) {
+	if !f.failWrite {
+		return f.File.Write(b)
+	}
+	f.failWrite = false
+	n, _ := f.File.Write(b[:len(b)/2])
+	return n, errors.New("no space left on device")
+}
+
+func (f *failingFile) Truncate(size int64) error {
+	if f.failTruncate {
+		return errors.New("truncate failed")
+	}
+	return f.File.Truncate(size)
+}
+
+func TestDiskEngine_FailedWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gord")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "data.log")
+
+	engine, err := NewDiskEngine(path)
+	assert.Nil(t, err)
+	assert.Nil(t, engine.Put(&Record{Key: "key1", Value: []byte("value1")}))
+	file := &failingFile{File: engine.file.(*os.File), failWrite: true}
+	engine.file = file
+	assert.NotNil(t, engine.Put(&Record{Key: "key2", Value: []byte("value2")}))
+	assert.Nil(t, engine.Put(&Record{Key: "key3", Value: []byte("value3")}))
+	assert.Nil(t, engine.Close())
+
+	// The half written entry is truncated, so the entries after it survive a reopen.
+	engine, err = NewDiskEngine(path)
+	assert.Nil(t, err)
+	for _, key := range []string{"key1", "key3"} {
+		record, err := engine.Get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("value"+key[3:]), record.Value)
+	}
+	_, err = engine.Get("key2")
+	assert.Equal(t, ErrNotFound, err)
+
+	// An engine which can't truncate the log refuses writes until it reopens the log.
+	file = &failingFile{File: engine.file.(*os.File), failWrite: true, failTruncate: true}
+	engine.file = file
+	assert.NotNil(t, engine.Put(&Record{Key: "key2", Value: []byte("value2")}))
+	assert.Equal(t, ErrFailed, engine.Put(&Record{Key: "key4", Value: []byte("value4")}))
+	assert.Nil(t, engine.Close())
+
+	engine, err = NewDiskEngine(path)
+	assert.Nil(t, err)
+	defer engine.Close()
+	assert.Nil(t, engine.Put(&Record{Key: "key4", Value: []byte("value4")}))
+	record, err := engine.Get("key4")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value4"), record.Value)
+	record, err = engine.Get("key3")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value3"), record.Value)
+}
+
+func TestDiskEngine_Versions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gord")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "data.log")
+
+	engine, err := NewDiskEngine(path)
+	assert.Nil(t, err)
+	assert.Nil(t, engine.Put(&Record{Key: "key1", Value: []byte("value1")}))
+	versioned := &Record{
+		Key:       "key2",
+		Value:     []byte("value2"),
+		Version:   &Version{Clock: map[string]uint64{"gord1": 2, "gord2": 1}, Timestamp: 2},
+		ExpiresAt: ExpiresAt(time.Now().Add(time.Hour)),
+		Siblings:  []*Sibling{{Value: []byte("sibling"), Version: &Version{Clock: map[string]uint64{"gord3": 1}, Timestamp: 1}}},
+	}
+	assert.Nil(t, engine.Put(versioned))
+	assert.Nil(t, engine.Close())
+
+	engine, err = NewDiskEngine(path)
+	assert.Nil(t, err)
+	defer engine.Close()
+	record, err := engine.Get("key1")
+	assert.Nil(t, err)
+	assert.Equal(t, &Record{Key: "key1", Value: []byte("value1")}, record)
+	record, err = engine.Get("key2")
+	assert.Nil(t, err)
+	assert.Equal(t, versioned, record)
+	assert.Equal(t, versioned.Digest(), record.Digest())
+}
+
+func TestDiskEngine_Compaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gord")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "data.log")
+
+	engine, err := NewDiskEngine(path, WithCompaction(0.5, 1024))
+	assert.Nil(t, err)
+	for i := 0; i < 1000; i++ {
+		assert.Nil(t, engine.Put(&Record{Key: fmt.Sprintf("key%d", i%10), Value: []byte(fmt.Sprintf("value%d", i))}))
+	}
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.True(t, info.Size() < 2048, "the log has %d bytes", info.Size())
+	assert.Nil(t, engine.Close())
+
+	engine, err = NewDiskEngine(path)
+	assert.Nil(t, err)
+	defer engine.Close()
+	for i := 990; i < 1000; i++ {
+		record, err := engine.Get(fmt.Sprintf("key%d", i%10))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("value%d", i)), record.Value)
+	}
+}
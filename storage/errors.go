@@ -0,0 +1,16 @@
+package storage
+
+import "errors"
+
+var (
+	// ErrNotFound represents a key is not stored
+	ErrNotFound = errors.New("NotFound")
+	// ErrClosed represents an engine has been closed
+	ErrClosed = errors.New("Closed")
+	// ErrTooLarge represents a record is larger than an engine can store
+	ErrTooLarge = errors.New("TooLarge")
+	// ErrCorrupted represents a log of a disk engine is broken
+	ErrCorrupted = errors.New("Corrupted")
+	// ErrFailed represents a disk engine refuses writes after a failed write it couldn't undo
+	ErrFailed = errors.New("Failed")
+)
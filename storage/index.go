@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"bytes"
+	"github.com/taisho6339/gord/pkg/model"
+	"sort"
+)
+
+type indexEntry struct {
+	id  model.HashID
+	key string
+}
+
+func (e indexEntry) less(id model.HashID, key string) bool {
+	if c := bytes.Compare(e.id, id); c != 0 {
+		return c < 0
+	}
+	return e.key < key
+}
+
+// keyIndex keeps keys sorted by their IDs, so that engines iterate ranges of IDs in ring order.
+type keyIndex struct {
+	entries []indexEntry
+}
+
+func (x *keyIndex) search(id model.HashID, key string) int {
+	return sort.Search(len(x.entries), func(i int) bool {
+		return !x.entries[i].less(id, key)
+	})
+}
+
+func (x *keyIndex) insert(key string) {
+	id := model.NewHashID(key)
+	i := x.search(id, key)
+	if i < len(x.entries) && x.entries[i].key == key {
+		return
+	}
+	x.entries = append(x.entries, indexEntry{})
+	copy(x.entries[i+1:], x.entries[i:])
+	x.entries[i] = indexEntry{id: id, key: key}
+}
+
+func (x *keyIndex) remove(key string) {
+	i := x.search(model.NewHashID(key), key)
+	if i < len(x.entries) && x.entries[i].key == key {
+		x.entries = append(x.entries[:i], x.entries[i+1:]...)
+	}
+}
+
+// keys returns keys whose IDs are in (from, to] in ring order.
+// If from equals to, it returns every key, starting after from.
+func (x *keyIndex) keys(from model.HashID, to model.HashID) []string {
+	n := len(x.entries)
+	if n == 0 {
+		return nil
+	}
+	start := sort.Search(n, func(i int) bool {
+		return bytes.Compare(x.entries[i].id, from) > 0
+	})
+	var keys []string
+	for k := 0; k < n; k++ {
+		entry := x.entries[(start+k)%n]
+		if !entry.id.Equals(to) && !entry.id.Between(from, to) {
+			break
+		}
+		keys = append(keys, entry.key)
+	}
+	return keys
+}
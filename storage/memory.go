@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"github.com/taisho6339/gord/pkg/model"
+	"sync"
+	"time"
+)
+
+// MemoryEngine keeps records in memory. Records are lost when the process exits.
+type MemoryEngine struct {
+	records map[string]*Record
+	index   *keyIndex
+	trees   merkleTrees
+	closed  bool
+	lock    sync.RWMutex
+	now     func() time.Time
+}
+
+// NewMemoryEngine creates an empty memory engine.
+func NewMemoryEngine() *MemoryEngine {
+	return &MemoryEngine{
+		records: map[string]*Record{},
+		index:   &keyIndex{},
+		now:     time.Now,
+	}
+}
+
+// Get is implemented for Engine interface.
+func (m *MemoryEngine) Get(key string) (*Record, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	record, ok := m.records[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	record = record.unexpired(m.now())
+	if record == nil {
+		return nil, ErrNotFound
+	}
+	copied := *record
+	return &copied, nil
+}
+
+// Put is implemented for Engine interface.
+func (m *MemoryEngine) Put(record *Record) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	copied := *record
+	old, ok := m.records[record.Key]
+	if !ok {
+		m.index.insert(record.Key)
+	}
+	m.records[record.Key] = &copied
+	m.updateTrees(record.Key, old, &copied)
+	return nil
+}
+
+// Update is implemented for Engine interface.
+func (m *MemoryEngine) Update(key string, fn func(current *Record) *Record) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	var current *Record
+	record, ok := m.records[key]
+	if ok {
+		current = record.unexpired(m.now())
+	}
+	if current != nil {
+		copied := *current
+		current = &copied
+	}
+	updated := fn(current)
+	if updated == nil {
+		return nil
+	}
+	if !ok {
+		m.index.insert(key)
+	}
+	copied := *updated
+	m.records[key] = &copied
+	m.updateTrees(key, record, &copied)
+	return nil
+}
+
+// DeleteExpired is implemented for Engine interface.
+func (m *MemoryEngine) DeleteExpired() (int, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return 0, ErrClosed
+	}
+	now := m.now()
+	deleted := 0
+	for key, record := range m.records {
+		unexpired := record.unexpired(now)
+		switch unexpired {
+		case record:
+			continue
+		case nil:
+			delete(m.records, key)
+			m.index.remove(key)
+			deleted++
+		default:
+			m.records[key] = unexpired
+		}
+		m.updateTrees(key, record, unexpired)
+	}
+	return deleted, nil
+}
+
+// Delete is implemented for Engine interface.
+func (m *MemoryEngine) Delete(key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	if record, ok := m.records[key]; ok {
+		delete(m.records, key)
+		m.index.remove(key)
+		m.updateTrees(key, record, nil)
+	}
+	return nil
+}
+
+// Range is implemented for Engine interface.
+func (m *MemoryEngine) Range(from model.HashID, to model.HashID, fn func(record *Record) bool) error {
+	m.lock.RLock()
+	if m.closed {
+		m.lock.RUnlock()
+		return ErrClosed
+	}
+	now := m.now()
+	keys := m.index.keys(from, to)
+	records := make([]Record, 0, len(keys))
+	for _, key := range keys {
+		if record := m.records[key].unexpired(now); record != nil {
+			records = append(records, *record)
+		}
+	}
+	m.lock.RUnlock()
+	for i := range records {
+		if !fn(&records[i]) {
+			break
+		}
+	}
+	return nil
+}
+
+// MerkleTree is implemented for Engine interface.
+func (m *MemoryEngine) MerkleTree(from model.HashID, to model.HashID, depth int) (*MerkleTree, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	return m.trees.get(from, to, depth, m.now(), func(fn func(id model.HashID, digest []byte)) {
+		for _, key := range m.index.keys(from, to) {
+			fn(model.NewHashID(key), m.records[key].Digest())
+		}
+	}), nil
+}
+
+// updateTrees replaces the digest of old with the digest of updated in Merkle trees.
+func (m *MemoryEngine) updateTrees(key string, old *Record, updated *Record) {
+	if m.trees.tracking() {
+		m.trees.update(key, digestOf(old), digestOf(updated))
+	}
+}
+
+// digestOf returns the digest of a record, or nil if the record doesn't exist.
+func digestOf(record *Record) []byte {
+	if record == nil {
+		return nil
+	}
+	return record.Digest()
+}
+
+// Close is implemented for Engine interface.
+func (m *MemoryEngine) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.closed = true
+	m.records = nil
+	m.index = &keyIndex{}
+	m.trees.clear()
+	return nil
+}
@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/taisho6339/gord/pkg/model"
+	"math/big"
+	"time"
+)
+
+const (
+	// maxMerkleTrees bounds trees an engine keeps up to date.
+	maxMerkleTrees = 64
+	// merkleTreeIdleTTL is how long an engine keeps updating a tree nobody asks for.
+	merkleTreeIdleTTL = time.Hour
+)
+
+// ringSize is the number of IDs on the ring.
+var ringSize = big.NewInt(0).Lsh(big.NewInt(1), model.BitSize)
+
+// Digest represents a hash of a record, which tells whether replicas of a key hold the same record.
+type Digest struct {
+	Key  string
+	Hash []byte
+}
+
+// Digest returns a hash of the key, the value, the version and the siblings of a record.
+func (r *Record) Digest() []byte {
+	h := sha256.New()
+	h.Write([]byte(r.Key))
+	h.Write([]byte{0})
+	h.Write(encodeRecord(r))
+	return h.Sum(nil)
+}
+
+// Digests returns digests of records whose IDs are in (from, to] in ring order.
+func Digests(engine Engine, from model.HashID, to model.HashID) ([]Digest, error) {
+	var digests []Digest
+	err := engine.Range(from, to, func(record *Record) bool {
+		digests = append(digests, Digest{Key: record.Key, Hash: record.Digest()})
+		return true
+	})
+	return digests, err
+}
+
+// MerkleTree summarizes records whose IDs are in (from, to].
+// The range is split into 2^depth buckets of equal width, each of which is a leaf of the tree.
+// A leaf is the XOR of digests of records in its bucket, so that a write updates it without reading the other records.
+// Replicas which build trees of the same range and depth can find buckets they disagree on by comparing hashes top down.
+type MerkleTree struct {
+	from  model.HashID
+	to    model.HashID
+	depth int
+	// levels[0] holds the root, and levels[depth] holds the leaves.
+	// Hashes are replaced rather than modified, so that a snapshot shares them.
+	levels [][][]byte
+}
+
+// newMerkleTree builds a tree of records in (from, to]. scan calls its argument with the ID and the digest of each record.
+func newMerkleTree(from model.HashID, to model.HashID, depth int, scan func(fn func(id model.HashID, digest []byte))) *MerkleTree {
+	tree := &MerkleTree{
+		from:   from,
+		to:     to,
+		depth:  depth,
+		levels: make([][][]byte, depth+1),
+	}
+	leaves := make([][]byte, 1<<depth)
+	for i := range leaves {
+		leaves[i] = make([]byte, sha256.Size)
+	}
+	scan(func(id model.HashID, digest []byte) {
+		xor(leaves[tree.leafIndex(id)], digest)
+	})
+	tree.levels[depth] = leaves
+	for level := depth - 1; level >= 0; level-- {
+		tree.levels[level] = make([][]byte, len(tree.levels[level+1])/2)
+		for i := range tree.levels[level] {
+			tree.levels[level][i] = tree.parent(level, i)
+		}
+	}
+	return tree
+}
+
+func xor(dst []byte, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
+
+// parent returns the hash of the node of index at a level from its children.
+func (t *MerkleTree) parent(level int, index int) []byte {
+	h := sha256.New()
+	h.Write(t.levels[level+1][2*index])
+	h.Write(t.levels[level+1][2*index+1])
+	return h.Sum(nil)
+}
+
+// contains returns whether id is in the range of the tree.
+func (t *MerkleTree) contains(id model.HashID) bool {
+	return id.Equals(t.to) || id.Between(t.from, t.to)
+}
+
+// update replaces the digest of a record of id, and the hashes on the path from its leaf to the root.
+// old or updated is nil if the record didn't exist, or has been deleted.
+func (t *MerkleTree) update(id model.HashID, old []byte, updated []byte) {
+	if !t.contains(id) {
+		return
+	}
+	index := t.leafIndex(id)
+	leaf := append([]byte{}, t.levels[t.depth][index]...)
+	xor(leaf, old)
+	xor(leaf, updated)
+	t.levels[t.depth][index] = leaf
+	for level := t.depth - 1; level >= 0; level-- {
+		index /= 2
+		t.levels[level][index] = t.parent(level, index)
+	}
+}
+
+// snapshot returns a copy of the tree, which later updates don't change.
+func (t *MerkleTree) snapshot() *MerkleTree {
+	copied := *t
+	copied.levels = make([][][]byte, len(t.levels))
+	for i, level := range t.levels {
+		copied.levels[i] = append([][]byte{}, level...)
+	}
+	return &copied
+}
+
+// width returns the number of IDs in (from, to]. The whole ring is in the range if from equals to.
+func (t *MerkleTree) width() *big.Int {
+	width := big.NewInt(0).Sub(big.NewInt(0).SetBytes(t.to), big.NewInt(0).SetBytes(t.from))
+	width.Mod(width, ringSize)
+	if width.Sign() == 0 {
+		width.Set(ringSize)
+	}
+	return width
+}
+
+// leafIndex returns the bucket of an ID in the range.
+func (t *MerkleTree) leafIndex(id model.HashID) int {
+	width := t.width()
+	offset := big.NewInt(0).Sub(big.NewInt(0).SetBytes(id), big.NewInt(0).SetBytes(t.from))
+	offset.Mod(offset, ringSize)
+	if offset.Sign() == 0 {
+		offset.Set(width)
+	}
+	// The bucket i holds offsets in (width*i/leaves, width*(i+1)/leaves].
+	index := offset.Mul(offset, big.NewInt(1<<t.depth))
+	index, rem := index.QuoRem(index, width, big.NewInt(0))
+	if rem.Sign() == 0 {
+		index.Sub(index, big.NewInt(1))
+	}
+	return int(index.Int64())
+}
+
+// bound returns the ID where the bucket of index starts, excluding the ID itself.
+func (t *MerkleTree) bound(index int) model.HashID {
+	offset := big.NewInt(0).Mul(t.width(), big.NewInt(int64(index)))
+	offset.Quo(offset, big.NewInt(1<<t.depth))
+	offset.Add(offset, big.NewInt(0).SetBytes(t.from))
+	offset.Mod(offset, ringSize)
+	return model.BytesToHashID(offset.Bytes())
+}
+
+// Depth returns the depth of leaves. The root is at level 0.
+func (t *MerkleTree) Depth() int {
+	return t.depth
+}
+
+// Hashes returns hashes of the nodes of indexes at a level.
+func (t *MerkleTree) Hashes(level int, indexes []int) ([][]byte, error) {
+	if level < 0 || level > t.depth {
+		return nil, fmt.Errorf("level %d is out of the tree of depth %d", level, t.depth)
+	}
+	hashes := make([][]byte, len(indexes))
+	for i, index := range indexes {
+		if index < 0 || index >= len(t.levels[level]) {
+			return nil, fmt.Errorf("index %d is out of level %d", index, level)
+		}
+		hashes[i] = t.levels[level][index]
+	}
+	return hashes, nil
+}
+
+// LeafRange returns the range of IDs (from, to] the leaf of index covers.
+func (t *MerkleTree) LeafRange(index int) (model.HashID, model.HashID) {
+	if index == (1<<t.depth)-1 {
+		return t.bound(index), t.to
+	}
+	return t.bound(index), t.bound(index + 1)
+}
+
+type trackedTree struct {
+	tree   *MerkleTree
+	usedAt time.Time
+}
+
+// merkleTrees keeps trees of ranges an engine has been asked for, and updates them on every write to the engine.
+// A range is scanned only when its tree is first asked for. Trees nobody has asked for in merkleTreeIdleTTL are dropped.
+type merkleTrees struct {
+	trees map[string]*trackedTree
+}
+
+// get returns a snapshot of the tree of (from, to], building it with scan if it isn't kept.
+func (m *merkleTrees) get(from model.HashID, to model.HashID, depth int, now time.Time, scan func(fn func(id model.HashID, digest []byte))) *MerkleTree {
+	if m.trees == nil {
+		m.trees = map[string]*trackedTree{}
+	}
+	var lru string
+	for key, tracked := range m.trees {
+		if now.Sub(tracked.usedAt) >= merkleTreeIdleTTL {
+			delete(m.trees, key)
+			continue
+		}
+		if lru == "" || tracked.usedAt.Before(m.trees[lru].usedAt) {
+			lru = key
+		}
+	}
+	key := fmt.Sprintf("%s-%s-%d", from, to, depth)
+	tracked, ok := m.trees[key]
+	if !ok {
+		if len(m.trees) >= maxMerkleTrees {
+			delete(m.trees, lru)
+		}
+		tracked = &trackedTree{tree: newMerkleTree(from, to, depth, scan)}
+		m.trees[key] = tracked
+	}
+	tracked.usedAt = now
+	return tracked.tree.snapshot()
+}
+
+// tracking returns whether any tree is kept, so that engines skip computing digests otherwise.
+func (m *merkleTrees) tracking() bool {
+	return len(m.trees) > 0
+}
+
+// update replaces the digest of a record of key in every tree.
+// old or updated is nil if the record didn't exist, or has been deleted.
+func (m *merkleTrees) update(key string, old []byte, updated []byte) {
+	if len(m.trees) == 0 {
+		return
+	}
+	id := model.NewHashID(key)
+	for _, tracked := range m.trees {
+		tracked.tree.update(id, old, updated)
+	}
+}
+
+// clear drops every tree.
+func (m *merkleTrees) clear() {
+	m.trees = nil
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/taisho6339/gord/pkg/model"
+	"testing"
+	"time"
+)
+
+func TestMerkleTree_LeafRange(t *testing.T) {
+	ranges := map[string][2]model.HashID{
+		"whole ring": {model.NewHashID("a"), model.NewHashID("a")},
+		"wrapped":    {model.NewHashID("a"), model.NewHashID("b")},
+		"reversed":   {model.NewHashID("b"), model.NewHashID("a")},
+	}
+	for name, r := range ranges {
+		tree, err := NewMemoryEngine().MerkleTree(r[0], r[1], 4)
+		assert.Nil(t, err, name)
+		for i := 0; i < 100; i++ {
+			id := model.NewHashID(fmt.Sprintf("key%d", i))
+			if !id.Equals(r[1]) && !id.Between(r[0], r[1]) {
+				continue
+			}
+			from, to := tree.LeafRange(tree.leafIndex(id))
+			assert.True(t, id.Equals(to) || id.Between(from, to), "%s: %s isn't in its leaf", name, id)
+		}
+		from, _ := tree.LeafRange(0)
+		_, to := tree.LeafRange(15)
+		assert.Equal(t, r[0], from, name)
+		assert.Equal(t, r[1], to, name)
+	}
+}
+
+func TestMerkleTree_Diff(t *testing.T) {
+	engine1, engine2 := NewMemoryEngine(), NewMemoryEngine()
+	for i := 0; i < 100; i++ {
+		record := &Record{Key: fmt.Sprintf("key%d", i), Value: []byte("value")}
+		assert.Nil(t, engine1.Put(record))
+		assert.Nil(t, engine2.Put(record))
+	}
+	from, to := model.NewHashID("from"), model.NewHashID("to")
+	tree1, err := engine1.MerkleTree(from, to, 4)
+	assert.Nil(t, err)
+	tree2, err := engine2.MerkleTree(from, to, 4)
+	assert.Nil(t, err)
+	root1, _ := tree1.Hashes(0, []int{0})
+	root2, _ := tree2.Hashes(0, []int{0})
+	assert.Equal(t, root1, root2)
+
+	// Find a key in the range, and change it on one replica.
+	var changed string
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if id := model.NewHashID(key); id.Equals(to) || id.Between(from, to) {
+			changed = key
+			break
+		}
+	}
+	assert.Nil(t, engine2.Put(&Record{Key: changed, Value: []byte("changed")}))
+	tree2, err = engine2.MerkleTree(from, to, 4)
+	assert.Nil(t, err)
+
+	var differing []int
+	leaves := make([]int, 16)
+	for i := range leaves {
+		leaves[i] = i
+	}
+	hashes1, err := tree1.Hashes(4, leaves)
+	assert.Nil(t, err)
+	hashes2, err := tree2.Hashes(4, leaves)
+	assert.Nil(t, err)
+	for i := range leaves {
+		if !bytes.Equal(hashes1[i], hashes2[i]) {
+			differing = append(differing, i)
+		}
+	}
+	assert.Equal(t, []int{tree1.leafIndex(model.NewHashID(changed))}, differing)
+
+	leafFrom, leafTo := tree1.LeafRange(differing[0])
+	digests1, err := Digests(engine1, leafFrom, leafTo)
+	assert.Nil(t, err)
+	digests2, err := Digests(engine2, leafFrom, leafTo)
+	assert.Nil(t, err)
+	assert.Equal(t, len(digests1), len(digests2))
+	for i := range digests1 {
+		assert.Equal(t, digests1[i].Key, digests2[i].Key)
+		assert.Equal(t, digests1[i].Key == changed, !bytes.Equal(digests1[i].Hash, digests2[i].Hash))
+	}
+
+	_, err = tree1.Hashes(5, []int{0})
+	assert.NotNil(t, err)
+	_, err = tree1.Hashes(4, []int{16})
+	assert.NotNil(t, err)
+}
+
+// scannedTree builds a tree of (from, to] by scanning an engine.
+func scannedTree(t *testing.T, engine Engine, from model.HashID, to model.HashID) *MerkleTree {
+	return newMerkleTree(from, to, 4, func(fn func(id model.HashID, digest []byte)) {
+		assert.Nil(t, engine.Range(from, to, func(record *Record) bool {
+			fn(record.ID(), record.Digest())
+			return true
+		}))
+	})
+}
+
+func TestEngine_MerkleTree_Incremental(t *testing.T) {
+	engines, cleanup := newTestEngines(t)
+	defer cleanup()
+	now := time.Unix(1000, 0)
+	clock := func() time.Time {
+		return now
+	}
+	from, to := model.NewHashID("from"), model.NewHashID("to")
+	for name, engine := range engines {
+		switch e := engine.(type) {
+		case *MemoryEngine:
+			e.now = clock
+		case *DiskEngine:
+			e.now = clock
+		}
+		later := ExpiresAt(now.Add(time.Minute))
+		for i := 0; i < 20; i++ {
+			record := &Record{Key: fmt.Sprintf("key%d", i), Value: []byte("value")}
+			switch i % 4 {
+			case 1:
+				record.ExpiresAt = later
+			case 2:
+				record.Siblings = []*Sibling{{Value: []byte("sibling"), ExpiresAt: later}}
+			}
+			assert.Nil(t, engine.Put(record), name)
+		}
+		tree, err := engine.MerkleTree(from, to, 4)
+		assert.Nil(t, err, name)
+		assert.Equal(t, scannedTree(t, engine, from, to).levels, tree.levels, name)
+
+		assert.Nil(t, engine.Put(&Record{Key: "key0", Value: []byte("changed")}), name)
+		assert.Nil(t, engine.Put(&Record{Key: "new", Value: []byte("value")}), name)
+		assert.Nil(t, engine.Update("key4", func(current *Record) *Record {
+			return &Record{Key: "key4", Value: append(current.Value, '!')}
+		}), name)
+		assert.Nil(t, engine.Delete("key8"), name)
+		updated, err := engine.MerkleTree(from, to, 4)
+		assert.Nil(t, err, name)
+		assert.Equal(t, scannedTree(t, engine, from, to).levels, updated.levels, name)
+		// A tree returned before is a snapshot.
+		assert.NotEqual(t, updated.levels[0], tree.levels[0], name)
+
+		now = now.Add(time.Minute)
+		if disk, ok := engine.(*DiskEngine); ok {
+			assert.Nil(t, disk.Compact(), name)
+		}
+		_, err = engine.DeleteExpired()
+		assert.Nil(t, err, name)
+		updated, err = engine.MerkleTree(from, to, 4)
+		assert.Nil(t, err, name)
+		assert.Equal(t, scannedTree(t, engine, from, to).levels, updated.levels, name)
+	}
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Version represents the causal history of a value.
+type Version struct {
+	// Clock is a vector clock, which maps each node having coordinated writes of the key to the timestamp of its latest write.
+	Clock map[string]uint64
+	// Timestamp is the hybrid logical clock timestamp of the write, which orders concurrent versions under last-writer-wins.
+	Timestamp uint64
+}
+
+// Ordering represents how two versions are related.
+type Ordering int
+
+const (
+	// Equal means both versions have the same history.
+	Equal Ordering = iota
+	// Before means a version is an ancestor of the other.
+	Before
+	// After means a version descends from the other.
+	After
+	// Concurrent means neither version knows the other.
+	Concurrent
+)
+
+// Compare returns how v is related to other. A nil version is the empty history, which precedes every other version.
+func (v *Version) Compare(other *Version) Ordering {
+	var before, after bool
+	for node, counter := range v.clock() {
+		if counter > other.clock()[node] {
+			after = true
+		}
+	}
+	for node, counter := range other.clock() {
+		if counter > v.clock()[node] {
+			before = true
+		}
+	}
+	switch {
+	case before && after:
+		return Concurrent
+	case before:
+		return Before
+	case after:
+		return After
+	default:
+		return Equal
+	}
+}
+
+func (v *Version) clock() map[string]uint64 {
+	if v == nil {
+		return nil
+	}
+	return v.Clock
+}
+
+func (v *Version) timestamp() uint64 {
+	if v == nil {
+		return 0
+	}
+	return v.Timestamp
+}
+
+// MergeVersions returns a version which descends from every given version.
+func MergeVersions(versions ...*Version) *Version {
+	merged := &Version{Clock: map[string]uint64{}}
+	for _, version := range versions {
+		for node, counter := range version.clock() {
+			if counter > merged.Clock[node] {
+				merged.Clock[node] = counter
+			}
+		}
+		if version.timestamp() > merged.Timestamp {
+			merged.Timestamp = version.timestamp()
+		}
+	}
+	return merged
+}
+
+// Sibling represents a value written concurrently with the value of a record.
+type Sibling struct {
+	Value   []byte
+	Version *Version
+	// ExpiresAt is when the value expires in unix milliseconds. 0 means it never expires.
+	ExpiresAt int64
+}
+
+// Context returns a version descending from the value and every sibling of a record.
+// A value written with it as its context supersedes all of them, which is how a client resolves siblings.
+func (r *Record) Context() *Version {
+	versions := []*Version{r.Version}
+	for _, sibling := range r.Siblings {
+		versions = append(versions, sibling.Version)
+	}
+	return MergeVersions(versions...)
+}
+
+func (r *Record) values() []*Sibling {
+	return append([]*Sibling{{Value: r.Value, Version: r.Version, ExpiresAt: r.ExpiresAt}}, r.Siblings...)
+}
+
+// newRecord creates a record of values ordered newest first.
+func newRecord(key string, values []*Sibling) *Record {
+	record := &Record{
+		Key:       key,
+		Value:     values[0].Value,
+		Version:   values[0].Version,
+		ExpiresAt: values[0].ExpiresAt,
+	}
+	if len(values) > 1 {
+		record.Siblings = values[1:]
+	}
+	return record
+}
+
+// ConflictPolicy decides which of concurrent values of a key a replica keeps.
+// Every node of a ring must use the same policy.
+type ConflictPolicy int
+
+const (
+	// LastWriterWins keeps only the value with the latest timestamp.
+	LastWriterWins ConflictPolicy = iota
+	// KeepSiblings keeps every concurrent value, until a client resolves them.
+	KeepSiblings
+)
+
+// Resolve merges two records of a key. Values which another value descends from are dropped,
+// and concurrent values are resolved with the policy. Values are ordered newest first, so the latest becomes Value.
+// Replicas resolving the same values get the same record regardless of the order they received them.
+func Resolve(policy ConflictPolicy, a *Record, b *Record) *Record {
+	candidates := append(a.values(), b.values()...)
+	var kept []*Sibling
+	for i, candidate := range candidates {
+		superseded := false
+		for j, other := range candidates {
+			if i == j {
+				continue
+			}
+			switch candidate.Version.Compare(other.Version) {
+			case Before:
+				superseded = true
+			case Equal:
+				// Values with the same history but different contents can only be written concurrently, so both are kept.
+				superseded = j < i && bytes.Equal(candidate.Value, other.Value)
+			}
+			if superseded {
+				break
+			}
+		}
+		if !superseded {
+			kept = append(kept, candidate)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		return newer(kept[i], kept[j])
+	})
+	if policy == LastWriterWins {
+		kept = kept[:1]
+	}
+	return newRecord(a.Key, kept)
+}
+
+// newer orders values by their timestamps, and by their contents if the timestamps are the same.
+func newer(a *Sibling, b *Sibling) bool {
+	if a.Version.timestamp() != b.Version.timestamp() {
+		return a.Version.timestamp() > b.Version.timestamp()
+	}
+	if c := bytes.Compare(a.Value, b.Value); c != 0 {
+		return c > 0
+	}
+	return bytes.Compare(appendVersion(nil, a.Version), appendVersion(nil, b.Version)) > 0
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVersion_Compare(t *testing.T) {
+	v1 := &Version{Clock: map[string]uint64{"gord1": 1}}
+	v2 := &Version{Clock: map[string]uint64{"gord1": 1, "gord2": 1}}
+	v3 := &Version{Clock: map[string]uint64{"gord1": 2}}
+	var empty *Version
+
+	assert.Equal(t, Equal, v1.Compare(&Version{Clock: map[string]uint64{"gord1": 1}}))
+	assert.Equal(t, Before, v1.Compare(v2))
+	assert.Equal(t, After, v2.Compare(v1))
+	assert.Equal(t, Concurrent, v2.Compare(v3))
+	assert.Equal(t, Before, empty.Compare(v1))
+	assert.Equal(t, Equal, empty.Compare(nil))
+
+	merged := MergeVersions(v2, v3)
+	assert.Equal(t, map[string]uint64{"gord1": 2, "gord2": 1}, merged.Clock)
+	assert.Equal(t, After, merged.Compare(v2))
+	assert.Equal(t, After, merged.Compare(v3))
+}
+
+func TestResolve(t *testing.T) {
+	old := &Record{Key: "key", Value: []byte("old"), Version: &Version{Clock: map[string]uint64{"gord1": 1}, Timestamp: 1}}
+	descendant := &Record{Key: "key", Value: []byte("new"), Version: &Version{Clock: map[string]uint64{"gord1": 1, "gord2": 3}, Timestamp: 3}}
+	concurrent := &Record{Key: "key", Value: []byte("concurrent"), Version: &Version{Clock: map[string]uint64{"gord1": 2}, Timestamp: 2}}
+
+	for _, policy := range []ConflictPolicy{LastWriterWins, KeepSiblings} {
+		assert.Equal(t, descendant, Resolve(policy, old, descendant))
+		assert.Equal(t, descendant, Resolve(policy, descendant, old))
+		assert.Equal(t, descendant, Resolve(policy, descendant, descendant))
+	}
+
+	// The latest of concurrent values wins.
+	assert.Equal(t, descendant, Resolve(LastWriterWins, concurrent, descendant))
+	assert.Equal(t, descendant, Resolve(LastWriterWins, descendant, concurrent))
+
+	// Concurrent values are kept, and the order of merges doesn't matter.
+	siblings := Resolve(KeepSiblings, concurrent, descendant)
+	assert.Equal(t, siblings, Resolve(KeepSiblings, descendant, concurrent))
+	assert.Equal(t, []byte("new"), siblings.Value)
+	assert.Equal(t, []*Sibling{{Value: concurrent.Value, Version: concurrent.Version}}, siblings.Siblings)
+	assert.Equal(t, siblings.Digest(), Resolve(KeepSiblings, Resolve(KeepSiblings, old, concurrent), descendant).Digest())
+
+	// A value written with the context of siblings supersedes all of them.
+	resolved := &Record{Key: "key", Value: []byte("resolved"), Version: siblings.Context()}
+	resolved.Version.Clock["gord3"] = 4
+	assert.Equal(t, resolved, Resolve(KeepSiblings, siblings, resolved))
+}